@@ -0,0 +1,187 @@
+package command
+
+import (
+	"fmt"
+	goos "os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/speechly/slu-client/internal/application"
+	"github.com/speechly/slu-client/pkg/audio"
+	"github.com/speechly/slu-client/pkg/audio/pcm"
+)
+
+var (
+	inputDevice  string
+	outputDevice string
+	sampleRate   int
+	chanCount    int
+	bitDepth     int
+	filterSpec   string
+	rawEncoding  string
+	rawRate      int
+)
+
+var audioCmd = &cobra.Command{
+	Use:   "audio",
+	Short: "Inspect audio devices of the OS",
+}
+
+var audioDevicesCmd = &cobra.Command{
+	Use:   "devices",
+	Short: "List audio host APIs and their devices",
+	Long: `List audio host APIs and their devices.
+The index or the name of a device can be used as the value of --input-device or --output-device flags.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		apis, err := audio.ListHostAPIs()
+		ensure(err)
+
+		t := tabwriter.NewWriter(goos.Stdout, 4, 0, 2, ' ', 0)
+
+		for i, a := range apis {
+			if i > 0 {
+				fmt.Fprintln(t)
+			}
+
+			if a.IsDefault {
+				fmt.Fprintf(t, "%s (default)\n", a.Name)
+			} else {
+				fmt.Fprintf(t, "%s\n", a.Name)
+			}
+
+			fmt.Fprintln(t, "  INDEX\tNAME\tINPUTS\tOUTPUTS\tSAMPLE RATE\tDEFAULT")
+
+			for _, d := range a.Devices {
+				var def []string
+				if d.IsDefaultInput {
+					def = append(def, "input")
+				}
+
+				if d.IsDefaultOutput {
+					def = append(def, "output")
+				}
+
+				fmt.Fprintf(
+					t, "  %d\t%s\t%d\t%d\t%.0f Hz\t%s\n", d.Index, d.Name, d.MaxInputChannels, d.MaxOutputChannels,
+					d.DefaultSampleRate, strings.Join(def, ", "),
+				)
+			}
+		}
+
+		ensure(t.Flush())
+	},
+}
+
+func init() {
+	audioCmd.AddCommand(audioDevicesCmd)
+	rootCmd.AddCommand(audioCmd)
+}
+
+// addInputFlags adds the flags for selecting the audio input device and the recording format to cmd.
+func addInputFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(
+		&inputDevice, "input-device", "", "Audio input device to record from, by index or name (default OS default).",
+	)
+	cmd.Flags().IntVar(&sampleRate, "sample-rate", defaultSampleRate, "Sample rate of recorded audio (in Hz).")
+	cmd.Flags().IntVar(&chanCount, "channels", defaultChanCount, "Number of channels of recorded audio.")
+	cmd.Flags().IntVar(&bitDepth, "bit-depth", defaultBitDepth, "Bit depth of recorded audio (8, 16 or 32).")
+}
+
+// addOutputFlags adds the flag for selecting the audio output device to cmd.
+func addOutputFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(
+		&outputDevice, "output-device", "", "Audio output device to play to, by index or name (default OS default).",
+	)
+}
+
+// addFilterFlag adds the flag for specifying the audio filter chain to cmd.
+func addFilterFlag(cmd *cobra.Command) {
+	cmd.Flags().StringVar(
+		&filterSpec, "filter", "",
+		"Comma-separated chain of audio filters: 'gain=dB', 'highpass=Hz', 'agc=dBFS', 'gate=dBFS', 'limiter=dBFS'.",
+	)
+}
+
+// addRawFlags adds the flags for reading headerless PCM audio files to cmd.
+func addRawFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(
+		&rawEncoding, "format", "", "Read files as raw PCM with this encoding, e.g. 's16le' or 'mulaw' (default WAV).",
+	)
+	cmd.Flags().IntVar(&rawRate, "rate", defaultSampleRate, "Sample rate of raw PCM files (in Hz).")
+	cmd.Flags().IntVar(&chanCount, "channels", defaultChanCount, "Number of channels of raw PCM files.")
+}
+
+// getInputDevice returns the audio input device specified by flags, or nil for the default one.
+func getInputDevice() (*audio.Device, error) {
+	if inputDevice == "" {
+		return nil, nil
+	}
+
+	d, err := audio.FindInputDevice(inputDevice)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugf("Using audio input device %s", d)
+
+	return &d, nil
+}
+
+// getOutputDevice returns the audio output device specified by flags, or nil for the default one.
+func getOutputDevice() (*audio.Device, error) {
+	if outputDevice == "" {
+		return nil, nil
+	}
+
+	d, err := audio.FindOutputDevice(outputDevice)
+	if err != nil {
+		return nil, err
+	}
+
+	log.Debugf("Using audio output device %s", d)
+
+	return &d, nil
+}
+
+// getInputFormat returns the recording format specified by flags.
+func getInputFormat() (audio.Format, error) {
+	if sampleRate <= 0 {
+		return audio.Format{}, fmt.Errorf("invalid sample rate: %d", sampleRate)
+	}
+
+	if chanCount <= 0 {
+		return audio.Format{}, fmt.Errorf("invalid number of channels: %d", chanCount)
+	}
+
+	// Audio devices do not support 64-bit integer samples.
+	if bitDepth == 64 {
+		return audio.Format{}, fmt.Errorf("%w: %d", audio.ErrInvalidBitDepth, bitDepth)
+	}
+
+	return audio.NewFormat(chanCount, sampleRate, bitDepth)
+}
+
+// getRawFormat returns the format of raw PCM files specified by flags, or nil if files should be read as WAV.
+func getRawFormat() (*application.RawFormat, error) {
+	if rawEncoding == "" {
+		return nil, nil
+	}
+
+	enc, err := pcm.ParseEncoding(rawEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	if rawRate <= 0 {
+		return nil, fmt.Errorf("invalid sample rate: %d", rawRate)
+	}
+
+	if chanCount <= 0 {
+		return nil, fmt.Errorf("invalid number of channels: %d", chanCount)
+	}
+
+	return &application.RawFormat{Encoding: enc, NumChannels: chanCount, SampleRate: rawRate}, nil
+}
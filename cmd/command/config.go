@@ -2,6 +2,7 @@ package command
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	goos "os"
 	"path"
@@ -26,6 +27,18 @@ const (
 	configKeyDeviceID     = "device_id"
 	configKeyLanguageCode = "language_code"
 
+	configKeyTLSCAFile             = "tls_ca_file"
+	configKeyTLSCertFile           = "tls_cert_file"
+	configKeyTLSKeyFile            = "tls_key_file"
+	configKeyTLSServerName         = "tls_server_name"
+	configKeyTLSPinSHA256          = "tls_pin_sha256"
+	configKeyTLSInsecureSkipVerify = "tls_insecure_skip_verify"
+
+	configKeyTokenVerifyKeyFile    = "token_verify_key_file"
+	configKeyTokenVerifyAlgorithms = "token_verify_algorithms"
+	configKeyTokenVerifyIssuer     = "token_verify_issuer"
+	configKeyTokenVerifyAudience   = "token_verify_audience"
+
 	configDefaultSluURL      = "grpc+tls://api.speechly.com"
 	configDefaultIdentityURL = "grpc+tls://api.speechly.com"
 
@@ -35,19 +48,61 @@ const (
 	configDescDeviceID     = "Device identifier, must be a valid UUIDv4."
 	configDescLanguageCode = "Speechly application language code, must be an IETF language tag (e.g. 'en-US')."
 
+	configDescTLSCAFile             = "Path to a PEM bundle of CA certificates for verifying API servers (default system pool)."
+	configDescTLSCertFile           = "Path to a PEM client certificate for mutual TLS, requires tls_key_file."
+	configDescTLSKeyFile            = "Path to a PEM client private key for mutual TLS, requires tls_cert_file."
+	configDescTLSServerName         = "Override the server name used for verifying API server certificates."
+	configDescTLSPinSHA256          = "Comma-separated base64 SHA-256 hashes of pinned server public keys (SPKI)."
+	configDescTLSInsecureSkipVerify = "Skip verification of API server certificates (development only!). Pins only match the server certificate." // nolint: lll
+
+	configDescTokenVerifyKeyFile    = "Path to a PEM public key or a JWKS file for verifying API tokens (default none)."
+	configDescTokenVerifyAlgorithms = "Comma-separated signing algorithms allowed for API tokens (default RS*, PS*, ES*)."
+	configDescTokenVerifyIssuer     = "Required issuer of API tokens, only checked when verifying tokens."
+	configDescTokenVerifyAudience   = "Required audience of API tokens, only checked when verifying tokens."
+
 	configFileName     = "config"
 	configFileFormat   = "json"
 	configFileFullName = configFileName + "." + configFileFormat
+	configDirPerms     = 0750
+
+	configEnvPrefix = "SPEECHLY"
+
+	configSourceDefault = "default"
+	configSourceFile    = "file"
+	configSourceEnv     = "env"
+	configSourceFlag    = "flag"
+
+	printFormatText = "text"
+	printFormatJSON = "json"
+)
+
+var (
+	printFormat            string
+	generateNonInteractive bool
 )
 
 var (
 	config          = application.Config{}
+	configFile      = application.NewConfigFile()
+	configProfile   = application.Profile{}
 	validConfigKeys = map[string]string{
 		configKeySluURL:       configDescSluURL,
 		configKeyIdentityURL:  configDescIdentityURL,
 		configKeyAppID:        configDescAppID,
 		configKeyDeviceID:     configDescDeviceID,
 		configKeyLanguageCode: configDescLanguageCode,
+
+		configKeyTLSCAFile:             configDescTLSCAFile,
+		configKeyTLSCertFile:           configDescTLSCertFile,
+		configKeyTLSKeyFile:            configDescTLSKeyFile,
+		configKeyTLSServerName:         configDescTLSServerName,
+		configKeyTLSPinSHA256:          configDescTLSPinSHA256,
+		configKeyTLSInsecureSkipVerify: configDescTLSInsecureSkipVerify,
+
+		configKeyTokenVerifyKeyFile:    configDescTokenVerifyKeyFile,
+		configKeyTokenVerifyAlgorithms: configDescTokenVerifyAlgorithms,
+		configKeyTokenVerifyIssuer:     configDescTokenVerifyIssuer,
+		configKeyTokenVerifyAudience:   configDescTokenVerifyAudience,
 	}
 )
 
@@ -58,7 +113,7 @@ var configCmd = &cobra.Command{
 
 var configPrintCmd = &cobra.Command{
 	Use:    "print",
-	Short:  "Print current configuration",
+	Short:  "Print current configuration and where each value comes from",
 	PreRun: checkConfig,
 	Run: func(cmd *cobra.Command, args []string) {
 		err := os.WithSignal(cmd.Context(), func(ctx context.Context) error {
@@ -69,76 +124,146 @@ var configPrintCmd = &cobra.Command{
 			}
 			sort.Strings(ks)
 
-			t := tabwriter.NewWriter(goos.Stdout, 4, 0, 1, ' ', 0)
-			for _, k := range ks {
-				fmt.Fprintf(t, "%s: \t%s\n", k, viper.GetString(k))
+			switch printFormat {
+			case printFormatText:
+				t := tabwriter.NewWriter(goos.Stdout, 4, 0, 1, ' ', 0)
+				fmt.Fprintf(t, "profile: \t%s\n", getProfileName())
+				for _, k := range ks {
+					fmt.Fprintf(t, "%s: \t%s\t(%s)\n", k, viper.GetString(k), getConfigSource(k))
+				}
+				return t.Flush()
+			case printFormatJSON:
+				type value struct {
+					Value  interface{} `json:"value"`
+					Source string      `json:"source"`
+				}
+
+				vs := make(map[string]value, len(ks))
+				for _, k := range ks {
+					vs[k] = value{getConfigValue(k), getConfigSource(k)}
+				}
+
+				e := json.NewEncoder(goos.Stdout)
+				e.SetIndent("", "  ")
+				return e.Encode(struct {
+					Profile string           `json:"profile"`
+					Values  map[string]value `json:"values"`
+				}{getProfileName(), vs})
+			default:
+				return fmt.Errorf("invalid output format '%s', must be either 'text' or 'json'", printFormat)
 			}
-			return t.Flush()
 		}, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 
 		ensure(err)
 	},
 }
 
+var configGetCmd = &cobra.Command{
+	Use:   "get key",
+	Short: "Print the value of a specific property of current configuration",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ensure(checkConfigKey(args[0]))
+		fmt.Println(viper.GetString(args[0]))
+	},
+}
+
 var configGenerateCmd = &cobra.Command{
-	Use:     "generate",
-	Short:   "Generate configuration file in interactive mode",
+	Use:   "generate",
+	Short: "Generate configuration of current profile in interactive mode",
+	Long: `Generate configuration of current profile in interactive mode.
+Values that are set with flags or SPEECHLY_* environment variables are not prompted for.
+With --non-interactive, the app ID and language code must be set that way, for example:
+
+  speechly-slu config generate --non-interactive -a <app ID> -l en-US`,
 	PostRun: removeCachedToken,
 	Run: func(cmd *cobra.Command, args []string) {
 		err := os.WithSignal(cmd.Context(), func(ctx context.Context) error {
-			var res string
-
-			fmt.Print("Speechly app ID: ")
-			if _, err := fmt.Scanln(&res); err != nil {
-				return err
+			for _, q := range []struct {
+				key, prompt string
+			}{
+				{configKeyAppID, "Speechly app ID: "},
+				{configKeyLanguageCode, "Speechly app language: "},
+			} {
+				switch {
+				case isConfigOverridden(q.key):
+					continue
+				case generateNonInteractive:
+					if viper.GetString(q.key) == "" {
+						return fmt.Errorf(
+							"missing value of '%s', set it with --%s flag or %s environment variable",
+							q.key, q.key, getConfigEnvName(q.key),
+						)
+					}
+				default:
+					var res string
+
+					fmt.Print(q.prompt)
+					if _, err := fmt.Scanln(&res); err != nil {
+						return err
+					}
+					viper.Set(q.key, res)
+				}
 			}
-			viper.Set(configKeyAppID, res)
 
-			fmt.Print("Speechly app language: ")
-			if _, err := fmt.Scanln(&res); err != nil {
-				return err
+			if !isConfigOverridden(configKeyDeviceID) {
+				id, err := uuid.NewRandom()
+				if err != nil {
+					return err
+				}
+
+				viper.Set(configKeyDeviceID, id.String())
 			}
-			viper.Set(configKeyLanguageCode, res)
 
-			id, err := uuid.NewRandom()
-			if err != nil {
-				return err
+			if !isConfigOverridden(configKeySluURL) {
+				viper.Set(configKeySluURL, configDefaultSluURL)
 			}
 
-			viper.Set(configKeyDeviceID, id.String())
-			viper.Set(configKeySluURL, configDefaultSluURL)
-			viper.Set(configKeyIdentityURL, configDefaultIdentityURL)
+			if !isConfigOverridden(configKeyIdentityURL) {
+				viper.Set(configKeyIdentityURL, configDefaultIdentityURL)
+			}
 
 			if err := parseConfig(); err != nil {
 				return err
 			}
 
-			if err := touchConfigFile(); err != nil {
-				return err
+			name := getProfileName()
+			for k := range validConfigKeys {
+				switch k {
+				case configKeyAppID, configKeyLanguageCode, configKeyDeviceID, configKeySluURL, configKeyIdentityURL:
+				default:
+					if !isConfigOverridden(k) {
+						continue
+					}
+				}
+
+				if err := configFile.SetValue(name, k, getConfigValue(k)); err != nil {
+					return err
+				}
+			}
+
+			if configFile.CurrentProfile == "" {
+				configFile.CurrentProfile = name
 			}
 
-			return viper.WriteConfig()
+			return saveConfigFile()
 		}, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 
-		log.Info("Config generated successfully!")
 		ensure(err)
+		log.Info("Config generated successfully!")
 	},
 }
 
 var configUpdateCmd = &cobra.Command{
 	Use:     "update key value",
-	Short:   "Update a specific property in configuration file",
+	Short:   "Update a specific property of current profile in configuration file",
 	PreRun:  checkConfig,
 	PostRun: removeCachedToken,
 	Args:    cobra.ExactArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
 		err := os.WithSignal(cmd.Context(), func(ctx context.Context) error {
-			if _, ok := validConfigKeys[args[0]]; !ok {
-				return fmt.Errorf(
-					"invalid config key '%s', valid keys are:\n%s",
-					args[0],
-					configKeysHelpString(),
-				)
+			if err := checkConfigKey(args[0]); err != nil {
+				return err
 			}
 
 			viper.Set(args[0], args[1])
@@ -147,11 +272,11 @@ var configUpdateCmd = &cobra.Command{
 				return err
 			}
 
-			if err := viper.WriteConfig(); err != nil {
+			if err := configFile.SetValue(getProfileName(), args[0], viper.Get(args[0])); err != nil {
 				return err
 			}
 
-			return nil
+			return saveConfigFile()
 		}, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 
 		log.Info("Config updated successfully!")
@@ -159,8 +284,28 @@ var configUpdateCmd = &cobra.Command{
 	},
 }
 
+var configUnsetCmd = &cobra.Command{
+	Use:     "unset key",
+	Short:   "Remove a specific property of current profile from configuration file",
+	PostRun: removeCachedToken,
+	Args:    cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ensure(checkConfigKey(args[0]))
+		ensure(configFile.UnsetValue(getProfileName(), args[0]))
+		ensure(saveConfigFile())
+		log.Info("Config updated successfully!")
+	},
+}
+
 func init() {
-	configCmd.AddCommand(configGenerateCmd, configUpdateCmd, configPrintCmd)
+	configPrintCmd.Flags().StringVar(&printFormat, "format", printFormatText, "Output format, either 'text' or 'json'.")
+	configGenerateCmd.Flags().BoolVar(
+		&generateNonInteractive, "non-interactive", false, "Do not prompt for values, take them from flags and environment.",
+	)
+
+	configCmd.AddCommand(
+		configGenerateCmd, configUpdateCmd, configPrintCmd, configGetCmd, configUnsetCmd,
+	)
 	rootCmd.AddCommand(configCmd)
 }
 
@@ -172,32 +317,89 @@ func checkConfig(cmd *cobra.Command, args []string) {
 			cmd.Println("Cannot proceed without valid config, please generate one using 'config generate'!")
 		}
 
-		goos.Exit(1)
+		exit(1)
 	}
 }
 
 func parseConfig() error {
-	return config.Parse(
+	if err := config.Parse(
 		viper.GetString(configKeySluURL),
 		viper.GetString(configKeyIdentityURL),
 		viper.GetString(configKeyAppID),
 		viper.GetString(configKeyDeviceID),
 		viper.GetString(configKeyLanguageCode),
+	); err != nil {
+		return err
+	}
+
+	if err := config.ParseTLS(
+		viper.GetString(configKeyTLSCAFile),
+		viper.GetString(configKeyTLSCertFile),
+		viper.GetString(configKeyTLSKeyFile),
+		viper.GetString(configKeyTLSServerName),
+		viper.GetString(configKeyTLSPinSHA256),
+		viper.GetBool(configKeyTLSInsecureSkipVerify),
+	); err != nil {
+		return err
+	}
+
+	return config.ParseTokenVerification(
+		viper.GetString(configKeyTokenVerifyKeyFile),
+		viper.GetString(configKeyTokenVerifyAlgorithms),
+		viper.GetString(configKeyTokenVerifyIssuer),
+		viper.GetString(configKeyTokenVerifyAudience),
 	)
 }
 
-func touchConfigFile() error {
-	dirname := getConfigDir()
-	if err := goos.Mkdir(dirname, 0750); err != nil && !goos.IsExist(err) {
+// loadConfigFile loads the configuration file and merges the values of the active profile into configuration.
+func loadConfigFile() error {
+	f, err := application.LoadConfigFile(getConfigFilePath())
+	if err != nil {
 		return err
 	}
 
-	file, err := goos.Create(path.Join(dirname, configFileFullName))
-	if err != nil && !goos.IsExist(err) {
+	configFile = f
+
+	p, err := configFile.Profile(getProfileName())
+	if err != nil {
 		return err
 	}
 
-	return file.Close()
+	configProfile = p
+
+	return viper.MergeConfigMap(p)
+}
+
+func saveConfigFile() error {
+	if configFilePath == "" {
+		if err := goos.Mkdir(getConfigDir(), configDirPerms); err != nil && !goos.IsExist(err) {
+			return err
+		}
+	}
+
+	return configFile.Save(getConfigFilePath())
+}
+
+func getConfigFilePath() string {
+	if configFilePath != "" {
+		return configFilePath
+	}
+
+	return path.Join(getConfigDir(), configFileFullName)
+}
+
+// getProfileName returns the name of the active profile, which is either set with a flag
+// or is the current profile of the configuration file.
+func getProfileName() string {
+	if profileName != "" {
+		return profileName
+	}
+
+	if configFile.CurrentProfile != "" {
+		return configFile.CurrentProfile
+	}
+
+	return application.DefaultProfile
 }
 
 func getConfigDir() string {
@@ -207,11 +409,55 @@ func getConfigDir() string {
 	return path.Join(home, ".speechly/")
 }
 
+// getConfigSource returns where the effective value of config key k comes from, in the order of precedence.
+func getConfigSource(k string) string {
+	if f := rootCmd.PersistentFlags().Lookup(k); f != nil && f.Changed {
+		return configSourceFlag
+	}
+
+	if v, ok := goos.LookupEnv(getConfigEnvName(k)); ok && v != "" {
+		return configSourceEnv
+	}
+
+	if _, ok := configProfile[k]; ok {
+		return configSourceFile
+	}
+
+	return configSourceDefault
+}
+
+// isConfigOverridden returns true if the value of config key k is set with a flag or an environment variable.
+func isConfigOverridden(k string) bool {
+	s := getConfigSource(k)
+	return s == configSourceFlag || s == configSourceEnv
+}
+
+// getConfigValue returns the effective value of config key k, typed according to its flag.
+func getConfigValue(k string) interface{} {
+	if f := rootCmd.PersistentFlags().Lookup(k); f != nil && f.Value.Type() == "bool" {
+		return viper.GetBool(k)
+	}
+
+	return viper.GetString(k)
+}
+
+func getConfigEnvName(k string) string {
+	return configEnvPrefix + "_" + strings.ToUpper(k)
+}
+
+func checkConfigKey(k string) error {
+	if _, ok := validConfigKeys[k]; !ok {
+		return fmt.Errorf("invalid config key '%s', valid keys are:\n%s", k, configKeysHelpString())
+	}
+
+	return nil
+}
+
 func configKeysHelpString() string {
 	b := strings.Builder{}
 
 	for key, desc := range validConfigKeys {
-		b.WriteString(fmt.Sprintf("* %s\t%s\n", key, desc))
+		b.WriteString(fmt.Sprintf("* %s\t%s (env %s)\n", key, desc, getConfigEnvName(key)))
 	}
 
 	return b.String()
@@ -0,0 +1,60 @@
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/speechly/slu-client/internal/application"
+	"github.com/speechly/slu-client/internal/os"
+)
+
+const doctorLineFormat = "%-6s %-26s %7v  %v\n"
+
+var doctorTimeout time.Duration
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Diagnose configuration, API connectivity and audio input",
+	Long: `Diagnose configuration, API connectivity and audio input.
+Checks that configuration is valid, that API hosts can be resolved and dialled, that an API token can be obtained
+and used for recognising a built-in audio sample, and that the default audio input device can be opened.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		var ok bool
+
+		err := os.WithSignal(cmd.Context(), func(ctx context.Context) error {
+			// Results are printed as soon as they are available, so that progress is visible during slow checks.
+			ok = application.Diagnose(ctx, config, parseConfig(), doctorTimeout, func(r application.CheckResult) {
+				d := r.Duration.Round(time.Millisecond)
+
+				switch r.Status {
+				case application.CheckPassed:
+					fmt.Printf(doctorLineFormat, "["+r.Status+"]", r.Name, d, r.Detail)
+				case application.CheckFailed:
+					fmt.Printf(doctorLineFormat, "["+r.Status+"]", r.Name, d, r.Err)
+					fmt.Printf(doctorLineFormat, "", "", "", "hint: "+r.Hint)
+				case application.CheckSkipped:
+					fmt.Printf(doctorLineFormat, "["+r.Status+"]", r.Name, "", "skipped because of a failed check")
+				}
+			}, log)
+
+			return nil
+		}, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+
+		ensure(err)
+
+		if !ok {
+			ensure(errors.New("some checks have failed"))
+		}
+	},
+}
+
+func init() {
+	doctorCmd.Flags().DurationVar(&doctorTimeout, "timeout", 10*time.Second, "Timeout of each check.")
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,115 @@
+package command
+
+import (
+	"context"
+	"fmt"
+	goos "os"
+	"syscall"
+	"text/tabwriter"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/speechly/slu-client/internal/application"
+	"github.com/speechly/slu-client/internal/os"
+)
+
+var configProfileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Manage named configuration profiles",
+}
+
+var configProfileAddCmd = &cobra.Command{
+	Use:   "add name",
+	Short: "Add a new profile, based on current profile and values set with flags",
+	Long: `Add a new profile, based on current profile and values set with flags.
+For example, to add a profile that uses a different app ID and language code:
+
+  speechly-slu config profile add staging -a <app ID> -l en-US`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := os.WithSignal(cmd.Context(), func(ctx context.Context) error {
+			if viper.GetString(configKeyDeviceID) == "" {
+				id, err := uuid.NewRandom()
+				if err != nil {
+					return err
+				}
+
+				viper.Set(configKeyDeviceID, id.String())
+			}
+
+			if err := parseConfig(); err != nil {
+				return err
+			}
+
+			p := application.Profile{}
+			for k := range validConfigKeys {
+				if v := getConfigValue(k); v != "" && v != false {
+					p[k] = v
+				}
+			}
+
+			if err := configFile.AddProfile(args[0], p); err != nil {
+				return err
+			}
+
+			if configFile.CurrentProfile == "" {
+				configFile.CurrentProfile = args[0]
+			}
+
+			return saveConfigFile()
+		}, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+
+		ensure(err)
+		log.Infof("Profile '%s' added successfully!", args[0])
+	},
+}
+
+var configProfileListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List profiles, current profile is marked with '*'",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		t := tabwriter.NewWriter(goos.Stdout, 4, 0, 1, ' ', 0)
+		for _, n := range configFile.ProfileNames() {
+			mark := " "
+			if n == configFile.CurrentProfile {
+				mark = "*"
+			}
+
+			p := configFile.Profiles[n]
+			fmt.Fprintf(t, "%s %s\t%v\t%v\n", mark, n, p[configKeyAppID], p[configKeyLanguageCode])
+		}
+
+		ensure(t.Flush())
+	},
+}
+
+var configProfileUseCmd = &cobra.Command{
+	Use:   "use name",
+	Short: "Make specified profile the current one",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ensure(configFile.UseProfile(args[0]))
+		ensure(saveConfigFile())
+		log.Infof("Using profile '%s'", args[0])
+	},
+}
+
+var configProfileRemoveCmd = &cobra.Command{
+	Use:   "remove name",
+	Short: "Remove specified profile and its cached API token",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		ensure(configFile.RemoveProfile(args[0]))
+		ensure(saveConfigFile())
+		removeProfileToken(args[0])
+		log.Infof("Profile '%s' removed successfully!", args[0])
+	},
+}
+
+func init() {
+	configProfileCmd.AddCommand(configProfileAddCmd, configProfileListCmd, configProfileUseCmd, configProfileRemoveCmd)
+	configCmd.AddCommand(configProfileCmd)
+}
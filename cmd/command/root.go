@@ -2,6 +2,7 @@ package command
 
 import (
 	"fmt"
+	"io"
 	"os"
 
 	"github.com/sirupsen/logrus"
@@ -16,20 +17,40 @@ const (
 	defaultSampleRate = 16000 // 16 kHz
 	defaultBitDepth   = 16    // 16 bit
 	defaultChanCount  = 1     // Mono
+
+	logFilePerms = 0600
 )
 
 var (
 	bufferSize     int
 	enableDebug    bool
+	logFormat      string
+	logFilePath    string
 	configFilePath string
+	profileName    string
 	appID          string
 	deviceID       string
 	languageCode   string
 	sluURL         string
 	identityURL    string
+
+	tlsCAFile             string
+	tlsCertFile           string
+	tlsKeyFile            string
+	tlsServerName         string
+	tlsPinSHA256          string
+	tlsInsecureSkipVerify bool
+
+	tokenVerifyKeyFile    string
+	tokenVerifyAlgorithms string
+	tokenVerifyIssuer     string
+	tokenVerifyAudience   string
 )
 
-var log logger.Logger
+var (
+	log     logger.Logger
+	logFile *os.File
+)
 
 var rootCmd = &cobra.Command{
 	Use:   "speechly-slu",
@@ -37,20 +58,41 @@ var rootCmd = &cobra.Command{
 }
 
 func Execute() error { // nolint: revive
+	defer closeLogFile()
+
 	return rootCmd.Execute()
 }
 
 func init() {
 	cobra.OnInitialize(setup)
 
-	rootCmd.PersistentFlags().IntVarP(&bufferSize, "buffer_size", "b", 2048, "Size of memory buffer to use (in bytes).")
+	rootCmd.PersistentFlags().IntVarP(&bufferSize, "buffer_size", "b", 2048, "Size of audio buffers to use (in samples).")
 	rootCmd.PersistentFlags().StringVarP(&configFilePath, "config", "c", "", "Config file (default $HOME/.speechly/config.json).") // nolint: lll
+	rootCmd.PersistentFlags().StringVarP(&profileName, "profile", "p", "", "Config profile to use (default current one).")
 	rootCmd.PersistentFlags().BoolVar(&enableDebug, "debug", false, "Enable debug output.")
+	rootCmd.PersistentFlags().StringVar(&logFormat, "log-format", "text", "Log output format, either 'text' or 'json'.")
+	rootCmd.PersistentFlags().StringVar(&logFilePath, "log-file", "", "Write logs to this file instead of STDERR.")
 	rootCmd.PersistentFlags().StringVar(&sluURL, configKeySluURL, "", configDescSluURL)
 	rootCmd.PersistentFlags().StringVar(&identityURL, configKeyIdentityURL, "", configDescIdentityURL)
 	rootCmd.PersistentFlags().StringVarP(&appID, configKeyAppID, "a", "", configDescAppID)
 	rootCmd.PersistentFlags().StringVarP(&deviceID, configKeyDeviceID, "d", "", configDescDeviceID)
 	rootCmd.PersistentFlags().StringVarP(&languageCode, configKeyLanguageCode, "l", "", configDescLanguageCode)
+	rootCmd.PersistentFlags().StringVar(&tlsCAFile, configKeyTLSCAFile, "", configDescTLSCAFile)
+	rootCmd.PersistentFlags().StringVar(&tlsCertFile, configKeyTLSCertFile, "", configDescTLSCertFile)
+	rootCmd.PersistentFlags().StringVar(&tlsKeyFile, configKeyTLSKeyFile, "", configDescTLSKeyFile)
+	rootCmd.PersistentFlags().StringVar(&tlsServerName, configKeyTLSServerName, "", configDescTLSServerName)
+	rootCmd.PersistentFlags().StringVar(&tlsPinSHA256, configKeyTLSPinSHA256, "", configDescTLSPinSHA256)
+	rootCmd.PersistentFlags().BoolVar(
+		&tlsInsecureSkipVerify, configKeyTLSInsecureSkipVerify, false, configDescTLSInsecureSkipVerify,
+	)
+	rootCmd.PersistentFlags().StringVar(&tokenVerifyKeyFile, configKeyTokenVerifyKeyFile, "", configDescTokenVerifyKeyFile)
+	rootCmd.PersistentFlags().StringVar(
+		&tokenVerifyAlgorithms, configKeyTokenVerifyAlgorithms, "", configDescTokenVerifyAlgorithms,
+	)
+	rootCmd.PersistentFlags().StringVar(&tokenVerifyIssuer, configKeyTokenVerifyIssuer, "", configDescTokenVerifyIssuer)
+	rootCmd.PersistentFlags().StringVar(
+		&tokenVerifyAudience, configKeyTokenVerifyAudience, "", configDescTokenVerifyAudience,
+	)
 }
 
 func setup() {
@@ -59,33 +101,40 @@ func setup() {
 		level = logrus.DebugLevel
 	}
 
-	log = application.NewLogger(os.Stderr, level)
+	format, err := logger.ParseFormat(logFormat)
+	ensure(err)
+
+	out := io.Writer(os.Stderr)
+	if logFilePath != "" {
+		f, err := os.OpenFile(logFilePath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, logFilePerms) // nolint: gosec
+		ensure(err)
+		out, logFile = f, f
+	}
+
+	log = application.NewLogger(out, level, format)
 
 	viper.SetDefault(configKeySluURL, configDefaultSluURL)
 	viper.SetDefault(configKeyIdentityURL, configDefaultIdentityURL)
 
-	ensure(viper.BindPFlag(configKeySluURL, rootCmd.PersistentFlags().Lookup(configKeySluURL)))
-	ensure(viper.BindPFlag(configKeyIdentityURL, rootCmd.PersistentFlags().Lookup(configKeyIdentityURL)))
-	ensure(viper.BindPFlag(configKeyAppID, rootCmd.PersistentFlags().Lookup(configKeyAppID)))
-	ensure(viper.BindPFlag(configKeyDeviceID, rootCmd.PersistentFlags().Lookup(configKeyDeviceID)))
-	ensure(viper.BindPFlag(configKeyLanguageCode, rootCmd.PersistentFlags().Lookup(configKeyLanguageCode)))
+	viper.SetEnvPrefix(configEnvPrefix)
 
-	if configFilePath != "" {
-		viper.SetConfigFile(configFilePath)
-	} else {
-		viper.AddConfigPath(getConfigDir())
-		viper.SetConfigName(configFileName)
+	for k := range validConfigKeys {
+		ensure(viper.BindPFlag(k, rootCmd.PersistentFlags().Lookup(k)))
+		ensure(viper.BindEnv(k))
 	}
 
-	viper.SetConfigType(configFileFormat)
-
-	if err := viper.ReadInConfig(); err != nil {
-		log.Warnf("Error loading config file: '%s'", err)
-		return
+	// Configuration can also be provided with flags and environment, so parse it even without config file.
+	loadErr := loadConfigFile()
+	if loadErr != nil {
+		log.Warnf("Error loading config file: '%s'", loadErr)
 	}
 
 	if err := parseConfig(); err != nil {
-		log.Warnf("Error parsing config: '%s', proceeding without config...", err)
+		if loadErr == nil {
+			log.Warnf("Error parsing config: '%s', proceeding without config...", err)
+		} else {
+			log.Debugf("Error parsing config: '%s', proceeding without config...", err)
+		}
 	} else {
 		log.Debugf("Running with config: %+v", config)
 	}
@@ -102,5 +151,29 @@ func ensure(err error) {
 		fmt.Fprintf(os.Stderr, "Error: %s\n", err)
 	}
 
-	os.Exit(1)
+	exit(1)
+}
+
+// exit closes the log file, so that no log entries are lost, and exits with code.
+func exit(code int) {
+	closeLogFile()
+	os.Exit(code)
+}
+
+// closeLogFile flushes and closes the file opened with --log-file, if any.
+func closeLogFile() {
+	if logFile == nil {
+		return
+	}
+
+	f := logFile
+	logFile = nil
+
+	if err := f.Sync(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error syncing log file: %s\n", err)
+	}
+
+	if err := f.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error closing log file: %s\n", err)
+	}
 }
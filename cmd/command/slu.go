@@ -5,44 +5,82 @@ import (
 	goos "os"
 	"path/filepath"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/speechly/slu-client/internal/application"
 	"github.com/speechly/slu-client/internal/os"
-	"github.com/speechly/slu-client/pkg/audio"
 	"github.com/speechly/slu-client/pkg/speechly"
+	"github.com/speechly/slu-client/pkg/speechly/slu"
+	"github.com/speechly/slu-client/pkg/speechly/slu/metrics"
 )
 
 var (
 	apiToken        speechly.AccessToken
 	enableTentative bool
+	metricsAddr     string
+	instr           slu.Instrumentation
+	splitChannels   bool
+	speakers        []string
+	inputFile       string
+	paceSpeed       float64
+	paceJitter      time.Duration
+	chunkMillis     int
+	adaptiveChunks  bool
+	captureBuffer   time.Duration
 )
 
+const defaultChunkMillis = 100
+
 var sluCmd = &cobra.Command{
 	Use:   "slu",
 	Short: "Interact with Speechly SLU API",
+	Long: `Interact with Speechly SLU API.
+Audio is sent in chunks of --chunk-ms milliseconds. With live audio, every chunk has to be recorded before it's sent,
+so the chunk size adds directly to the latency of results, while smaller chunks mean more messages and overhead.
+With --adaptive-chunks, chunks of files grow up to a second for better throughput, and chunks of live audio
+shrink down to 20 ms while the network keeps up with them, for lower latency.
+Every audio context (a file, or a microphone session) can be limited with --max-audio, --idle-timeout
+and --context-timeout flags, so that forgotten or stalled recognition does not run forever.`,
 	PersistentPreRun: func(cmd *cobra.Command, args []string) {
 		checkConfig(cmd, args)
 		setToken(cmd, args)
+		setMetrics(cmd, args)
 	},
 }
 
 var streamCmd = &cobra.Command{
 	Use:   "stream",
 	Short: "Stream audio from microphone to SLU API",
+	Long: `Stream audio from microphone to SLU API.
+With --input-file, audio is streamed from a WAV file at real-time speed instead, simulating the microphone.
+Use --speed and --jitter flags for changing the pace of the audio.
+Input overflows and audio dropped from the capture buffer are reported, along with the gap between captured
+and sent audio. With --capture-buffer, audio is buffered between capturing and sending it, to survive network stalls.`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if inputFile != "" {
+			streamFile(cmd)
+			return
+		}
+
 		log.Info("Starting microphone streaming...")
 
 		err := os.WithSignal(cmd.Context(), func(ctx context.Context) error {
 			log.Info("Started microphone streaming, press Ctrl+C to finish.")
-			audioFmt, err := audio.NewFormat(defaultChanCount, defaultSampleRate, defaultBitDepth)
+			audioFmt, err := getInputFormat()
+			if err != nil {
+				return err
+			}
+
+			dev, err := getInputDevice()
 			if err != nil {
 				return err
 			}
 
 			return application.RecogniseMicrophone(
-				ctx, config, audioFmt, apiToken, goos.Stdout, enableTentative, bufferSize, log,
+				ctx, config, dev, audioFmt, filterSpec, getChunking(), captureBuffer, apiToken, goos.Stdout,
+				enableTentative, bufferSize, instr, log,
 			)
 		}, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 
@@ -53,8 +91,14 @@ var streamCmd = &cobra.Command{
 
 var uploadCmd = &cobra.Command{
 	Use:   "upload file1 file2 ... fileN",
-	Short: "Upload a WAV file to SLU API",
-	Args:  cobra.MinimumNArgs(1),
+	Short: "Upload WAV or raw PCM files to SLU API",
+	Long: `Upload WAV or raw PCM files to SLU API.
+Use '-' as the file name to read audio from STDIN, e.g. when piping it from another program.
+Raw PCM files have no header, so their format must be specified with --format, --rate and --channels flags.
+G.711 (mu-law and A-law) audio is supported as well, and audio sampled below 16 kHz is upsampled to 16 kHz.
+With --split-channels, every channel (e.g. each side of a call recording) is recognised separately
+and the output is a conversation transcript, with one finalised segment per line, labelled with its channel.`,
+	Args: cobra.MinimumNArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		log.Info("Starting file upload...")
 
@@ -64,7 +108,22 @@ var uploadCmd = &cobra.Command{
 				return err
 			}
 
-			return application.RecogniseFiles(ctx, config, apiToken, paths, goos.Stdout, enableTentative, bufferSize, log)
+			raw, err := getRawFormat()
+			if err != nil {
+				return err
+			}
+
+			if splitChannels {
+				return application.RecogniseFileChannels(
+					ctx, config, apiToken, paths, raw, filterSpec, getChunking(), speakers, goos.Stdout, bufferSize,
+					instr, log,
+				)
+			}
+
+			return application.RecogniseFiles(
+				ctx, config, apiToken, paths, raw, filterSpec, getChunking(), goos.Stdout, enableTentative, bufferSize,
+				instr, log,
+			)
 		}, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 
 		ensure(err)
@@ -74,15 +133,101 @@ var uploadCmd = &cobra.Command{
 
 func init() {
 	sluCmd.PersistentFlags().BoolVarP(&enableTentative, "enable_tentative", "t", false, "output tentative context states")
+	sluCmd.PersistentFlags().IntVar(
+		&chunkMillis, "chunk-ms", defaultChunkMillis,
+		"Duration of audio chunks sent to the API (in milliseconds), which adds to the latency of live audio.",
+	)
+	sluCmd.PersistentFlags().BoolVar(
+		&adaptiveChunks, "adaptive-chunks", false,
+		"Grow chunks of files for throughput and shrink chunks of live audio for latency, starting from --chunk-ms.",
+	)
+	sluCmd.PersistentFlags().DurationVar(
+		&config.Limits.MaxAudio, "max-audio", 0,
+		"Stop audio contexts after this much audio (e.g. '10m') and finish with the results so far.",
+	)
+	sluCmd.PersistentFlags().DurationVar(
+		&config.Limits.IdleTimeout, "idle-timeout", 0,
+		"Fail if the API sends no responses for this long (e.g. '30s'), once all audio has been sent.",
+	)
+	sluCmd.PersistentFlags().DurationVar(
+		&config.Limits.Timeout, "context-timeout", 0, "Fail if an audio context is not finished within this time.",
+	)
+	sluCmd.PersistentFlags().StringVar(
+		&metricsAddr, "metrics-addr", "", "serve latency metrics in Prometheus format on this address (e.g. 'localhost:9090')",
+	)
+
+	addInputFlags(streamCmd)
+	streamCmd.Flags().StringVar(
+		&inputFile, "input-file", "", "Stream audio from this WAV file at real-time speed, use '-' for STDIN.",
+	)
+	streamCmd.Flags().Float64Var(&paceSpeed, "speed", 1, "Speed multiplier of audio streamed with --input-file.")
+	streamCmd.Flags().DurationVar(
+		&paceJitter, "jitter", 0, "Delay chunks of audio streamed with --input-file by a random duration up to this.",
+	)
+	streamCmd.Flags().DurationVar(
+		&captureBuffer, "capture-buffer", 0,
+		"Buffer up to this much microphone audio while it cannot be sent, e.g. '5s'. The oldest audio is dropped once full.",
+	)
+	addFilterFlag(streamCmd)
+	addFilterFlag(uploadCmd)
+	addRawFlags(uploadCmd)
+	uploadCmd.Flags().BoolVar(
+		&splitChannels, "split-channels", false, "Recognise every channel separately and merge results by time.",
+	)
+	uploadCmd.Flags().StringSliceVar(
+		&speakers, "speakers", nil, "Comma-separated speaker labels of channels, used with --split-channels.",
+	)
 
 	sluCmd.AddCommand(uploadCmd, streamCmd)
 	rootCmd.AddCommand(sluCmd)
 }
 
+func streamFile(cmd *cobra.Command) {
+	log.Info("Starting file streaming...")
+
+	err := os.WithSignal(cmd.Context(), func(ctx context.Context) error {
+		paths, err := normalisePaths([]string{inputFile})
+		if err != nil {
+			return err
+		}
+
+		return application.RecognisePacedFile(
+			ctx, config, paths[0], paceSpeed, paceJitter, filterSpec, getChunking(), apiToken, goos.Stdout, bufferSize,
+			instr, log,
+		)
+	}, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
+
+	ensure(err)
+	log.Info("File streaming finished!")
+}
+
+// getChunking returns the chunking of audio sent to the API specified by flags.
+func getChunking() application.Chunking {
+	return application.Chunking{
+		Duration: time.Duration(chunkMillis) * time.Millisecond,
+		Adaptive: adaptiveChunks,
+	}
+}
+
+func setMetrics(cmd *cobra.Command, args []string) { // nolint: unparam
+	if metricsAddr == "" {
+		return
+	}
+
+	m := metrics.NewLatency()
+	ensure(application.ServeMetrics(cmd.Context(), metricsAddr, m, log))
+	instr = m
+}
+
 func normalisePaths(paths []string) ([]string, error) {
 	p := make([]string, 0, len(paths))
 
 	for _, v := range paths {
+		if v == application.StdinPath {
+			p = append(p, v)
+			continue
+		}
+
 		a, err := filepath.Abs(v)
 		if err != nil {
 			return nil, err
@@ -1,48 +1,165 @@
 package command
 
 import (
-	"bytes"
-	"encoding/base32"
 	"fmt"
-	"os"
-	"path/filepath"
+	goos "os"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 
 	"github.com/speechly/slu-client/internal/application"
+	pgrpc "github.com/speechly/slu-client/pkg/grpc"
+	"github.com/speechly/slu-client/pkg/speechly/tokencache"
 )
 
-const tokenFilename = "access_token"
+var clearAllTokens bool
+
+var tokenCmd = &cobra.Command{
+	Use:   "token",
+	Short: "Inspect and manage the cached Speechly API token of current profile",
+}
+
+var tokenShowCmd = &cobra.Command{
+	Use:    "show",
+	Short:  "Print the claims of current API token",
+	Args:   cobra.NoArgs,
+	PreRun: checkConfigAndSetToken,
+	Run: func(cmd *cobra.Command, args []string) {
+		var (
+			now = time.Now()
+			exp = apiToken.ExpiresAtTime()
+			rem string
+		)
+
+		if exp.After(now) {
+			rem = fmt.Sprintf("in %s", exp.Sub(now).Round(time.Second))
+		} else {
+			rem = fmt.Sprintf("%s ago", now.Sub(exp).Round(time.Second))
+		}
+
+		t := tabwriter.NewWriter(goos.Stdout, 4, 0, 1, ' ', 0)
+		fmt.Fprintf(t, "app_id: \t%s\n", apiToken.AppID)
+		fmt.Fprintf(t, "project_id: \t%s\n", apiToken.ProjectID)
+		fmt.Fprintf(t, "device_id: \t%s\n", apiToken.DeviceID)
+		fmt.Fprintf(t, "config_id: \t%s\n", apiToken.ConfigID)
+		fmt.Fprintf(t, "scopes: \t%s\n", strings.Join(apiToken.Scopes(), ", "))
+		fmt.Fprintf(t, "issuer: \t%s\n", apiToken.Issuer)
+		fmt.Fprintf(t, "audience: \t%s\n", apiToken.Audience)
+		fmt.Fprintf(t, "issued_at: \t%s\n", apiToken.IssuedAtTime().Format(time.RFC3339))
+		fmt.Fprintf(t, "expires_at: \t%s (%s)\n", exp.Format(time.RFC3339), rem)
+		ensure(t.Flush())
+	},
+}
+
+var tokenRefreshCmd = &cobra.Command{
+	Use:   "refresh",
+	Short: "Fetch a new API token from Speechly Identity API, replacing the cached one",
+	Args:  cobra.NoArgs,
+	PreRun: func(cmd *cobra.Command, args []string) {
+		checkConfig(cmd, args)
+		removeCachedToken(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		setToken(cmd, args)
+		log.Infof("API token refreshed, expires at %s", apiToken.ExpiresAtTime().Format(time.RFC3339))
+	},
+}
+
+var tokenPrintCmd = &cobra.Command{
+	Use:    "print",
+	Short:  "Print the raw JWT of current API token, e.g. for use in scripts",
+	Args:   cobra.NoArgs,
+	PreRun: checkConfigAndSetToken,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println(apiToken.String())
+	},
+}
+
+var tokenClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove the cached API token of current profile",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		if !clearAllTokens {
+			removeCachedToken(cmd, args)
+			return
+		}
+
+		c, err := getTokenCache()
+		ensure(err)
+		ensure(c.Clear())
+	},
+}
+
+func init() {
+	tokenClearCmd.Flags().BoolVar(&clearAllTokens, "all", false, "Remove cached API tokens of all profiles.")
+
+	tokenCmd.AddCommand(tokenShowCmd, tokenRefreshCmd, tokenPrintCmd, tokenClearCmd)
+	rootCmd.AddCommand(tokenCmd)
+}
+
+func checkConfigAndSetToken(cmd *cobra.Command, args []string) {
+	checkConfig(cmd, args)
+	setToken(cmd, args)
+}
 
 func setToken(cmd *cobra.Command, args []string) { // nolint: unparam
+	c, err := getTokenCache()
+	ensure(err)
+
 	token, err := application.GetAPIToken(
-		cmd.Context(), getTokenPath(), config.IdentityURL, config.AppID, config.DeviceID, log,
+		cmd.Context(), c, getTokenKey(getProfileName()),
+		config.IdentityURL, config.AppID, config.DeviceID, log, pgrpc.WithTLS(config.TLS),
 	)
 	ensure(err)
 	apiToken = token
 }
 
 func removeCachedToken(cmd *cobra.Command, args []string) {
-	if err := os.Remove(getTokenPath()); err != nil && !os.IsNotExist(err) {
+	removeProfileToken(getProfileName())
+}
+
+func removeProfileToken(profile string) {
+	c, err := getTokenCache()
+	if err == nil {
+		err = c.Delete(getTokenKey(profile))
+	}
+
+	if err != nil {
 		log.Errorf("Error deleting cached API token: %s", err)
 	}
 }
 
-func getTokenPath() string {
-	if configFilePath != "" ||
-		appID != "" || deviceID != "" || languageCode != "" ||
-		sluURL != "" || identityURL != "" {
-		// base32 custom params together for the filename
-		b := bytes.NewBufferString(
-			fmt.Sprintf("%s%s%s%s%s%s", configFilePath, appID, deviceID, languageCode, sluURL, identityURL),
-		)
-		f := base32.StdEncoding.EncodeToString(b.Bytes())
+func getTokenCache() (*tokencache.Cache, error) {
+	dir, err := tokencache.DefaultDir()
+	if err != nil {
+		return nil, err
+	}
+
+	var opts []tokencache.Option
+	if config.Verifier != nil {
+		opts = append(opts, tokencache.WithVerifier(config.Verifier))
+	}
+
+	return tokencache.New(dir, opts...)
+}
+
+// getTokenKey returns the token cache key of specified profile.
+func getTokenKey(profile string) string {
+	// Make sure we don't mix up tokens for different config files / profiles.
+	k := []string{getConfigFilePath(), profile}
 
-		// Make sure we don't mix up tokens for different config files / custom identity URLs.
-		p := filepath.Join(os.TempDir(), fmt.Sprintf("%s_%s", f, tokenFilename))
-		log.Debugf("Using temporary token from '%s'", p)
-		return p
+	// Make sure we don't mix up tokens for custom identity URLs, app IDs, etc.
+	for _, c := range []string{
+		configKeyAppID, configKeyDeviceID, configKeyLanguageCode, configKeySluURL, configKeyIdentityURL,
+	} {
+		if isConfigOverridden(c) {
+			k = append(k, c+"="+viper.GetString(c))
+		}
 	}
 
-	return filepath.Join(getConfigDir(), tokenFilename)
+	return strings.Join(k, "\n")
 }
@@ -3,6 +3,7 @@ package command
 import (
 	"context"
 	"encoding/binary"
+	goos "os"
 	"syscall"
 
 	"github.com/spf13/cobra"
@@ -12,6 +13,9 @@ import (
 	"github.com/speechly/slu-client/pkg/audio/wav"
 )
 
+// stdoutPath is the file path that refers to STDOUT.
+const stdoutPath = "-"
+
 var wavCmd = &cobra.Command{
 	Use:   "wav",
 	Short: "Interact with WAV audio files",
@@ -25,8 +29,30 @@ var wavPlayCmd = &cobra.Command{
 		log.Info("Starting playback...")
 
 		err := os.WithSignal(cmd.Context(), func(ctx context.Context) error {
-			p, err := wav.NewFilePlayer(args[0], bufferSize, binary.LittleEndian, log)
-			ensure(err)
+			dev, err := getOutputDevice()
+			if err != nil {
+				return err
+			}
+
+			r, err := wav.NewFileReader(args[0], bufferSize, binary.LittleEndian)
+			if err != nil {
+				return err
+			}
+
+			c, err := audio.ParseFilterChain(filterSpec, r.Format())
+			if err != nil {
+				if err := r.Close(); err != nil {
+					log.Warn("Error closing WAV reader:", err)
+				}
+
+				return err
+			}
+
+			p, err := audio.NewDevicePlayer(dev, audio.NewFilteredSource(r, c), log)
+			if err != nil {
+				return err
+			}
+
 			defer func() {
 				if err := p.Close(); err != nil {
 					log.Warn("Error closing WAV player:", err)
@@ -43,18 +69,29 @@ var wavPlayCmd = &cobra.Command{
 
 var wavRecordCmd = &cobra.Command{
 	Use:   "record filepath",
-	Short: "Record audio from microphone to a WAV file",
+	Short: "Record audio from microphone to a WAV file, use '-' to write to STDOUT",
 	Args:  cobra.ExactArgs(1),
 	Run: func(cmd *cobra.Command, args []string) {
 		log.Info("Starting recording, press Ctrl+C to finish...")
 
 		err := os.WithSignal(cmd.Context(), func(ctx context.Context) error {
-			format, err := audio.NewFormat(defaultChanCount, defaultSampleRate, defaultBitDepth)
+			format, err := getInputFormat()
+			if err != nil {
+				return err
+			}
+
+			dev, err := getInputDevice()
 			if err != nil {
 				return err
 			}
 
-			rec, err := wav.NewFileRecorder(args[0], format, bufferSize, log)
+			var rec *audio.Recorder
+			if args[0] == stdoutPath {
+				rec, err = wav.NewDeviceRecorder(dev, goos.Stdout, format, bufferSize, log)
+			} else {
+				rec, err = wav.NewDeviceFileRecorder(dev, args[0], format, bufferSize, log)
+			}
+
 			if err != nil {
 				return err
 			}
@@ -62,6 +99,10 @@ var wavRecordCmd = &cobra.Command{
 				if err := rec.Close(); err != nil {
 					log.Warn("Error closing WAV recorder:", err)
 				}
+
+				if n := rec.Overflows(); n > 0 {
+					log.Warnf("Audio input overflowed %d times during recording, some audio was lost", n)
+				}
 			}()
 
 			return rec.Record(ctx)
@@ -77,6 +118,10 @@ var wavRecordCmd = &cobra.Command{
 }
 
 func init() {
+	addOutputFlags(wavPlayCmd)
+	addFilterFlag(wavPlayCmd)
+	addInputFlags(wavRecordCmd)
+
 	wavCmd.AddCommand(wavPlayCmd, wavRecordCmd)
 	rootCmd.AddCommand(wavCmd)
 }
@@ -0,0 +1,85 @@
+package application
+
+import (
+	"time"
+
+	"github.com/speechly/slu-client/pkg/audio"
+	"github.com/speechly/slu-client/pkg/logger"
+	"github.com/speechly/slu-client/pkg/speechly/slu"
+)
+
+// Limits of the size of adaptive chunks.
+const (
+	minAdaptiveChunk = 20 * time.Millisecond
+	maxAdaptiveChunk = time.Second
+)
+
+// Chunking controls the size of audio chunks sent to SLU API, one chunk per message.
+// Every chunk of live audio adds its duration to the latency of results, but smaller chunks mean more messages.
+type Chunking struct {
+	// Duration is the duration of chunks, or of the first chunk if Adaptive is set.
+	// If it is zero, chunks are sent as they are read from the audio source.
+	Duration time.Duration
+
+	// Adaptive makes chunks of files grow up to a second, for better throughput,
+	// and chunks of live audio shrink down to 20 ms while the network keeps up with them, for lower latency.
+	Adaptive bool
+}
+
+// recordDuration returns the duration of audio that should be captured at a time from live audio sources,
+// so that chunks can be as small as needed, or zero if chunks are not sized by duration.
+func (c Chunking) recordDuration() time.Duration {
+	switch {
+	case c.Duration <= 0:
+		return 0
+	case c.Adaptive:
+		return minAdaptiveChunk
+	default:
+		return c.Duration
+	}
+}
+
+// recordSize returns the size of the buffer for recording live audio in format f, in samples,
+// or bufSize if chunks are not sized by duration.
+func (c Chunking) recordSize(f audio.Format, bufSize int) int {
+	if d := c.recordDuration(); d > 0 {
+		return audio.ChunkSize(f, d)
+	}
+
+	return bufSize
+}
+
+// newChunkedSource returns src wrapped so that it writes chunks as specified by c, or src itself if c has no duration.
+// live tells whether src is live audio, which affects adaptive chunking. src is closed if c is not valid.
+func newChunkedSource(
+	src slu.AudioSource, f audio.Format, c Chunking, live bool, log logger.Logger,
+) (slu.AudioSource, error) {
+	if c.Duration <= 0 {
+		return src, nil
+	}
+
+	var (
+		opts  []audio.ChunkOption
+		limit = maxAdaptiveChunk
+	)
+
+	if c.Duration > limit {
+		limit = c.Duration
+	}
+
+	switch {
+	case !c.Adaptive:
+	case live:
+		opts = append(opts, audio.WithAdaptiveChunks(minAdaptiveChunk, limit))
+	default:
+		opts = append(opts, audio.WithGrowingChunks(limit))
+	}
+
+	s, err := audio.NewChunkedStream(src, f, c.Duration, opts...)
+	if err != nil {
+		closeAndLog(src, "Error closing audio source", log)
+		return nil, err
+	}
+
+	return s, nil
+}
@@ -0,0 +1,188 @@
+package application
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/speechly/slu-client/pkg/audio"
+	"github.com/speechly/slu-client/pkg/logger"
+)
+
+// chunkSource is an audio source that writes size bytes of silence in chunks of chunkSize bytes.
+type chunkSource struct {
+	size      int
+	chunkSize int
+	closed    bool
+}
+
+func (s *chunkSource) WriteTo(w io.Writer) (int64, error) {
+	n := s.chunkSize
+	if n > s.size {
+		n = s.size
+	}
+
+	s.size -= n
+
+	written, err := w.Write(make([]byte, n))
+	if err == nil && s.size == 0 {
+		err = io.EOF
+	}
+
+	return int64(written), err
+}
+
+func (s *chunkSource) Close() error {
+	s.closed = true
+	return nil
+}
+
+// testFormats are 16-bit formats of narrowband and wideband audio, with one and two channels.
+var testFormats = []audio.Format{
+	{NumChannels: 1, SampleRateHertz: 8000, BitDepth: audio.BitDepth16},
+	{NumChannels: 1, SampleRateHertz: 16000, BitDepth: audio.BitDepth16},
+	{NumChannels: 2, SampleRateHertz: 8000, BitDepth: audio.BitDepth16},
+	{NumChannels: 2, SampleRateHertz: 16000, BitDepth: audio.BitDepth16},
+}
+
+func TestChunkingRecordSize(t *testing.T) {
+	tests := []struct {
+		name  string
+		chunk Chunking
+		want  []int // Samples for every one of testFormats.
+	}{
+		{name: "sent as read", chunk: Chunking{}, want: []int{2048, 2048, 2048, 2048}},
+		{name: "fixed", chunk: Chunking{Duration: 100 * time.Millisecond}, want: []int{800, 1600, 1600, 3200}},
+		{
+			// Live audio is recorded in the smallest adaptive chunks, so that chunks can shrink down to them.
+			name:  "adaptive",
+			chunk: Chunking{Duration: 100 * time.Millisecond, Adaptive: true},
+			want:  []int{160, 320, 320, 640},
+		},
+	}
+
+	for _, tt := range tests {
+		for i, f := range testFormats {
+			if got := tt.chunk.recordSize(f, 2048); got != tt.want[i] {
+				t.Errorf("%s: recordSize(%+v) = %d, want %d", tt.name, f, got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestNewChunkedSource(t *testing.T) {
+	log := logger.NewStdLogger(io.Discard)
+
+	for _, f := range testFormats {
+		// Bytes in 20ms of audio.
+		c := int(f.SampleRateHertz) / 50 * int(f.NumChannels) * 2
+
+		tests := []struct {
+			name  string
+			chunk Chunking
+			live  bool
+			size  int
+			want  []int
+		}{
+			{name: "fixed", chunk: Chunking{Duration: 20 * time.Millisecond}, size: 3*c + c/2, want: []int{c, c, c, c / 2}},
+			{
+				name:  "fixed live",
+				chunk: Chunking{Duration: 20 * time.Millisecond},
+				live:  true,
+				size:  2*c + c/2,
+				want:  []int{c, c, c / 2},
+			},
+			{
+				// Chunks of files double in size.
+				name:  "adaptive file",
+				chunk: Chunking{Duration: 20 * time.Millisecond, Adaptive: true},
+				size:  7*c + c/2,
+				want:  []int{c, 2 * c, 4 * c, c / 2},
+			},
+			{
+				// Chunks of files grow up to a second.
+				name:  "adaptive file limit",
+				chunk: Chunking{Duration: 500 * time.Millisecond, Adaptive: true},
+				size:  125 * c,
+				want:  []int{25 * c, 50 * c, 50 * c},
+			},
+		}
+
+		for _, tt := range tests {
+			src := &chunkSource{size: tt.size, chunkSize: 99}
+
+			s, err := newChunkedSource(src, f, tt.chunk, tt.live, log)
+			if err != nil {
+				t.Fatalf("%s: newChunkedSource() error = %v", tt.name, err)
+			}
+
+			var chunks []int
+
+			for {
+				n, err := s.WriteTo(io.Discard)
+				chunks = append(chunks, int(n))
+
+				if err == io.EOF {
+					break
+				}
+
+				if err != nil {
+					t.Fatalf("%s: WriteTo() error = %v", tt.name, err)
+				}
+
+				if len(chunks) > len(tt.want) {
+					break
+				}
+			}
+
+			if !equalInts(chunks, tt.want) {
+				t.Errorf("%s: %d Hz, %d channels: chunks = %v, want %v",
+					tt.name, f.SampleRateHertz, f.NumChannels, chunks, tt.want)
+			}
+		}
+	}
+}
+
+func TestNewChunkedSourceWithoutDuration(t *testing.T) {
+	src := &chunkSource{size: 100, chunkSize: 10}
+
+	s, err := newChunkedSource(src, testFormats[0], Chunking{}, false, logger.NewStdLogger(io.Discard))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Chunks are sent as they are read from the source.
+	if s != src {
+		t.Errorf("newChunkedSource() = %T, want the source itself", s)
+	}
+}
+
+func TestNewChunkedSourceInvalid(t *testing.T) {
+	var (
+		src = &chunkSource{}
+		f   = audio.Format{NumChannels: 1, SampleRateHertz: 16000, BitDepth: audio.BitDepth(24)}
+	)
+
+	_, err := newChunkedSource(src, f, Chunking{Duration: time.Second}, false, logger.NewStdLogger(io.Discard))
+	if err == nil {
+		t.Error("newChunkedSource() with invalid format succeeded")
+	}
+
+	if !src.closed {
+		t.Error("source was not closed")
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
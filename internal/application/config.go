@@ -2,9 +2,13 @@ package application
 
 import (
 	"net/url"
+	"strings"
 
 	"github.com/google/uuid"
 	"golang.org/x/text/language"
+
+	pgrpc "github.com/speechly/slu-client/pkg/grpc"
+	"github.com/speechly/slu-client/pkg/speechly"
 )
 
 // Config is the configuration of the CLI app.
@@ -14,6 +18,9 @@ type Config struct {
 	AppID        uuid.UUID
 	DeviceID     uuid.UUID
 	LanguageCode language.Tag
+	TLS          pgrpc.TLSConfig
+	Verifier     *speechly.Verifier
+	Limits       ContextLimits
 	isValid      bool
 }
 
@@ -54,7 +61,65 @@ func (c *Config) Parse(sluURL, identityURL, appID, deviceID, languageCode string
 	return nil
 }
 
+// ParseTLS parses the TLS configuration from provided values.
+// pins is a comma-separated list of base64-encoded SHA-256 SPKI hashes.
+func (c *Config) ParseTLS(caFile, certFile, keyFile, serverName, pins string, insecureSkipVerify bool) error {
+	if (certFile == "") != (keyFile == "") {
+		return pgrpc.ErrInvalidKeyPair
+	}
+
+	c.TLS = pgrpc.TLSConfig{
+		CAFile:             caFile,
+		CertFile:           certFile,
+		KeyFile:            keyFile,
+		ServerName:         serverName,
+		PinnedSPKI:         splitList(pins),
+		InsecureSkipVerify: insecureSkipVerify,
+	}
+
+	return nil
+}
+
+// ParseTokenVerification parses the configuration of access token verification from provided values.
+// If keyFile is empty, tokens are not verified. Otherwise it must be a PEM public key or a JWKS file.
+// algs is a comma-separated list of allowed signing algorithms, empty issuer and audience are not checked.
+func (c *Config) ParseTokenVerification(keyFile, algs, issuer, audience string) error {
+	if keyFile == "" {
+		c.Verifier = nil
+		return nil
+	}
+
+	opts := []speechly.VerifierOption{
+		speechly.WithIssuer(issuer),
+		speechly.WithAudience(audience),
+	}
+
+	if a := splitList(algs); len(a) > 0 {
+		opts = append(opts, speechly.WithAlgorithms(a...))
+	}
+
+	v, err := speechly.NewVerifierFromFile(keyFile, opts...)
+	if err != nil {
+		return err
+	}
+
+	c.Verifier = v
+
+	return nil
+}
+
 // IsValid returns true if the config is valid and false otherwise.
 func (c *Config) IsValid() bool {
 	return c.isValid
 }
+
+func splitList(s string) []string {
+	var l []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			l = append(l, v)
+		}
+	}
+
+	return l
+}
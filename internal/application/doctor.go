@@ -0,0 +1,294 @@
+package application
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/speechly/slu-client/pkg/audio"
+	pgrpc "github.com/speechly/slu-client/pkg/grpc"
+	"github.com/speechly/slu-client/pkg/logger"
+	"github.com/speechly/slu-client/pkg/speechly"
+	"github.com/speechly/slu-client/pkg/speechly/identity"
+	"github.com/speechly/slu-client/pkg/speechly/slu"
+)
+
+// CheckStatus is the status of a diagnostic check.
+type CheckStatus string
+
+// Possible values of CheckStatus.
+const (
+	CheckPassed  = CheckStatus("PASS")
+	CheckFailed  = CheckStatus("FAIL")
+	CheckSkipped = CheckStatus("SKIP")
+)
+
+// CheckResult is the result of a single diagnostic check.
+type CheckResult struct {
+	Name     string
+	Status   CheckStatus
+	Duration time.Duration
+	Detail   string
+	Err      error
+	Hint     string // Remediation hint, only set for failed checks.
+}
+
+const (
+	sampleRate      = 16000
+	sampleDuration  = time.Second
+	sampleChunks    = 10
+	sampleFrequency = 440
+	sampleAmplitude = 0.1
+)
+
+var sampleFormat = audio.Format{NumChannels: 1, SampleRateHertz: sampleRate, BitDepth: audio.BitDepth16}
+
+// Diagnose runs end-to-end diagnostics of CLI configuration, API connectivity and audio input.
+// cfgErr is the error of parsing cfg, if any. Every check is run with specified timeout.
+// Results of checks are passed to report as soon as they are done.
+// Checks that depend on failed checks are skipped. Diagnose returns true if all checks have passed.
+func Diagnose(
+	ctx context.Context, cfg Config, cfgErr error, timeout time.Duration, report func(CheckResult), log logger.Logger,
+) bool {
+	d := doctor{ctx: ctx, timeout: timeout, report: report, ok: true}
+
+	if cfgErr == nil && !cfg.IsValid() {
+		cfgErr = errors.New("configuration is missing")
+	}
+
+	cfgOK := d.check("configuration", "run 'config generate' to create configuration or 'config print' to inspect it",
+		false, func(context.Context) (string, error) {
+			if cfgErr != nil {
+				return "", cfgErr
+			}
+
+			return fmt.Sprintf("app %s, language %s", cfg.AppID, cfg.LanguageCode), nil
+		},
+	)
+
+	identityOK := d.checkHost("Identity API", "identity_url", cfg.IdentityURL, cfg.TLS, !cfgOK, log)
+	sluOK := d.checkHost("SLU API", "slu_url", cfg.SluURL, cfg.TLS, !cfgOK, log)
+
+	var token speechly.AccessToken
+
+	loginOK := d.check("identity login", "check that app_id is correct and the app is deployed in Speechly Dashboard",
+		!identityOK, func(ctx context.Context) (string, error) {
+			var err error
+
+			token, err = identity.GetAccessToken(ctx, cfg.IdentityURL, cfg.AppID, cfg.DeviceID, log, pgrpc.WithTLS(cfg.TLS))
+			if err != nil {
+				return "", err
+			}
+
+			return fmt.Sprintf("token expires at %s", token.ExpiresAtTime().Format(time.RFC3339)), nil
+		},
+	)
+
+	tokenOK := d.check("access token", "check token_verify_* configuration, or report the issue to Speechly",
+		!loginOK, func(context.Context) (string, error) {
+			if token.AppID != "" && token.AppID != cfg.AppID.String() {
+				return "", fmt.Errorf("token was issued for app %s", token.AppID)
+			}
+
+			if cfg.Verifier != nil {
+				if err := cfg.Verifier.Verify(token); err != nil {
+					return "", err
+				}
+			}
+
+			return fmt.Sprintf("app %s, project %s, scopes %s", token.AppID, token.ProjectID, token.Scope), nil
+		},
+	)
+
+	d.check("SLU recognition", "check that language_code is supported by the app and the token grants 'slu' scope",
+		!(tokenOK && sluOK), func(ctx context.Context) (string, error) {
+			return recogniseSample(ctx, cfg, token, log)
+		},
+	)
+
+	d.check("audio input", "check that a microphone is connected and the OS allows this program to access it",
+		false, func(context.Context) (string, error) {
+			name, err := audio.ProbeDefaultInput(sampleFormat)
+			if err != nil {
+				return "", err
+			}
+
+			return fmt.Sprintf("default input device '%s'", name), nil
+		},
+	)
+
+	return d.ok
+}
+
+type doctor struct {
+	ctx     context.Context
+	timeout time.Duration
+	report  func(CheckResult)
+	ok      bool
+}
+
+// check runs fn as a check, unless it is skipped, and reports its result. It returns true if the check has passed.
+func (d *doctor) check(name, hint string, skip bool, fn func(context.Context) (string, error)) bool {
+	res := CheckResult{
+		Name:   name,
+		Status: CheckSkipped,
+	}
+
+	if skip {
+		d.ok = false
+		d.report(res)
+		return false
+	}
+
+	ctx, cancel := context.WithTimeout(d.ctx, d.timeout)
+	defer cancel()
+
+	start := time.Now()
+	res.Detail, res.Err = fn(ctx)
+	res.Duration = time.Since(start)
+
+	if res.Err != nil {
+		res.Status = CheckFailed
+		res.Hint = hint
+		d.ok = false
+	} else {
+		res.Status = CheckPassed
+	}
+
+	d.report(res)
+
+	return res.Err == nil
+}
+
+// checkHost checks that the host of API URL u can be resolved and dialled.
+func (d *doctor) checkHost(api, key string, u url.URL, tlsCfg pgrpc.TLSConfig, skip bool, log logger.Logger) bool {
+	host := u.Hostname()
+
+	ok := d.check("resolve "+api+" host", fmt.Sprintf("check that %s is correct and DNS works on this host", key),
+		skip, func(ctx context.Context) (string, error) {
+			addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+			if err != nil {
+				return "", err
+			}
+
+			return fmt.Sprintf("%s resolved to %s", host, strings.Join(addrs, ", ")), nil
+		},
+	)
+
+	hint := fmt.Sprintf(
+		"check that %s port is reachable from this host, that the URL scheme ('grpc+tls://' or 'grpc://') "+
+			"matches the server and that tls_* configuration is correct", key,
+	)
+
+	return d.check("dial "+api, hint, !ok, func(ctx context.Context) (string, error) {
+		c, err := pgrpc.NewClient(api, u, log, pgrpc.WithTLS(tlsCfg), pgrpc.WithBlock())
+		if err != nil {
+			return "", err
+		}
+
+		if err := c.Dial(ctx); err != nil {
+			return "", err
+		}
+
+		closeAndLog(c, "Error closing gRPC client", log)
+
+		return fmt.Sprintf("connected to %s", u.Host), nil
+	})
+}
+
+// recogniseSample runs the built-in audio sample through an audio context of a new SLU stream.
+func recogniseSample(ctx context.Context, cfg Config, token speechly.AccessToken, log logger.Logger) (string, error) {
+	cli, stream, err := newStream(ctx, cfg, token, slu.Config{
+		NumChannels:     sampleFormat.NumChannels,
+		SampleRateHertz: sampleFormat.SampleRateHertz,
+		LanguageCode:    cfg.LanguageCode,
+	}, nil, log)
+	if err != nil {
+		return "", err
+	}
+
+	defer func() {
+		closeAndLog(stream, "Error closing SLU stream", log)
+		closeAndLog(cli, "Error closing SLU client", log)
+	}()
+
+	h, err := stream.NewAudioContext(ctx, newSampleSource(), 1)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		n   int
+		res slu.AudioContext
+	)
+
+	for {
+		r, err := h.Read()
+		if err == io.EOF {
+			break
+		}
+
+		res = r
+		n++
+	}
+
+	// Errors of the context are only returned when it is closed.
+	err = h.Close()
+
+	// The sample does not contain speech, so it is fine if no segments are recognised.
+	if err != nil && !errors.Is(err, slu.ErrNoSegments) {
+		return "", err
+	}
+
+	return fmt.Sprintf("context %s finished, %d responses, %d segments", res.ID, n, len(res.Segments)), nil
+}
+
+// sampleSource is an audio source of a built-in synthetic audio sample, which is a short sine tone.
+type sampleSource struct {
+	data []byte
+	off  int
+	size int
+}
+
+func newSampleSource() *sampleSource {
+	n := int(sampleRate * sampleDuration / time.Second)
+	d := make([]byte, n*2)
+
+	for i := 0; i < n; i++ {
+		v := sampleAmplitude * math.MaxInt16 * math.Sin(2*math.Pi*sampleFrequency*float64(i)/sampleRate)
+		binary.LittleEndian.PutUint16(d[i*2:], uint16(int16(v)))
+	}
+
+	return &sampleSource{
+		data: d,
+		size: len(d) / sampleChunks,
+	}
+}
+
+// WriteTo writes the next chunk of the sample to w, it returns io.EOF with the last chunk.
+func (s *sampleSource) WriteTo(w io.Writer) (int64, error) {
+	end := s.off + s.size
+	if end > len(s.data) {
+		end = len(s.data)
+	}
+
+	n, err := w.Write(s.data[s.off:end])
+	s.off += n
+
+	if err == nil && s.off >= len(s.data) {
+		err = io.EOF
+	}
+
+	return int64(n), err
+}
+
+func (s *sampleSource) Close() error {
+	return nil
+}
@@ -0,0 +1,90 @@
+package application
+
+import (
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/speechly/slu-client/pkg/audio"
+	"github.com/speechly/slu-client/pkg/audio/pcm"
+	"github.com/speechly/slu-client/pkg/audio/wav"
+	"github.com/speechly/slu-client/pkg/speechly/slu"
+)
+
+// StdinPath is the path of an audio file that refers to STDIN.
+const StdinPath = "-"
+
+// minSampleRate is the lowest sample rate sent to SLU API, audio with lower sample rates
+// (e.g. 8 kHz telephony audio) is upsampled to it.
+const minSampleRate = 16000
+
+// RawFormat is the format of headerless PCM audio files.
+type RawFormat struct {
+	Encoding    pcm.Encoding
+	NumChannels int
+	SampleRate  int
+}
+
+// audioFile is an audio source with a known format.
+type audioFile interface {
+	slu.AudioSource
+	Format() audio.Format
+}
+
+// openAudioFile opens a WAV file specified by path, or a raw PCM file if raw is not nil.
+// Audio is decoded into 16-bit linear PCM, which is the only encoding SLU API accepts,
+// and upsampled to minSampleRate, if necessary.
+func openAudioFile(path string, raw *RawFormat, bufSize int) (audioFile, error) {
+	var src io.ReadCloser
+
+	if path == StdinPath {
+		src = io.NopCloser(os.Stdin)
+	} else {
+		// nolint: gosec // Paths are specified by the user on purpose.
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		src = f
+	}
+
+	var (
+		r   audioFile
+		err error
+	)
+
+	if raw != nil {
+		r, err = pcm.NewReader(src, raw.Encoding, raw.NumChannels, raw.SampleRate, bufSize, binary.LittleEndian)
+	} else {
+		r, err = wav.NewReader(src, bufSize, binary.LittleEndian)
+	}
+
+	if err != nil {
+		_ = src.Close()
+		return nil, err
+	}
+
+	if f := r.Format(); f.BitDepth != audio.BitDepth16 {
+		c, err := audio.NewConvertedStream(r, f, binary.LittleEndian, audio.BitDepth16)
+		if err != nil {
+			_ = r.Close()
+			return nil, err
+		}
+
+		r = c
+	}
+
+	f := r.Format()
+	if f.SampleRateHertz >= minSampleRate {
+		return r, nil
+	}
+
+	s, err := audio.NewResampledStream(r, f, binary.LittleEndian, minSampleRate)
+	if err != nil {
+		_ = r.Close()
+		return nil, err
+	}
+
+	return s, nil
+}
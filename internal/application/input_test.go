@@ -0,0 +1,196 @@
+package application
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/speechly/slu-client/pkg/audio"
+	"github.com/speechly/slu-client/pkg/audio/pcm"
+	"github.com/speechly/slu-client/pkg/audio/wav"
+)
+
+// testSamples are 16-bit samples that are exactly representable in all bit depths.
+var testSamples = []int16{0, 256, -256, 16384, -16384, 32512, -32768}
+
+// encodeTestSamples encodes testSamples with encoding e.
+func encodeTestSamples(e pcm.Encoding) []byte {
+	var buf bytes.Buffer
+
+	for _, v := range testSamples {
+		switch {
+		case e.Unsigned:
+			buf.WriteByte(byte(v>>8) + 128)
+		case e.BitDepth == audio.BitDepth8:
+			buf.WriteByte(byte(v >> 8))
+		case e.BitDepth == audio.BitDepth16:
+			_ = binary.Write(&buf, e.Order, v)
+		case e.BitDepth == audio.BitDepth32:
+			_ = binary.Write(&buf, e.Order, int32(v)<<16)
+		default:
+			_ = binary.Write(&buf, e.Order, int64(v)<<48)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// readTestFile reads all audio from a file opened with openAudioFile and checks that it's 16-bit testSamples.
+func readTestFile(t *testing.T, path string, raw *RawFormat) {
+	t.Helper()
+
+	r, err := openAudioFile(path, raw, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if d := r.Format().BitDepth; d != audio.BitDepth16 {
+		t.Errorf("Format().BitDepth = %d, want 16", d)
+	}
+
+	var buf bytes.Buffer
+
+	for {
+		_, err := r.WriteTo(&buf)
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got := make([]int16, buf.Len()/2)
+	if err := binary.Read(&buf, binary.LittleEndian, got); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != len(testSamples) {
+		t.Fatalf("got %d samples, want %d", len(got), len(testSamples))
+	}
+
+	for i, v := range got {
+		if v != testSamples[i] {
+			t.Errorf("sample %d = %d, want %d", i, v, testSamples[i])
+		}
+	}
+}
+
+func TestOpenAudioFileRawEncodings(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, name := range []string{"u8", "s8", "s16le", "s16be", "s32le", "s32be", "s64le", "s64be"} {
+		name := name
+
+		t.Run(name, func(t *testing.T) {
+			e, err := pcm.ParseEncoding(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			path := filepath.Join(dir, name+".raw")
+			if err := os.WriteFile(path, encodeTestSamples(e), 0o600); err != nil {
+				t.Fatal(err)
+			}
+
+			readTestFile(t, path, &RawFormat{Encoding: e, NumChannels: 1, SampleRate: minSampleRate})
+		})
+	}
+}
+
+// writeTestWAV writes testSamples into a WAV file with bit depth d.
+func writeTestWAV(t *testing.T, path string, d audio.BitDepth) {
+	t.Helper()
+
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	format := audio.Format{NumChannels: 1, SampleRateHertz: minSampleRate, BitDepth: d}
+
+	w, err := wav.NewWriter(f, format, len(testSamples))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ints := make([]int, len(testSamples))
+	for i, v := range testSamples {
+		if d == audio.BitDepth8 {
+			ints[i] = int(v) >> 8
+		} else {
+			ints[i] = int(v) << (uint(d) - 16)
+		}
+	}
+
+	b := w.Buffer()
+	if _, err := b.Write(ints, d); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := w.WriteBuffer(b); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// WAV files of all bit depths are sent as 16-bit samples, e.g. with slu stream --input-file.
+func TestOpenAudioFileWAVBitDepths(t *testing.T) {
+	dir := t.TempDir()
+
+	for _, d := range []audio.BitDepth{audio.BitDepth8, audio.BitDepth16, audio.BitDepth32, audio.BitDepth64} {
+		d := d
+
+		t.Run(strconv.Itoa(int(d)), func(t *testing.T) {
+			path := filepath.Join(dir, strconv.Itoa(int(d))+".wav")
+			writeTestWAV(t, path, d)
+			readTestFile(t, path, nil)
+		})
+	}
+}
+
+// Narrowband audio, e.g. G.711 telephony audio, is upsampled to minSampleRate.
+func TestOpenAudioFileUpsamples(t *testing.T) {
+	const numFrames = 801
+
+	path := filepath.Join(t.TempDir(), "narrowband.ulaw")
+	if err := os.WriteFile(path, bytes.Repeat([]byte{0xFF}, numFrames), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := openAudioFile(path, &RawFormat{Encoding: pcm.EncodingMuLaw, NumChannels: 1, SampleRate: 8000}, 160)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	if f := r.Format(); f.SampleRateHertz != minSampleRate || f.BitDepth != audio.BitDepth16 {
+		t.Errorf("Format() = %+v, want 16-bit audio at %d Hz", f, minSampleRate)
+	}
+
+	var buf bytes.Buffer
+
+	for {
+		_, err := r.WriteTo(&buf)
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, want := buf.Len()/2, 2*numFrames; got != want {
+		t.Errorf("got %d samples, want %d", got, want)
+	}
+}
@@ -0,0 +1,67 @@
+package application
+
+import (
+	"errors"
+	"time"
+
+	"github.com/speechly/slu-client/pkg/logger"
+	"github.com/speechly/slu-client/pkg/speechly/slu"
+)
+
+// ContextLimits limit the duration of audio contexts, so that forgotten or stalled recognition does not run forever.
+// Zero values disable the limits.
+type ContextLimits struct {
+	// MaxAudio is the maximum duration of audio sent in a context, after which the audio is stopped,
+	// as if its source was exhausted, and the results of audio sent so far are still read.
+	MaxAudio time.Duration
+
+	// IdleTimeout is the maximum time to wait for a response from API, once all audio of a context has been sent.
+	IdleTimeout time.Duration
+
+	// Timeout is the maximum duration of a context, from starting it to receiving its last result.
+	Timeout time.Duration
+}
+
+// options returns the options of a new audio context, which has to be started right away.
+func (l ContextLimits) options() []slu.ContextOption {
+	var opts []slu.ContextOption
+
+	if l.MaxAudio > 0 {
+		opts = append(opts, slu.WithMaxAudioDuration(l.MaxAudio))
+	}
+
+	if l.IdleTimeout > 0 {
+		opts = append(opts, slu.WithIdleTimeout(l.IdleTimeout))
+	}
+
+	if l.Timeout > 0 {
+		opts = append(opts, slu.WithDeadline(time.Now().Add(l.Timeout)))
+	}
+
+	return opts
+}
+
+// closeContext closes h after all its results have been read.
+// Timeouts fail the recognition, while reaching the audio limit and other errors of the context are only logged.
+func closeContext(h slu.AudioContextHandler, log logger.Logger) error {
+	err := h.Close()
+
+	var (
+		limit *slu.AudioLimitError
+		idle  *slu.IdleTimeoutError
+		dl    *slu.DeadlineError
+	)
+
+	switch {
+	case err == nil:
+		return nil
+	case errors.As(err, &limit):
+		log.Infof("Audio stopped after reaching maximum duration of %s", limit.Limit)
+		return nil
+	case errors.As(err, &idle), errors.As(err, &dl):
+		return err
+	default:
+		log.Warn("Error closing SLU context", err)
+		return nil
+	}
+}
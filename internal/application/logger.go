@@ -8,13 +8,27 @@ import (
 	"github.com/speechly/slu-client/pkg/logger"
 )
 
-// NewLogger returns a new logger that writes to out with specified level.
-func NewLogger(out io.Writer, level logrus.Level) logger.Logger {
+// NewLogger returns a new logger that writes to out with specified level and format.
+func NewLogger(out io.Writer, level logrus.Level, format logger.Format) logger.FieldLogger {
 	l := logrus.New()
 
-	l.SetFormatter(&logrus.TextFormatter{})
+	if format == logger.FormatJSON {
+		l.SetFormatter(&logrus.JSONFormatter{})
+	} else {
+		l.SetFormatter(&logrus.TextFormatter{})
+	}
+
 	l.SetOutput(out)
 	l.SetLevel(level)
 
-	return l
+	return fieldLogger{logrus.NewEntry(l)}
+}
+
+// fieldLogger adapts logrus.Entry to logger.FieldLogger.
+type fieldLogger struct {
+	*logrus.Entry
+}
+
+func (l fieldLogger) WithFields(f logger.Fields) logger.Logger {
+	return fieldLogger{l.Entry.WithFields(logrus.Fields(f))}
 }
@@ -0,0 +1,51 @@
+package application
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/speechly/slu-client/pkg/logger"
+)
+
+const (
+	metricsPath              = "/metrics"
+	metricsReadHeaderTimeout = 5 * time.Second
+)
+
+// ServeMetrics starts serving metrics from h over HTTP on addr, under the "/metrics" path.
+// The listener is opened synchronously, so that any errors are returned to the caller,
+// after which the server runs in the background until ctx is done.
+func ServeMetrics(ctx context.Context, addr string, h http.Handler, log logger.Logger) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(metricsPath, h)
+
+	srv := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: metricsReadHeaderTimeout,
+	}
+
+	go func() {
+		if err := srv.Serve(l); err != nil && err != http.ErrServerClosed {
+			log.Warn("Error serving metrics", err)
+		}
+	}()
+
+	go func() {
+		<-ctx.Done()
+
+		if err := srv.Close(); err != nil {
+			log.Warn("Error closing metrics server", err)
+		}
+	}()
+
+	log.Infof("Serving metrics at http://%s%s", l.Addr(), metricsPath)
+
+	return nil
+}
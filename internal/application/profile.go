@@ -0,0 +1,195 @@
+package application
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+)
+
+// DefaultProfile is the name of the profile that is used when no profile has been selected.
+// Configuration files without profiles are loaded as a single profile with this name.
+const DefaultProfile = "default"
+
+const (
+	configFilePerms = 0600
+
+	configKeyCurrentProfile = "current_profile"
+	configKeyProfiles       = "profiles"
+)
+
+// Profile errors.
+var (
+	ErrProfileNotFound    = errors.New("profile not found")
+	ErrProfileExists      = errors.New("profile already exists")
+	ErrInvalidProfileName = errors.New("profile name must only contain letters, digits, '-' and '_'")
+)
+
+var profileNameRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// Profile is a named set of configuration values.
+type Profile map[string]interface{}
+
+// ConfigFile is the configuration file of the CLI app, which contains named configuration profiles.
+type ConfigFile struct {
+	CurrentProfile string             `json:"current_profile,omitempty"`
+	Profiles       map[string]Profile `json:"profiles"`
+}
+
+// NewConfigFile returns a new empty ConfigFile.
+func NewConfigFile() *ConfigFile {
+	return &ConfigFile{
+		Profiles: make(map[string]Profile),
+	}
+}
+
+// LoadConfigFile loads the ConfigFile from specified path.
+// A configuration file without profiles is loaded as a single DefaultProfile, which is also the current one.
+func LoadConfigFile(path string) (*ConfigFile, error) {
+	data, err := os.ReadFile(path) // nolint: gosec
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	f := NewConfigFile()
+
+	if _, ok := raw[configKeyProfiles]; !ok {
+		// Legacy config file with a single set of values.
+		var p Profile
+		if err := json.Unmarshal(data, &p); err != nil {
+			return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+
+		delete(p, configKeyCurrentProfile)
+
+		f.CurrentProfile = DefaultProfile
+		f.Profiles[DefaultProfile] = p
+
+		return f, nil
+	}
+
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("invalid config file %s: %w", path, err)
+	}
+
+	if f.Profiles == nil {
+		f.Profiles = make(map[string]Profile)
+	}
+
+	return f, nil
+}
+
+// Save writes the ConfigFile to specified path.
+func (f *ConfigFile) Save(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, append(data, '\n'), configFilePerms)
+}
+
+// ProfileNames returns sorted names of all profiles in the file.
+func (f *ConfigFile) ProfileNames() []string {
+	ns := make([]string, 0, len(f.Profiles))
+	for n := range f.Profiles {
+		ns = append(ns, n)
+	}
+
+	sort.Strings(ns)
+
+	return ns
+}
+
+// Profile returns a copy of the profile with specified name.
+func (f *ConfigFile) Profile(name string) (Profile, error) {
+	p, ok := f.Profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+
+	c := make(Profile, len(p))
+	for k, v := range p {
+		c[k] = v
+	}
+
+	return c, nil
+}
+
+// AddProfile adds a new profile with specified name and values.
+func (f *ConfigFile) AddProfile(name string, p Profile) error {
+	if !profileNameRe.MatchString(name) {
+		return ErrInvalidProfileName
+	}
+
+	if _, ok := f.Profiles[name]; ok {
+		return fmt.Errorf("%w: %s", ErrProfileExists, name)
+	}
+
+	f.Profiles[name] = p
+
+	return nil
+}
+
+// SetValue sets the value of key in the profile with specified name, creating the profile if it does not exist.
+func (f *ConfigFile) SetValue(name, key string, val interface{}) error {
+	if !profileNameRe.MatchString(name) {
+		return ErrInvalidProfileName
+	}
+
+	p, ok := f.Profiles[name]
+	if !ok {
+		p = make(Profile)
+		f.Profiles[name] = p
+	}
+
+	p[key] = val
+
+	return nil
+}
+
+// UnsetValue removes key from the profile with specified name.
+func (f *ConfigFile) UnsetValue(name, key string) error {
+	p, ok := f.Profiles[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+
+	delete(p, key)
+
+	return nil
+}
+
+// UseProfile makes the profile with specified name the current one.
+func (f *ConfigFile) UseProfile(name string) error {
+	if _, ok := f.Profiles[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+
+	f.CurrentProfile = name
+
+	return nil
+}
+
+// RemoveProfile removes the profile with specified name.
+// If it was the current profile, the file is left without a current profile.
+func (f *ConfigFile) RemoveProfile(name string) error {
+	if _, ok := f.Profiles[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrProfileNotFound, name)
+	}
+
+	delete(f.Profiles, name)
+
+	if f.CurrentProfile == name {
+		f.CurrentProfile = ""
+	}
+
+	return nil
+}
@@ -6,34 +6,146 @@ import (
 	"encoding/binary"
 	"encoding/json"
 	"io"
-	"net/url"
+	"sync/atomic"
+	"time"
 
 	"github.com/speechly/slu-client/pkg/audio"
-	"github.com/speechly/slu-client/pkg/audio/wav"
+	pgrpc "github.com/speechly/slu-client/pkg/grpc"
 	"github.com/speechly/slu-client/pkg/logger"
 	"github.com/speechly/slu-client/pkg/speechly"
 	"github.com/speechly/slu-client/pkg/speechly/slu"
 )
 
 // RecogniseMicrophone uses Speechly SLU API to recognise audio from the microphone.
+// Audio is recorded in format fmt and converted into 16-bit samples, if necessary.
+// It is processed with the filter chain specified by filters, see audio.ParseFilterChain,
+// and sent in chunks specified by chunk. If chunks are not sized by duration, bufSize samples are sent at a time.
+// If capBuf is positive, up to capBuf of audio is buffered between capturing and sending it,
+// see audio.WithCaptureBuffer. Once finished, the statistics of captured audio, including any lost audio, are logged.
 func RecogniseMicrophone(
-	ctx context.Context, cfg Config, fmt audio.Format, token speechly.AccessToken, dst io.Writer,
-	enableTentative bool, bufSize int, log logger.Logger,
+	ctx context.Context, cfg Config, dev *audio.Device, fmt audio.Format, filters string, chunk Chunking,
+	capBuf time.Duration, token speechly.AccessToken, dst io.Writer, enableTentative bool, bufSize int,
+	instr slu.Instrumentation, log logger.Logger,
 ) error {
-	rec, err := audio.NewRecordStream(fmt, binary.LittleEndian, bufSize, log)
+	var opts []audio.RecordOption
+	if capBuf > 0 {
+		opts = append(opts, audio.WithCaptureBuffer(capBuf))
+	}
+
+	rec, err := audio.NewDeviceRecordStream(dev, fmt, binary.LittleEndian, chunk.recordSize(fmt, bufSize), log, opts...)
 	if err != nil {
 		return err
 	}
 
+	src, f, err := toLinear16(rec, fmt)
+	if err != nil {
+		closeAndLog(rec, "Error closing audio recorder", log)
+		return err
+	}
+
+	sent, err := recogniseLive(ctx, cfg, src, f, filters, chunk, token, dst, instr, log)
+
+	// Audio written by the recorder may still be buffered by chunking, so only the audio sent counts.
+	stats := rec.Stats()
+	stats.Sent = sent
+	logCaptureStats(stats, log)
+
+	return err
+}
+
+// toLinear16 converts audio from src in format f into 16-bit samples, which is the only encoding SLU API accepts,
+// and returns the converted source with its format. src is returned as it is, if it's 16-bit already.
+func toLinear16(src slu.AudioSource, f audio.Format) (slu.AudioSource, audio.Format, error) {
+	if f.BitDepth == audio.BitDepth16 {
+		return src, f, nil
+	}
+
+	s, err := audio.NewConvertedStream(src, f, binary.LittleEndian, audio.BitDepth16)
+	if err != nil {
+		return nil, f, err
+	}
+
+	return s, s.Format(), nil
+}
+
+func logCaptureStats(s audio.CaptureStats, log logger.Logger) {
+	log.Infof(
+		"Captured %s of audio, sent %s (gap %s), dropped %s, input overflows %d",
+		s.Captured, s.Sent, s.Gap(), s.Dropped, s.Overflows,
+	)
+
+	if s.Overflows > 0 || s.Dropped > 0 {
+		log.Warn("Some audio was lost during capture, consider using a larger capture buffer")
+	}
+}
+
+// RecognisePacedFile uses Speechly SLU API to recognise audio from a WAV file, which is sent at real-time speed
+// multiplied by speed, with every chunk delayed by up to jitter. This simulates streaming from the microphone,
+// including tentative results and latency. StdinPath can be used as path for reading audio from STDIN.
+// WAV files of any bit depth are converted into 16-bit samples, same as with RecogniseFiles.
+// Audio is processed with the filter chain specified by filters, see audio.ParseFilterChain,
+// and sent in chunks specified by chunk, same as audio from the microphone.
+func RecognisePacedFile(
+	ctx context.Context, cfg Config, path string, speed float64, jitter time.Duration, filters string,
+	chunk Chunking, token speechly.AccessToken, dst io.Writer, bufSize int, instr slu.Instrumentation,
+	log logger.Logger,
+) error {
+	r, err := openAudioFile(path, nil, bufSize)
+	if err != nil {
+		return err
+	}
+
+	var (
+		f   = r.Format()
+		src = slu.AudioSource(r)
+	)
+
+	// Audio is released in pieces of the same size as it would be recorded from the microphone.
+	if d := chunk.recordDuration(); d > 0 {
+		if src, err = audio.NewChunkedStream(src, f, d); err != nil {
+			closeAndLog(r, "Error closing audio file reader", log)
+			return err
+		}
+	}
+
+	paced, err := audio.NewPacedStream(src, f, audio.WithSpeed(speed), audio.WithJitter(jitter))
+	if err != nil {
+		closeAndLog(src, "Error closing audio file reader", log)
+		return err
+	}
+
+	_, err = recogniseLive(ctx, cfg, paced, f, filters, chunk, token, dst, instr, log)
+
+	return err
+}
+
+// recogniseLive recognises live audio from src in a single audio context, writing tentative results as well.
+// It returns the duration of audio sent to the API. src is closed once it's recognised.
+func recogniseLive(
+	ctx context.Context, cfg Config, src slu.AudioSource, fmt audio.Format, filters string, chunk Chunking,
+	token speechly.AccessToken, dst io.Writer, instr slu.Instrumentation, log logger.Logger,
+) (time.Duration, error) {
+	src, err := newFilteredSource(src, fmt, filters, log)
+	if err != nil {
+		return 0, err
+	}
+
+	if src, err = newChunkedSource(src, fmt, chunk, true, log); err != nil {
+		return 0, err
+	}
+
+	sent := &countingSource{AudioSource: src}
+
 	c := slu.Config{
 		NumChannels:     fmt.NumChannels,
 		SampleRateHertz: fmt.SampleRateHertz,
 		LanguageCode:    cfg.LanguageCode,
 	}
 
-	cli, stream, err := newStream(ctx, cfg.SluURL, token, c, log)
+	cli, stream, err := newStream(ctx, cfg, token, c, instr, log)
 	if err != nil {
-		return err
+		closeAndLog(src, "Error closing audio source", log)
+		return 0, err
 	}
 
 	defer func() {
@@ -41,26 +153,56 @@ func RecogniseMicrophone(
 		closeAndLog(cli, "Error closing SLU client", log)
 	}()
 
-	return recogniseSrc(ctx, stream, rec, dst, true, log)
+	err = recogniseSrc(ctx, stream, sent, cfg.Limits, dst, true, log)
+
+	return sent.duration(fmt), err
 }
 
-// RecogniseFiles uses Speechly API to recognise audio from WAV files stored on disk.
+// countingSource is an audio source that counts the bytes of audio written by src.
+type countingSource struct {
+	slu.AudioSource
+	n int64
+}
+
+func (s *countingSource) WriteTo(w io.Writer) (int64, error) {
+	n, err := s.AudioSource.WriteTo(w)
+	atomic.AddInt64(&s.n, n)
+
+	return n, err
+}
+
+// duration returns the duration of audio in format f written so far, counting whole frames only.
+func (s *countingSource) duration(f audio.Format) time.Duration {
+	frameSize := int64(f.BitDepth) / 8 * int64(f.NumChannels)
+	if frameSize <= 0 || f.SampleRateHertz <= 0 {
+		return 0
+	}
+
+	frames := time.Duration(atomic.LoadInt64(&s.n) / frameSize)
+
+	return frames * time.Second / time.Duration(f.SampleRateHertz)
+}
+
+// RecogniseFiles uses Speechly API to recognise audio from WAV files, or raw PCM files if raw is not nil.
+// StdinPath can be used in paths for reading audio from STDIN.
+// Audio is processed with the filter chain specified by filters, see audio.ParseFilterChain,
+// and sent in chunks specified by chunk. Files are read bufSize samples at a time.
 func RecogniseFiles(
-	ctx context.Context, cfg Config, token speechly.AccessToken, paths []string, dst io.Writer,
-	enableTentative bool, bufSize int, log logger.Logger,
+	ctx context.Context, cfg Config, token speechly.AccessToken, paths []string, raw *RawFormat, filters string,
+	chunk Chunking, dst io.Writer, enableTentative bool, bufSize int, instr slu.Instrumentation, log logger.Logger,
 ) error {
 	if len(paths[0]) < 1 {
 		return nil
 	}
 
 	// Need to handle first file differently, because we need to access its format to configure recognition stream.
-	read, err := wav.NewFileReader(paths[0], bufSize, binary.LittleEndian)
+	read, err := openAudioFile(paths[0], raw, bufSize)
 	if err != nil {
 		return err
 	}
 	defer func() {
 		if err := read.Close(); err != nil {
-			log.Warn("Error closing WAV file reader", err)
+			log.Warn("Error closing audio file reader", err)
 		}
 	}()
 
@@ -71,7 +213,7 @@ func RecogniseFiles(
 		LanguageCode:    cfg.LanguageCode,
 	}
 
-	cli, stream, err := newStream(ctx, cfg.SluURL, token, c, log)
+	cli, stream, err := newStream(ctx, cfg, token, c, instr, log)
 	if err != nil {
 		return err
 	}
@@ -81,17 +223,27 @@ func RecogniseFiles(
 		closeAndLog(cli, "Error closing SLU client", log)
 	}()
 
-	if err := recogniseSrc(ctx, stream, read, dst, enableTentative, log); err != nil {
+	src, err := newFileSource(read, f, filters, chunk, log)
+	if err != nil {
+		return err
+	}
+
+	if err := recogniseSrc(ctx, stream, src, cfg.Limits, dst, enableTentative, log); err != nil {
 		return err
 	}
 
 	for _, p := range paths[1:] {
-		r, err := wav.NewFileReader(p, bufSize, binary.LittleEndian)
+		r, err := openAudioFile(p, raw, bufSize)
+		if err != nil {
+			return err
+		}
+
+		src, err := newFileSource(r, r.Format(), filters, chunk, log)
 		if err != nil {
 			return err
 		}
 
-		if err := recogniseSrc(ctx, stream, r, dst, enableTentative, log); err != nil {
+		if err := recogniseSrc(ctx, stream, src, cfg.Limits, dst, enableTentative, log); err != nil {
 			return err
 		}
 	}
@@ -100,23 +252,34 @@ func RecogniseFiles(
 }
 
 func recogniseSrc(
-	ctx context.Context, stream slu.RecogniseStream, read slu.AudioSource, dst io.Writer, tent bool, log logger.Logger,
+	ctx context.Context, stream slu.RecogniseStream, read slu.AudioSource, lim ContextLimits, dst io.Writer, tent bool,
+	log logger.Logger,
 ) error {
 	defer closeAndLog(read, "Error closing audio source", log)
 
-	out, err := stream.NewAudioContext(ctx, read, 8)
+	out, err := stream.NewAudioContext(ctx, read, 8, lim.options()...)
 	if err != nil {
 		return err
 	}
-	defer closeAndLog(out, "Error closing SLU context", log)
 
+	err = writeResults(out, dst, tent)
+	if cerr := closeContext(out, log); err == nil {
+		err = cerr
+	}
+
+	return err
+}
+
+// writeResults writes the results read from h into dst as JSON, until h is exhausted.
+// Tentative results are skipped unless tent is true.
+func writeResults(h slu.AudioContextHandler, dst io.Writer, tent bool) error {
 	var (
 		buf = new(bytes.Buffer)
 		enc = json.NewEncoder(buf)
 	)
 
 	for {
-		res, err := out.Read()
+		res, err := h.Read()
 		if err == io.EOF {
 			return nil
 		}
@@ -142,17 +305,13 @@ func recogniseSrc(
 }
 
 func newStream(
-	ctx context.Context, u url.URL, t speechly.AccessToken, c slu.Config, log logger.Logger,
+	ctx context.Context, cfg Config, t speechly.AccessToken, c slu.Config, instr slu.Instrumentation, log logger.Logger,
 ) (*slu.Client, slu.RecogniseStream, error) {
-	cli, err := slu.NewClient(u, t, log)
+	cli, err := newClient(ctx, cfg, t, instr, log)
 	if err != nil {
 		return nil, nil, err
 	}
 
-	if err := cli.Dial(ctx); err != nil {
-		return nil, nil, err
-	}
-
 	stream, err := cli.StreamingRecognise(ctx, c)
 	if err != nil {
 		if err := cli.Close(); err != nil {
@@ -165,8 +324,61 @@ func newStream(
 	return cli, stream, nil
 }
 
+func newClient(
+	ctx context.Context, cfg Config, t speechly.AccessToken, instr slu.Instrumentation, log logger.Logger,
+) (*slu.Client, error) {
+	cli, err := slu.NewClient(cfg.SluURL, t, log, pgrpc.WithTLS(cfg.TLS))
+	if err != nil {
+		return nil, err
+	}
+
+	cli.SetInstrumentation(instr)
+
+	if err := cli.Dial(ctx); err != nil {
+		return nil, err
+	}
+
+	return cli, nil
+}
+
 func closeAndLog(c io.Closer, msg string, log logger.Logger) {
 	if err := c.Close(); err != nil {
 		log.Warn(msg, err)
 	}
 }
+
+// newFileSource returns src of an audio file wrapped with the filter chain specified by filters
+// and chunked as specified by chunk. src is closed on error.
+func newFileSource(
+	src slu.AudioSource, f audio.Format, filters string, chunk Chunking, log logger.Logger,
+) (slu.AudioSource, error) {
+	src, err := newFilteredSource(src, f, filters, log)
+	if err != nil {
+		return nil, err
+	}
+
+	return newChunkedSource(src, f, chunk, false, log)
+}
+
+// newFilteredSource returns src wrapped with the filter chain specified by filters, or src itself if there are none.
+// src is closed if the filter chain is not valid.
+func newFilteredSource(
+	src slu.AudioSource, f audio.Format, filters string, log logger.Logger,
+) (slu.AudioSource, error) {
+	c, err := audio.ParseFilterChain(filters, f)
+	if err == nil && len(c) == 0 {
+		return src, nil
+	}
+
+	var s slu.AudioSource
+	if err == nil {
+		s, err = audio.NewFilteredStream(src, f, binary.LittleEndian, c)
+	}
+
+	if err != nil {
+		closeAndLog(src, "Error closing audio source", log)
+		return nil, err
+	}
+
+	return s, nil
+}
@@ -0,0 +1,42 @@
+package application
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/speechly/slu-client/pkg/audio"
+)
+
+func TestCountingSource(t *testing.T) {
+	for _, f := range testFormats {
+		// 1.5 seconds of audio and an incomplete frame, which is not counted.
+		src := &countingSource{AudioSource: &chunkSource{
+			size:      int(f.SampleRateHertz)*3/2*int(f.NumChannels)*2 + 1,
+			chunkSize: 1000,
+		}}
+
+		if got := src.duration(f); got != 0 {
+			t.Errorf("%+v: duration() before writing = %s, want 0", f, got)
+		}
+
+		for {
+			_, err := src.WriteTo(io.Discard)
+			if err == io.EOF {
+				break
+			}
+
+			if err != nil {
+				t.Fatalf("%+v: WriteTo() error = %v", f, err)
+			}
+		}
+
+		if got, want := src.duration(f), 1500*time.Millisecond; got != want {
+			t.Errorf("%+v: duration() = %s, want %s", f, got, want)
+		}
+	}
+
+	if got := (&countingSource{n: 100}).duration(audio.Format{}); got != 0 {
+		t.Errorf("duration() of invalid format = %s, want 0", got)
+	}
+}
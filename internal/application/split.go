@@ -0,0 +1,251 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sort"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/speechly/slu-client/pkg/audio"
+	"github.com/speechly/slu-client/pkg/logger"
+	"github.com/speechly/slu-client/pkg/speechly"
+	"github.com/speechly/slu-client/pkg/speechly/slu"
+)
+
+// ChannelSegment is an SLU segment recognised from a single channel of a multi-channel audio file,
+// e.g. one side of a call recording.
+type ChannelSegment struct {
+	File      string      `json:"file"`
+	Channel   int         `json:"channel"`
+	Speaker   string      `json:"speaker,omitempty"`
+	StartTime int32       `json:"start_time"`
+	EndTime   int32       `json:"end_time"`
+	Text      string      `json:"text"`
+	Segment   slu.Segment `json:"segment"`
+}
+
+// RecogniseFileChannels uses Speechly API to recognise every channel of WAV files, or raw PCM files if raw is not nil,
+// as a separate audio context. Channels are recognised concurrently, each on its own recognition stream.
+// Once a file is recognised, its finalised segments are written into dst as ChannelSegments ordered by time,
+// labelled with speakers, which are assigned to channels in order. Tentative results are not written.
+// Audio of each channel is processed with the filter chain specified by filters, see audio.ParseFilterChain,
+// and sent in chunks specified by chunk.
+func RecogniseFileChannels(
+	ctx context.Context, cfg Config, token speechly.AccessToken, paths []string, raw *RawFormat, filters string,
+	chunk Chunking, speakers []string, dst io.Writer, bufSize int, instr slu.Instrumentation, log logger.Logger,
+) error {
+	cli, err := newClient(ctx, cfg, token, instr, log)
+	if err != nil {
+		return err
+	}
+
+	defer closeAndLog(cli, "Error closing SLU client", log)
+
+	var (
+		pool    *slu.StreamPool
+		poolFmt audio.Format
+		enc     = json.NewEncoder(dst)
+	)
+
+	defer func() {
+		if pool != nil {
+			closeAndLog(pool, "Error closing SLU stream pool", log)
+		}
+	}()
+
+	for _, p := range paths {
+		r, err := openAudioFile(p, raw, bufSize)
+		if err != nil {
+			return err
+		}
+
+		f := r.Format()
+
+		// Streams are configured with the sample rate of the file and the pool has a stream for every channel,
+		// so a new pool is needed whenever either of them changes.
+		if pool == nil || f.SampleRateHertz != poolFmt.SampleRateHertz || f.NumChannels != poolFmt.NumChannels {
+			if pool != nil {
+				closeAndLog(pool, "Error closing SLU stream pool", log)
+				pool = nil
+			}
+
+			c := slu.Config{
+				NumChannels:     1,
+				SampleRateHertz: f.SampleRateHertz,
+				LanguageCode:    cfg.LanguageCode,
+			}
+
+			pool, err = cli.NewStreamPool(ctx, c, slu.PoolConfig{Size: int(f.NumChannels)})
+			if err != nil {
+				closeAndLog(r, "Error closing audio file reader", log)
+				return err
+			}
+
+			poolFmt = f
+		}
+
+		segs, err := recogniseChannels(ctx, pool, r, f, filters, chunk, speakers, cfg.Limits, log)
+		if err != nil {
+			return err
+		}
+
+		for _, s := range segs {
+			s.File = p
+
+			if err := enc.Encode(s); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// recogniseChannels recognises every channel of src in a separate audio context
+// and returns their finalised segments, see mergeChannelSegments. src is closed once all channels are recognised.
+func recogniseChannels(
+	ctx context.Context, pool *slu.StreamPool, src slu.AudioSource, f audio.Format, filters string, chunk Chunking,
+	speakers []string, lim ContextLimits, log logger.Logger,
+) ([]ChannelSegment, error) {
+	chans, err := audio.SplitChannels(src, f)
+	if err != nil {
+		closeAndLog(src, "Error closing audio source", log)
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		g, gctx = errgroup.WithContext(ctx)
+		res     = make([]slu.AudioContext, len(chans))
+	)
+
+	for i, c := range chans {
+		i, c := i, c
+
+		s, err := newFileSource(c, c.Format(), filters, chunk, log)
+		if err != nil {
+			// Channel sources have to be closed for src to be closed.
+			for _, c := range chans[i+1:] {
+				closeAndLog(c, "Error closing audio source", log)
+			}
+
+			cancel()
+			_ = g.Wait()
+
+			return nil, err
+		}
+
+		g.Go(func() (err error) {
+			res[i], err = recogniseContext(gctx, pool, s, lim, log)
+			return err
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	return mergeChannelSegments(res, speakers), nil
+}
+
+// mergeChannelSegments returns finalised segments of audio contexts of every channel ordered by time,
+// labelled with speakers, which are assigned to channels in order.
+func mergeChannelSegments(res []slu.AudioContext, speakers []string) []ChannelSegment {
+	var segs []ChannelSegment
+
+	for i, c := range res {
+		for _, s := range c.Segments {
+			if !s.IsFinalised {
+				continue
+			}
+
+			cs := newChannelSegment(i, s)
+			if i < len(speakers) {
+				cs.Speaker = speakers[i]
+			}
+
+			segs = append(segs, cs)
+		}
+	}
+
+	sort.SliceStable(segs, func(i, j int) bool {
+		a, b := segs[i], segs[j]
+
+		if a.StartTime != b.StartTime {
+			return a.StartTime < b.StartTime
+		}
+
+		if a.Channel != b.Channel {
+			return a.Channel < b.Channel
+		}
+
+		return a.Segment.ID < b.Segment.ID
+	})
+
+	return segs
+}
+
+// recogniseContext recognises audio from src in a single audio context and returns its final state.
+func recogniseContext(
+	ctx context.Context, str slu.RecogniseStream, src slu.AudioSource, lim ContextLimits, log logger.Logger,
+) (slu.AudioContext, error) {
+	defer closeAndLog(src, "Error closing audio source", log)
+
+	out, err := str.NewAudioContext(ctx, src, 8, lim.options()...)
+	if err != nil {
+		return slu.AudioContext{}, err
+	}
+
+	var last slu.AudioContext
+
+	for {
+		res, err := out.Read()
+		if err == io.EOF {
+			return last, closeContext(out, log)
+		}
+
+		if err != nil {
+			closeAndLog(out, "Error closing SLU context", log)
+			return last, err
+		}
+
+		last = res
+	}
+}
+
+func newChannelSegment(channel int, s slu.Segment) ChannelSegment {
+	ts := make([]slu.Transcript, 0, len(s.Transcripts))
+	for _, t := range s.Transcripts {
+		ts = append(ts, t)
+	}
+
+	sort.Slice(ts, func(i, j int) bool {
+		return ts[i].Index < ts[j].Index
+	})
+
+	var (
+		words = make([]string, len(ts))
+		c     = ChannelSegment{Channel: channel, Segment: s}
+	)
+
+	for i, t := range ts {
+		words[i] = t.Word
+
+		if i == 0 || t.StartTime < c.StartTime {
+			c.StartTime = t.StartTime
+		}
+
+		if t.EndTime > c.EndTime {
+			c.EndTime = t.EndTime
+		}
+	}
+
+	c.Text = strings.Join(words, " ")
+
+	return c
+}
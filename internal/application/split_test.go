@@ -0,0 +1,67 @@
+package application
+
+import (
+	"testing"
+
+	"github.com/speechly/slu-client/pkg/speechly/slu"
+)
+
+// testSegment returns a finalised segment of words, starting at start milliseconds and 300ms apart,
+// each lasting 200ms.
+func testSegment(id, start int32, words ...string) slu.Segment {
+	s := slu.Segment{ID: id, IsFinalised: true, Transcripts: slu.Transcripts{}}
+
+	for i, w := range words {
+		t := start + int32(i)*300
+		s.Transcripts[int32(i)] = slu.Transcript{Word: w, Index: int32(i), StartTime: t, EndTime: t + 200}
+	}
+
+	return s
+}
+
+func TestMergeChannelSegments(t *testing.T) {
+	tentative := testSegment(2, 0, "maybe")
+	tentative.IsFinalised = false
+
+	res := []slu.AudioContext{
+		{Segments: slu.Segments{
+			0: testSegment(0, 100, "hello", "there"),
+			1: testSegment(1, 2000, "bye"),
+			2: tentative,
+		}},
+		{Segments: slu.Segments{
+			0: testSegment(0, 1000, "hi"),
+			1: testSegment(1, 2000, "see", "you"),
+			2: testSegment(2, 2000, "later"),
+		}},
+		{Segments: slu.Segments{
+			0: testSegment(0, 100, "unlabelled"),
+		}},
+	}
+
+	// Segments are ordered by start time, then by channel and then by segment ID.
+	want := []ChannelSegment{
+		{Channel: 0, Speaker: "agent", StartTime: 100, EndTime: 600, Text: "hello there"},
+		{Channel: 2, Speaker: "", StartTime: 100, EndTime: 300, Text: "unlabelled"},
+		{Channel: 1, Speaker: "customer", StartTime: 1000, EndTime: 1200, Text: "hi"},
+		{Channel: 0, Speaker: "agent", StartTime: 2000, EndTime: 2200, Text: "bye"},
+		{Channel: 1, Speaker: "customer", StartTime: 2000, EndTime: 2500, Text: "see you"},
+		{Channel: 1, Speaker: "customer", StartTime: 2000, EndTime: 2200, Text: "later"},
+	}
+
+	// Segments are stored in maps, so the result must not depend on the iteration order.
+	for i := 0; i < 10; i++ {
+		got := mergeChannelSegments(res, []string{"agent", "customer"})
+		if len(got) != len(want) {
+			t.Fatalf("mergeChannelSegments() returned %d segments, want %d", len(got), len(want))
+		}
+
+		for j, s := range got {
+			w := want[j]
+			if s.Channel != w.Channel || s.Speaker != w.Speaker || s.StartTime != w.StartTime ||
+				s.EndTime != w.EndTime || s.Text != w.Text {
+				t.Errorf("segment %d = %+v, want %+v", j, s, w)
+			}
+		}
+	}
+}
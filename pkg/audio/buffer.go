@@ -10,44 +10,53 @@ import (
 var ErrInvalidBuffer = errors.New("invalid buffer")
 
 // Buffer represents a chunk of audio data of a specific size and bit depth.
-// TODO: need to make sure that Write / Read are balanced and do not overwrite.
+// A buffer holds up to Size samples, of which the first Len are valid.
+// Samples past Len are always zero, so the whole underlying slice can be safely consumed, e.g. by audio devices.
+// Buffers are not safe for concurrent use.
 type Buffer interface {
-	// Size returns the size of the underlying slice.
+	// Size returns the size of the underlying slice, which is the maximum number of samples in the buffer.
 	Size() int
 
+	// Len returns the number of valid samples in the buffer.
+	Len() int
+
+	// SetLen sets the number of valid samples in the buffer to n, which is capped to Size.
+	// It must be called after filling the slice returned by Data directly. Samples past n are zeroed.
+	SetLen(n int)
+
 	// BitDepth returns bit depth of the buffer.
 	BitDepth() BitDepth
 
-	// Data returns the pointer to the underlying slice.
+	// Data returns the pointer to the underlying slice, which always has Size samples.
 	Data() interface{}
 
 	// Clone clones the buffer, copying underlying slice.
 	Clone() Buffer
 
-	// Write writes provided int slice into the buffer.
+	// Write replaces the contents of the buffer with provided int slice and returns the number of samples written.
+	// If the slice is longer than Size, only Size samples are written and io.ErrShortBuffer is returned.
 	Write([]int, BitDepth) (int, error)
 
-	// Read reads the contents of the buffer into provided slice.
+	// Read reads valid samples of the buffer into provided slice and returns the number of samples read.
 	Read([]int, BitDepth) (int, error)
 
-	// WriteTo writes the contents of the buffer into buf.
+	// WriteTo replaces the contents of buf with valid samples of the buffer.
 	// buf has to have matching bit depth.
 	WriteTo(buf Buffer) (int, error)
 
-	// ReadFrom reads the contents of buf into the buffer.
+	// ReadFrom replaces the contents of the buffer with valid samples of buf.
 	// buf has to have matching bit depth.
 	ReadFrom(buf Buffer) (int, error)
 
-	// Encode writes the contents of the buffer into provided writer.
-	// The buffer is responsible for performing resampling.
+	// Encode writes valid samples of the buffer into provided writer and returns the number of samples written.
 	Encode(binary.ByteOrder, io.Writer) (int, error)
 
-	// Decode reads the contents of provided reader into the buffer.
-	// The buffer is responsible for performing resampling.
+	// Decode replaces the contents of the buffer with up to Size samples read from provided reader.
+	// It returns io.EOF if fewer samples were read and io.ErrUnexpectedEOF if the last sample was incomplete.
 	Decode(binary.ByteOrder, io.Reader) (int, error)
 }
 
-// NewBuffer returns a new Buffer with specified bit depth and size.
+// NewBuffer returns a new empty Buffer with specified bit depth and size.
 func NewBuffer(d BitDepth, size int) (Buffer, error) {
 	switch d {
 	case BitDepth8:
@@ -65,71 +74,84 @@ func NewBuffer(d BitDepth, size int) (Buffer, error) {
 
 type int8Buffer struct {
 	data []int8
-	size int
+	raw  []byte // Scratch space for encoding and decoding.
+	len  int
 }
 
-func newInt8Buffer(size int) Buffer {
-	return int8Buffer{
+func newInt8Buffer(size int) *int8Buffer {
+	return &int8Buffer{
 		data: make([]int8, size),
-		size: size,
+		raw:  make([]byte, size),
 	}
 }
 
-func (b int8Buffer) Size() int {
-	return b.size
+func (b *int8Buffer) Size() int {
+	return len(b.data)
 }
 
-func (b int8Buffer) BitDepth() BitDepth {
-	return BitDepth16
+func (b *int8Buffer) Len() int {
+	return b.len
+}
+
+func (b *int8Buffer) SetLen(n int) {
+	if n > len(b.data) {
+		n = len(b.data)
+	}
+
+	if n < 0 {
+		n = 0
+	}
+
+	for i := n; i < len(b.data); i++ {
+		b.data[i] = 0
+	}
+
+	b.len = n
 }
 
-func (b int8Buffer) Data() interface{} {
+func (b *int8Buffer) BitDepth() BitDepth {
+	return BitDepth8
+}
+
+func (b *int8Buffer) Data() interface{} {
 	return &b.data
 }
 
-func (b int8Buffer) Clone() Buffer {
-	data := make([]int8, b.size)
-	copy(data, b.data)
+func (b *int8Buffer) Clone() Buffer {
+	c := newInt8Buffer(len(b.data))
+	copy(c.data, b.data)
+	c.len = b.len
 
-	return int8Buffer{
-		data: data,
-		size: b.size,
-	}
+	return c
 }
 
-func (b int8Buffer) Write(buf []int, d BitDepth) (int, error) {
-	var (
-		dlen = len(b.data)
-		blen = len(buf)
-	)
+func (b *int8Buffer) Write(buf []int, d BitDepth) (int, error) {
+	n := copyLen(len(buf), len(b.data))
 
-	if dlen < blen {
-		b.data = append(b.data, make([]int8, blen-dlen)...)
-	} else if dlen > blen {
-		b.data = b.data[:blen]
+	for i, v := range buf[:n] {
+		b.data[i] = int8(v)
 	}
 
-	for i := 0; i < len(buf); i++ {
-		b.data[i] = int8(buf[i])
+	b.SetLen(n)
+
+	if n < len(buf) {
+		return n, io.ErrShortBuffer
 	}
 
-	return len(b.data), nil
+	return n, nil
 }
 
-func (b int8Buffer) Read(buf []int, d BitDepth) (int, error) {
-	ln := len(buf)
-	if len(b.data) < ln {
-		ln = len(b.data)
-	}
+func (b *int8Buffer) Read(buf []int, d BitDepth) (int, error) {
+	n := copyLen(len(buf), b.len)
 
-	for i := 0; i < ln; i++ {
-		buf[i] = int(b.data[i])
+	for i, v := range b.data[:n] {
+		buf[i] = int(v)
 	}
 
-	return ln, nil
+	return n, nil
 }
 
-func (b int8Buffer) WriteTo(buf Buffer) (int, error) {
+func (b *int8Buffer) WriteTo(buf Buffer) (int, error) {
 	// Changing bit depths is not yet supported.
 	if b.BitDepth() != buf.BitDepth() {
 		return 0, ErrInvalidBitDepth
@@ -140,10 +162,13 @@ func (b int8Buffer) WriteTo(buf Buffer) (int, error) {
 		return 0, ErrInvalidBuffer
 	}
 
-	return copy(*s, b.data), nil
+	n := copy(*s, b.data[:b.len])
+	buf.SetLen(n)
+
+	return n, nil
 }
 
-func (b int8Buffer) ReadFrom(buf Buffer) (int, error) {
+func (b *int8Buffer) ReadFrom(buf Buffer) (int, error) {
 	// Changing bit depths is not yet supported.
 	if b.BitDepth() != buf.BitDepth() {
 		return 0, ErrInvalidBitDepth
@@ -154,107 +179,107 @@ func (b int8Buffer) ReadFrom(buf Buffer) (int, error) {
 		return 0, ErrInvalidBuffer
 	}
 
-	return copy(b.data, *s), nil
-}
-
-func (b int8Buffer) Encode(enc binary.ByteOrder, w io.Writer) (int, error) {
-	n := 0
-
-	for _, v := range b.data {
-		if err := binary.Write(w, enc, v); err != nil {
-			return n, err
-		}
-
-		n++
-	}
+	n := copy(b.data, (*s)[:buf.Len()])
+	b.SetLen(n)
 
 	return n, nil
 }
 
-func (b int8Buffer) Decode(enc binary.ByteOrder, r io.Reader) (int, error) {
-	// Make sure we're at max capacity
-	b.data = b.data[:cap(b.data)]
+func (b *int8Buffer) Encode(enc binary.ByteOrder, w io.Writer) (int, error) {
+	raw := b.raw[:b.len]
+	putInt8s(enc, raw, b.data[:b.len])
 
-	n := 0
+	return w.Write(raw)
+}
 
-	for i := 0; i < len(b.data); i++ {
-		if err := binary.Read(r, enc, &b.data[i]); err != nil {
-			return n, err
-		}
+func (b *int8Buffer) Decode(enc binary.ByteOrder, r io.Reader) (int, error) {
+	n, err := readSamples(r, b.raw, 1)
+	getInt8s(enc, b.data[:n], b.raw)
+	b.SetLen(n)
 
-		n++
-	}
-
-	return n, nil
+	return n, err
 }
 
 type int16Buffer struct {
 	data []int16
-	size int
+	raw  []byte // Scratch space for encoding and decoding.
+	len  int
 }
 
-func newInt16Buffer(size int) Buffer {
-	return int16Buffer{
+func newInt16Buffer(size int) *int16Buffer {
+	return &int16Buffer{
 		data: make([]int16, size),
-		size: size,
+		raw:  make([]byte, size*2),
 	}
 }
 
-func (b int16Buffer) Size() int {
-	return b.size
+func (b *int16Buffer) Size() int {
+	return len(b.data)
+}
+
+func (b *int16Buffer) Len() int {
+	return b.len
 }
 
-func (b int16Buffer) BitDepth() BitDepth {
+func (b *int16Buffer) SetLen(n int) {
+	if n > len(b.data) {
+		n = len(b.data)
+	}
+
+	if n < 0 {
+		n = 0
+	}
+
+	for i := n; i < len(b.data); i++ {
+		b.data[i] = 0
+	}
+
+	b.len = n
+}
+
+func (b *int16Buffer) BitDepth() BitDepth {
 	return BitDepth16
 }
 
-func (b int16Buffer) Data() interface{} {
+func (b *int16Buffer) Data() interface{} {
 	return &b.data
 }
 
-func (b int16Buffer) Clone() Buffer {
-	data := make([]int16, b.size)
-	copy(data, b.data)
+func (b *int16Buffer) Clone() Buffer {
+	c := newInt16Buffer(len(b.data))
+	copy(c.data, b.data)
+	c.len = b.len
 
-	return int16Buffer{
-		data: data,
-		size: b.size,
-	}
+	return c
 }
 
-func (b int16Buffer) Write(buf []int, d BitDepth) (int, error) {
-	var (
-		dlen = len(b.data)
-		blen = len(buf)
-	)
+func (b *int16Buffer) Write(buf []int, d BitDepth) (int, error) {
+	n := copyLen(len(buf), len(b.data))
 
-	if dlen < blen {
-		b.data = append(b.data, make([]int16, blen-dlen)...)
-	} else if dlen > blen {
-		b.data = b.data[:blen]
+	for i, v := range buf[:n] {
+		b.data[i] = int16(v)
 	}
 
-	for i := 0; i < len(buf); i++ {
-		b.data[i] = int16(buf[i])
+	b.SetLen(n)
+
+	if n < len(buf) {
+		return n, io.ErrShortBuffer
 	}
 
-	return len(b.data), nil
+	return n, nil
 }
 
-func (b int16Buffer) Read(buf []int, d BitDepth) (int, error) {
-	ln := len(buf)
-	if len(b.data) < ln {
-		ln = len(b.data)
-	}
+func (b *int16Buffer) Read(buf []int, d BitDepth) (int, error) {
+	n := copyLen(len(buf), b.len)
 
-	for i := 0; i < ln; i++ {
-		buf[i] = int(b.data[i])
+	for i, v := range b.data[:n] {
+		buf[i] = int(v)
 	}
 
-	return ln, nil
+	return n, nil
 }
 
-func (b int16Buffer) WriteTo(buf Buffer) (int, error) {
+func (b *int16Buffer) WriteTo(buf Buffer) (int, error) {
 	// Changing bit depths is not yet supported.
 	if b.BitDepth() != buf.BitDepth() {
 		return 0, ErrInvalidBitDepth
@@ -265,10 +290,13 @@ func (b int16Buffer) WriteTo(buf Buffer) (int, error) {
 		return 0, ErrInvalidBuffer
 	}
 
-	return copy(*s, b.data), nil
+	n := copy(*s, b.data[:b.len])
+	buf.SetLen(n)
+
+	return n, nil
 }
 
-func (b int16Buffer) ReadFrom(buf Buffer) (int, error) {
+func (b *int16Buffer) ReadFrom(buf Buffer) (int, error) {
 	// Changing bit depths is not yet supported.
 	if b.BitDepth() != buf.BitDepth() {
 		return 0, ErrInvalidBitDepth
@@ -279,107 +307,109 @@ func (b int16Buffer) ReadFrom(buf Buffer) (int, error) {
 		return 0, ErrInvalidBuffer
 	}
 
-	return copy(b.data, *s), nil
-}
-
-func (b int16Buffer) Encode(enc binary.ByteOrder, w io.Writer) (int, error) {
-	n := 0
-
-	for _, v := range b.data {
-		if err := binary.Write(w, enc, v); err != nil {
-			return n, err
-		}
-
-		n++
-	}
+	n := copy(b.data, (*s)[:buf.Len()])
+	b.SetLen(n)
 
 	return n, nil
 }
 
-func (b int16Buffer) Decode(enc binary.ByteOrder, r io.Reader) (int, error) {
-	// Make sure we're at max capacity
-	b.data = b.data[:cap(b.data)]
+func (b *int16Buffer) Encode(enc binary.ByteOrder, w io.Writer) (int, error) {
+	raw := b.raw[:b.len*2]
+	putInt16s(enc, raw, b.data[:b.len])
 
-	n := 0
+	n, err := w.Write(raw)
 
-	for i := 0; i < len(b.data); i++ {
-		if err := binary.Read(r, enc, &b.data[i]); err != nil {
-			return n, err
-		}
+	return n / 2, err
+}
 
-		n++
-	}
+func (b *int16Buffer) Decode(enc binary.ByteOrder, r io.Reader) (int, error) {
+	n, err := readSamples(r, b.raw, 2)
+	getInt16s(enc, b.data[:n], b.raw)
+	b.SetLen(n)
 
-	return n, nil
+	return n, err
 }
 
 type int32Buffer struct {
 	data []int32
-	size int
+	raw  []byte // Scratch space for encoding and decoding.
+	len  int
 }
 
-func newInt32Buffer(size int) Buffer {
-	return int32Buffer{
+func newInt32Buffer(size int) *int32Buffer {
+	return &int32Buffer{
 		data: make([]int32, size),
-		size: size,
+		raw:  make([]byte, size*4),
 	}
 }
 
-func (b int32Buffer) Size() int {
-	return b.size
+func (b *int32Buffer) Size() int {
+	return len(b.data)
 }
 
-func (b int32Buffer) BitDepth() BitDepth {
-	return BitDepth16
+func (b *int32Buffer) Len() int {
+	return b.len
+}
+
+func (b *int32Buffer) SetLen(n int) {
+	if n > len(b.data) {
+		n = len(b.data)
+	}
+
+	if n < 0 {
+		n = 0
+	}
+
+	for i := n; i < len(b.data); i++ {
+		b.data[i] = 0
+	}
+
+	b.len = n
 }
 
-func (b int32Buffer) Data() interface{} {
+func (b *int32Buffer) BitDepth() BitDepth {
+	return BitDepth32
+}
+
+func (b *int32Buffer) Data() interface{} {
 	return &b.data
 }
 
-func (b int32Buffer) Clone() Buffer {
-	data := make([]int32, b.size)
-	copy(data, b.data)
+func (b *int32Buffer) Clone() Buffer {
+	c := newInt32Buffer(len(b.data))
+	copy(c.data, b.data)
+	c.len = b.len
 
-	return int32Buffer{
-		data: data,
-		size: b.size,
-	}
+	return c
 }
 
-func (b int32Buffer) Write(buf []int, d BitDepth) (int, error) {
-	var (
-		dlen = len(b.data)
-		blen = len(buf)
-	)
+func (b *int32Buffer) Write(buf []int, d BitDepth) (int, error) {
+	n := copyLen(len(buf), len(b.data))
 
-	if dlen < blen {
-		b.data = append(b.data, make([]int32, blen-dlen)...)
-	} else if dlen > blen {
-		b.data = b.data[:blen]
+	for i, v := range buf[:n] {
+		b.data[i] = int32(v)
 	}
 
-	for i := 0; i < len(buf); i++ {
-		b.data[i] = int32(buf[i])
+	b.SetLen(n)
+
+	if n < len(buf) {
+		return n, io.ErrShortBuffer
 	}
 
-	return len(b.data), nil
+	return n, nil
 }
 
-func (b int32Buffer) Read(buf []int, d BitDepth) (int, error) {
-	ln := len(buf)
-	if len(b.data) < ln {
-		ln = len(b.data)
-	}
+func (b *int32Buffer) Read(buf []int, d BitDepth) (int, error) {
+	n := copyLen(len(buf), b.len)
 
-	for i := 0; i < ln; i++ {
-		buf[i] = int(b.data[i])
+	for i, v := range b.data[:n] {
+		buf[i] = int(v)
 	}
 
-	return ln, nil
+	return n, nil
 }
 
-func (b int32Buffer) WriteTo(buf Buffer) (int, error) {
+func (b *int32Buffer) WriteTo(buf Buffer) (int, error) {
 	// Changing bit depths is not yet supported.
 	if b.BitDepth() != buf.BitDepth() {
 		return 0, ErrInvalidBitDepth
@@ -390,10 +420,13 @@ func (b int32Buffer) WriteTo(buf Buffer) (int, error) {
 		return 0, ErrInvalidBuffer
 	}
 
-	return copy(*s, b.data), nil
+	n := copy(*s, b.data[:b.len])
+	buf.SetLen(n)
+
+	return n, nil
 }
 
-func (b int32Buffer) ReadFrom(buf Buffer) (int, error) {
+func (b *int32Buffer) ReadFrom(buf Buffer) (int, error) {
 	// Changing bit depths is not yet supported.
 	if b.BitDepth() != buf.BitDepth() {
 		return 0, ErrInvalidBitDepth
@@ -404,107 +437,109 @@ func (b int32Buffer) ReadFrom(buf Buffer) (int, error) {
 		return 0, ErrInvalidBuffer
 	}
 
-	return copy(b.data, *s), nil
-}
-
-func (b int32Buffer) Encode(enc binary.ByteOrder, w io.Writer) (int, error) {
-	n := 0
-
-	for _, v := range b.data {
-		if err := binary.Write(w, enc, v); err != nil {
-			return n, err
-		}
-
-		n++
-	}
+	n := copy(b.data, (*s)[:buf.Len()])
+	b.SetLen(n)
 
 	return n, nil
 }
 
-func (b int32Buffer) Decode(enc binary.ByteOrder, r io.Reader) (int, error) {
-	// Make sure we're at max capacity
-	b.data = b.data[:cap(b.data)]
+func (b *int32Buffer) Encode(enc binary.ByteOrder, w io.Writer) (int, error) {
+	raw := b.raw[:b.len*4]
+	putInt32s(enc, raw, b.data[:b.len])
 
-	n := 0
+	n, err := w.Write(raw)
 
-	for i := 0; i < len(b.data); i++ {
-		if err := binary.Read(r, enc, &b.data[i]); err != nil {
-			return n, err
-		}
+	return n / 4, err
+}
 
-		n++
-	}
+func (b *int32Buffer) Decode(enc binary.ByteOrder, r io.Reader) (int, error) {
+	n, err := readSamples(r, b.raw, 4)
+	getInt32s(enc, b.data[:n], b.raw)
+	b.SetLen(n)
 
-	return n, nil
+	return n, err
 }
 
 type int64Buffer struct {
 	data []int64
-	size int
+	raw  []byte // Scratch space for encoding and decoding.
+	len  int
 }
 
-func newInt64Buffer(size int) Buffer {
-	return int64Buffer{
+func newInt64Buffer(size int) *int64Buffer {
+	return &int64Buffer{
 		data: make([]int64, size),
-		size: size,
+		raw:  make([]byte, size*8),
 	}
 }
 
-func (b int64Buffer) Size() int {
-	return b.size
+func (b *int64Buffer) Size() int {
+	return len(b.data)
 }
 
-func (b int64Buffer) BitDepth() BitDepth {
-	return BitDepth16
+func (b *int64Buffer) Len() int {
+	return b.len
 }
 
-func (b int64Buffer) Data() interface{} {
+func (b *int64Buffer) SetLen(n int) {
+	if n > len(b.data) {
+		n = len(b.data)
+	}
+
+	if n < 0 {
+		n = 0
+	}
+
+	for i := n; i < len(b.data); i++ {
+		b.data[i] = 0
+	}
+
+	b.len = n
+}
+
+func (b *int64Buffer) BitDepth() BitDepth {
+	return BitDepth64
+}
+
+func (b *int64Buffer) Data() interface{} {
 	return &b.data
 }
 
-func (b int64Buffer) Clone() Buffer {
-	data := make([]int64, b.size)
-	copy(data, b.data)
+func (b *int64Buffer) Clone() Buffer {
+	c := newInt64Buffer(len(b.data))
+	copy(c.data, b.data)
+	c.len = b.len
 
-	return int64Buffer{
-		data: data,
-		size: b.size,
-	}
+	return c
 }
 
-func (b int64Buffer) Write(buf []int, d BitDepth) (int, error) {
-	var (
-		dlen = len(b.data)
-		blen = len(buf)
-	)
+func (b *int64Buffer) Write(buf []int, d BitDepth) (int, error) {
+	n := copyLen(len(buf), len(b.data))
 
-	if dlen < blen {
-		b.data = append(b.data, make([]int64, blen-dlen)...)
-	} else if dlen > blen {
-		b.data = b.data[:blen]
+	for i, v := range buf[:n] {
+		b.data[i] = int64(v)
 	}
 
-	for i := 0; i < len(buf); i++ {
-		b.data[i] = int64(buf[i])
+	b.SetLen(n)
+
+	if n < len(buf) {
+		return n, io.ErrShortBuffer
 	}
 
-	return len(b.data), nil
+	return n, nil
 }
 
-func (b int64Buffer) Read(buf []int, d BitDepth) (int, error) {
-	ln := len(buf)
-	if len(b.data) < ln {
-		ln = len(b.data)
-	}
+func (b *int64Buffer) Read(buf []int, d BitDepth) (int, error) {
+	n := copyLen(len(buf), b.len)
 
-	for i := 0; i < ln; i++ {
-		buf[i] = int(b.data[i])
+	for i, v := range b.data[:n] {
+		buf[i] = int(v)
 	}
 
-	return ln, nil
+	return n, nil
 }
 
-func (b int64Buffer) WriteTo(buf Buffer) (int, error) {
+func (b *int64Buffer) WriteTo(buf Buffer) (int, error) {
 	// Changing bit depths is not yet supported.
 	if b.BitDepth() != buf.BitDepth() {
 		return 0, ErrInvalidBitDepth
@@ -515,10 +550,13 @@ func (b int64Buffer) WriteTo(buf Buffer) (int, error) {
 		return 0, ErrInvalidBuffer
 	}
 
-	return copy(*s, b.data), nil
+	n := copy(*s, b.data[:b.len])
+	buf.SetLen(n)
+
+	return n, nil
 }
 
-func (b int64Buffer) ReadFrom(buf Buffer) (int, error) {
+func (b *int64Buffer) ReadFrom(buf Buffer) (int, error) {
 	// Changing bit depths is not yet supported.
 	if b.BitDepth() != buf.BitDepth() {
 		return 0, ErrInvalidBitDepth
@@ -529,36 +567,33 @@ func (b int64Buffer) ReadFrom(buf Buffer) (int, error) {
 		return 0, ErrInvalidBuffer
 	}
 
-	return copy(b.data, *s), nil
-}
+	n := copy(b.data, (*s)[:buf.Len()])
+	b.SetLen(n)
 
-func (b int64Buffer) Encode(enc binary.ByteOrder, w io.Writer) (int, error) {
-	n := 0
+	return n, nil
+}
 
-	for _, v := range b.data {
-		if err := binary.Write(w, enc, v); err != nil {
-			return n, err
-		}
+func (b *int64Buffer) Encode(enc binary.ByteOrder, w io.Writer) (int, error) {
+	raw := b.raw[:b.len*8]
+	putInt64s(enc, raw, b.data[:b.len])
 
-		n++
-	}
+	n, err := w.Write(raw)
 
-	return n, nil
+	return n / 8, err
 }
 
-func (b int64Buffer) Decode(enc binary.ByteOrder, r io.Reader) (int, error) {
-	// Make sure we're at max capacity
-	b.data = b.data[:cap(b.data)]
+func (b *int64Buffer) Decode(enc binary.ByteOrder, r io.Reader) (int, error) {
+	n, err := readSamples(r, b.raw, 8)
+	getInt64s(enc, b.data[:n], b.raw)
+	b.SetLen(n)
 
-	n := 0
-
-	for i := 0; i < len(b.data); i++ {
-		if err := binary.Read(r, enc, &b.data[i]); err != nil {
-			return n, err
-		}
+	return n, err
+}
 
-		n++
+func copyLen(a, b int) int {
+	if a < b {
+		return a
 	}
 
-	return n, nil
+	return b
 }
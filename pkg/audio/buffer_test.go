@@ -0,0 +1,207 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+const benchBufferSize = 4096
+
+// testBitDepths are all supported bit depths.
+var testBitDepths = []BitDepth{BitDepth8, BitDepth16, BitDepth32, BitDepth64}
+
+// newBenchBuffer returns a full buffer of bit depth d with benchBufferSize samples.
+func newBenchBuffer(b *testing.B, d BitDepth) Buffer {
+	b.Helper()
+
+	buf, err := NewBuffer(d, benchBufferSize)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	ints := make([]int, benchBufferSize)
+	for i := range ints {
+		ints[i] = i%256 - 128
+	}
+
+	if _, err := buf.Write(ints, d); err != nil {
+		b.Fatal(err)
+	}
+
+	return buf
+}
+
+func BenchmarkEncode(b *testing.B) {
+	for _, d := range testBitDepths {
+		b.Run(strconv.Itoa(int(d)), func(b *testing.B) {
+			buf := newBenchBuffer(b, d)
+
+			b.SetBytes(int64(benchBufferSize * sampleBytes(d)))
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := buf.Encode(binary.LittleEndian, io.Discard); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkDecode(b *testing.B) {
+	for _, d := range testBitDepths {
+		b.Run(strconv.Itoa(int(d)), func(b *testing.B) {
+			var (
+				buf = newBenchBuffer(b, d)
+				enc bytes.Buffer
+			)
+
+			if _, err := buf.Encode(binary.LittleEndian, &enc); err != nil {
+				b.Fatal(err)
+			}
+
+			r := bytes.NewReader(enc.Bytes())
+
+			b.SetBytes(int64(enc.Len()))
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				r.Reset(enc.Bytes())
+
+				if _, err := buf.Decode(binary.LittleEndian, r); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkRead(b *testing.B) {
+	for _, d := range testBitDepths {
+		b.Run(strconv.Itoa(int(d)), func(b *testing.B) {
+			var (
+				buf  = newBenchBuffer(b, d)
+				ints = make([]int, benchBufferSize)
+			)
+
+			b.SetBytes(int64(benchBufferSize * sampleBytes(d)))
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := buf.Read(ints, d); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// checkBuffer checks that b contains exactly want and that the samples past its length are zero.
+func checkBuffer(t *testing.T, b Buffer, want []int) {
+	t.Helper()
+
+	if b.Len() != len(want) {
+		t.Fatalf("Len() = %d, want %d", b.Len(), len(want))
+	}
+
+	got := make([]int, b.Size())
+
+	n, err := b.Read(got, b.BitDepth())
+	if err != nil && err != io.EOF {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	if !reflect.DeepEqual(got[:n], want) {
+		t.Errorf("Read() = %v, want %v", got[:n], want)
+	}
+
+	data := reflect.ValueOf(b.Data()).Elem()
+	for i := len(want); i < data.Len(); i++ {
+		if v := data.Index(i).Int(); v != 0 {
+			t.Errorf("sample %d past Len = %d, want 0", i, v)
+		}
+	}
+}
+
+func TestBufferWrite(t *testing.T) {
+	for _, d := range testBitDepths {
+		d := d
+
+		t.Run(strconv.Itoa(int(d)), func(t *testing.T) {
+			b, err := NewBuffer(d, 4)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			checkBuffer(t, b, []int{})
+
+			if n, err := b.Write([]int{1, -2, 3, -4}, d); n != 4 || err != nil {
+				t.Fatalf("Write() = %d, %v, want 4, nil", n, err)
+			}
+
+			checkBuffer(t, b, []int{1, -2, 3, -4})
+
+			// A shorter write must not leave stale samples of the previous one.
+			if n, err := b.Write([]int{5, -6}, d); n != 2 || err != nil {
+				t.Fatalf("Write() = %d, %v, want 2, nil", n, err)
+			}
+
+			checkBuffer(t, b, []int{5, -6})
+
+			if n, err := b.Write([]int{1, 2, 3, 4, 5}, d); n != 4 || err != io.ErrShortBuffer {
+				t.Fatalf("Write() = %d, %v, want 4, %v", n, err, io.ErrShortBuffer)
+			}
+
+			checkBuffer(t, b, []int{1, 2, 3, 4})
+
+			b.SetLen(1)
+			checkBuffer(t, b, []int{1})
+		})
+	}
+}
+
+func TestBufferDecodePartialChunk(t *testing.T) {
+	tests := []struct {
+		name    string
+		samples []int64
+		extra   int
+		want    []int
+		wantErr error
+	}{
+		{name: "full chunk", samples: []int64{1, -2, 3, -4}, want: []int{1, -2, 3, -4}},
+		{name: "partial chunk", samples: []int64{5, -6}, want: []int{5, -6}, wantErr: io.EOF},
+		{name: "incomplete sample", samples: []int64{7}, extra: 1, want: []int{7}, wantErr: io.ErrUnexpectedEOF},
+		{name: "empty", want: []int{}, wantErr: io.EOF},
+	}
+
+	for _, d := range testBitDepths {
+		d := d
+
+		t.Run(strconv.Itoa(int(d)), func(t *testing.T) {
+			b, err := NewBuffer(d, 4)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// The same buffer is reused for every chunk, like readers do.
+			for _, tt := range tests {
+				if tt.extra >= int(d)/8 {
+					continue
+				}
+
+				data := append(encodeSamples(d, tt.samples...), make([]byte, tt.extra)...)
+
+				n, err := b.Decode(binary.LittleEndian, bytes.NewReader(data))
+				if n != len(tt.want) || err != tt.wantErr {
+					t.Errorf("%s: Decode() = %d, %v, want %d, %v", tt.name, n, err, len(tt.want), tt.wantErr)
+				}
+
+				checkBuffer(t, b, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package audio
+
+import (
+	"bytes"
+	"io"
+	"sync"
+)
+
+// ChannelSource is an EncodedSource of a single channel of multi-channel audio, returned by SplitChannels.
+type ChannelSource struct {
+	split  *channelSplitter
+	index  int
+	buf    bytes.Buffer
+	closed bool
+}
+
+// SplitChannels de-interleaves audio written by src, encoded in format f, into separate mono sources, one per channel.
+// Returned sources can be consumed concurrently, audio of each channel is buffered until its source consumes it,
+// so the memory usage grows if one of them falls behind. src is closed once all returned sources are closed.
+func SplitChannels(src EncodedSource, f Format) ([]*ChannelSource, error) {
+	if sampleBytes(f.BitDepth) == 0 {
+		return nil, ErrInvalidBitDepth
+	}
+
+	if f.NumChannels < 1 {
+		return nil, ErrInvalidBuffer
+	}
+
+	s := &channelSplitter{
+		src:  src,
+		fmt:  f,
+		open: int(f.NumChannels),
+	}
+
+	s.chans = make([]*ChannelSource, f.NumChannels)
+	for i := range s.chans {
+		s.chans[i] = &ChannelSource{split: s, index: i}
+	}
+
+	return s.chans, nil
+}
+
+// Channel returns the index of the channel in the original audio.
+func (c *ChannelSource) Channel() int {
+	return c.index
+}
+
+// Format returns the audio format of the channel, which is the original format with a single channel.
+func (c *ChannelSource) Format() Format {
+	f := c.split.fmt
+	f.NumChannels = 1
+
+	return f
+}
+
+// WriteTo writes the next chunk of the channel's audio into w, reading more audio from the original source if needed.
+// It returns the number of bytes written to w and io.EOF with the last chunk.
+func (c *ChannelSource) WriteTo(w io.Writer) (int64, error) {
+	s := c.split
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	for c.buf.Len() == 0 && s.err == nil {
+		s.readNext()
+	}
+
+	n, err := c.buf.WriteTo(w)
+	if err != nil {
+		return n, err
+	}
+
+	return n, s.err
+}
+
+// Close closes the channel source, discarding any audio of the channel that has not been consumed.
+// The original source is closed when the last channel source is closed.
+func (c *ChannelSource) Close() error {
+	s := c.split
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if c.closed {
+		return s.closeErr
+	}
+
+	c.closed = true
+	c.buf.Reset()
+
+	if s.open--; s.open == 0 {
+		s.closeErr = s.src.Close()
+	}
+
+	return s.closeErr
+}
+
+type channelSplitter struct {
+	src      EncodedSource
+	fmt      Format
+	chans    []*ChannelSource
+	lock     sync.Mutex
+	pending  bytes.Buffer
+	err      error // Error returned by src, io.EOF once it has been exhausted.
+	open     int
+	closeErr error
+}
+
+// readNext reads the next chunk of audio from src and de-interleaves complete frames into channel buffers.
+// An incomplete frame is kept until the next chunk.
+func (s *channelSplitter) readNext() {
+	_, s.err = s.src.WriteTo(&s.pending)
+
+	var (
+		size  = sampleBytes(s.fmt.BitDepth)
+		frame = size * len(s.chans)
+		data  = s.pending.Next(s.pending.Len() / frame * frame)
+	)
+
+	for i, c := range s.chans {
+		if c.closed {
+			continue
+		}
+
+		for j := i * size; j < len(data); j += frame {
+			c.buf.Write(data[j : j+size])
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package audio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+)
+
+func TestSplitChannels(t *testing.T) {
+	var (
+		f = Format{NumChannels: 2, SampleRateHertz: testRate, BitDepth: BitDepth16}
+		// Chunks are not aligned to frames, and the last frame is incomplete.
+		src = &chunkSource{data: encodeSamples(BitDepth16, 1, -1, 2, -2, 3, -3, 4, -4, 5), size: 6}
+	)
+
+	chans, err := SplitChannels(src, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(chans) != 2 {
+		t.Fatalf("SplitChannels() returned %d sources, want 2", len(chans))
+	}
+
+	var (
+		wg   sync.WaitGroup
+		got  = make([][]byte, len(chans))
+		errs = make([]error, len(chans))
+	)
+
+	// Channels are consumed concurrently, like audio contexts of every channel are.
+	for i, c := range chans {
+		i, c := i, c
+
+		if c.Channel() != i {
+			t.Errorf("Channel() = %d, want %d", c.Channel(), i)
+		}
+
+		if got := c.Format(); got.NumChannels != 1 || got.SampleRateHertz != testRate || got.BitDepth != BitDepth16 {
+			t.Errorf("Format() = %+v, want mono 16-bit audio at %d Hz", got, testRate)
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			got[i], errs[i] = readAll(c)
+		}()
+	}
+
+	wg.Wait()
+
+	for i, want := range [][]byte{
+		encodeSamples(BitDepth16, 1, 2, 3, 4),
+		encodeSamples(BitDepth16, -1, -2, -3, -4),
+	} {
+		if errs[i] != nil {
+			t.Errorf("channel %d WriteTo() error = %v", i, errs[i])
+		}
+
+		if !bytes.Equal(got[i], want) {
+			t.Errorf("channel %d = %v, want %v", i, got[i], want)
+		}
+	}
+
+	// The original source is closed with the last channel source.
+	if err := chans[0].Close(); err != nil || src.closed {
+		t.Errorf("Close() error = %v, source closed = %t, want source open", err, src.closed)
+	}
+
+	if err := chans[1].Close(); err != nil || !src.closed {
+		t.Errorf("Close() error = %v, source closed = %t, want source closed", err, src.closed)
+	}
+}
+
+func TestSplitChannelsClosedChannel(t *testing.T) {
+	var (
+		f   = Format{NumChannels: 2, SampleRateHertz: testRate, BitDepth: BitDepth8}
+		src = &chunkSource{data: []byte{1, 2, 3, 4, 5, 6}, size: 2}
+	)
+
+	chans, err := SplitChannels(src, f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Audio of a closed channel is discarded instead of buffered.
+	_ = chans[1].Close()
+
+	got, err := readAll(chans[0])
+	if err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+
+	if want := []byte{1, 3, 5}; !bytes.Equal(got, want) {
+		t.Errorf("channel 0 = %v, want %v", got, want)
+	}
+
+	if chans[1].buf.Len() != 0 {
+		t.Errorf("closed channel buffered %d bytes", chans[1].buf.Len())
+	}
+
+	if n, err := chans[0].WriteTo(io.Discard); n != 0 || err != io.EOF {
+		t.Errorf("WriteTo() after end of audio = %d, %v, want 0, %v", n, err, io.EOF)
+	}
+}
+
+func TestSplitChannelsInvalidFormat(t *testing.T) {
+	tests := []struct {
+		f    Format
+		want error
+	}{
+		{f: Format{NumChannels: 2, BitDepth: BitDepth(24)}, want: ErrInvalidBitDepth},
+		{f: Format{NumChannels: 0, BitDepth: BitDepth16}, want: ErrInvalidBuffer},
+	}
+
+	for _, tt := range tests {
+		if _, err := SplitChannels(&chunkSource{}, tt.f); !errors.Is(err, tt.want) {
+			t.Errorf("SplitChannels(%+v) error = %v, want %v", tt.f, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,167 @@
+package audio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"time"
+)
+
+// ErrInvalidChunkSize is returned when the duration of chunks is not positive.
+var ErrInvalidChunkSize = errors.New("invalid chunk size")
+
+type chunkMode int
+
+const (
+	chunkFixed chunkMode = iota
+	chunkGrowing
+	chunkAdaptive
+)
+
+// ChunkOption is an option of a ChunkedStream.
+type ChunkOption func(*ChunkedStream)
+
+// WithGrowingChunks makes the size of chunks double after every chunk, up to maxSize.
+// This suits uploading files, where first results should arrive quickly, but the throughput matters afterwards.
+func WithGrowingChunks(maxSize time.Duration) ChunkOption {
+	return func(s *ChunkedStream) {
+		s.mode = chunkGrowing
+		s.max = maxSize
+	}
+}
+
+// WithAdaptiveChunks makes the size of chunks adapt to the consumer, between minSize and maxSize.
+// The size is halved while the consumer easily keeps up with the chunks and doubled when it falls behind,
+// e.g. due to network latency. This suits live audio, where chunks should be as small as possible,
+// to minimise the latency. src must write chunks no longer than minSize, otherwise chunks cannot be smaller.
+func WithAdaptiveChunks(minSize, maxSize time.Duration) ChunkOption {
+	return func(s *ChunkedStream) {
+		s.mode = chunkAdaptive
+		s.min = minSize
+		s.max = maxSize
+	}
+}
+
+// ChunkedStream is an EncodedSource that writes audio from another EncodedSource in chunks of specified duration,
+// regardless of the size of chunks written by the source. Chunks always contain whole frames.
+// The size of chunks controls the trade-off between latency and overhead, when the chunks are sent over network:
+// every chunk adds its duration to the latency, but smaller chunks mean more messages.
+type ChunkedStream struct {
+	src      EncodedSource
+	fmt      Format
+	mode     chunkMode
+	size     time.Duration
+	min      time.Duration
+	max      time.Duration
+	pending  bytes.Buffer
+	srcErr   error
+	lastRead time.Time
+}
+
+// NewChunkedStream returns a new ChunkedStream that writes audio from src, encoded in format f, in chunks of size d.
+func NewChunkedStream(src EncodedSource, f Format, d time.Duration, opts ...ChunkOption) (*ChunkedStream, error) {
+	if sampleBytes(f.BitDepth) == 0 {
+		return nil, ErrInvalidBitDepth
+	}
+
+	if f.SampleRateHertz < 1 || f.NumChannels < 1 {
+		return nil, ErrInvalidSampleRate
+	}
+
+	s := &ChunkedStream{
+		src:  src,
+		fmt:  f,
+		size: d,
+		min:  d,
+		max:  d,
+	}
+
+	for _, o := range opts {
+		o(s)
+	}
+
+	if d <= 0 || s.min <= 0 || s.max < s.min {
+		return nil, ErrInvalidChunkSize
+	}
+
+	s.size = clampDuration(s.size, s.min, s.max)
+
+	return s, nil
+}
+
+// ChunkSize returns the number of samples (in all channels) in a chunk of duration d of audio in format f.
+// Chunks are at least one frame long.
+func ChunkSize(f Format, d time.Duration) int {
+	frames := int(d * time.Duration(f.SampleRateHertz) / time.Second)
+	if frames < 1 {
+		frames = 1
+	}
+
+	return frames * int(f.NumChannels)
+}
+
+// WriteTo writes the next chunk of audio into w, reading from src until there is enough audio for the chunk.
+// It returns the number of bytes written to w and io.EOF with the last chunk, which may be shorter.
+func (s *ChunkedStream) WriteTo(w io.Writer) (int64, error) {
+	if s.mode == chunkAdaptive {
+		s.adapt()
+	}
+
+	size := ChunkSize(s.fmt, s.size) * sampleBytes(s.fmt.BitDepth)
+
+	for s.pending.Len() < size && s.srcErr == nil {
+		_, s.srcErr = s.src.WriteTo(&s.pending)
+	}
+
+	if s.srcErr != nil && s.srcErr != io.EOF {
+		return 0, s.srcErr
+	}
+
+	n, err := w.Write(s.pending.Next(size))
+	if err != nil {
+		return int64(n), err
+	}
+
+	s.lastRead = time.Now()
+
+	if s.mode == chunkGrowing {
+		s.size = clampDuration(s.size*2, s.min, s.max)
+	}
+
+	if s.srcErr == io.EOF && s.pending.Len() == 0 {
+		return int64(n), io.EOF
+	}
+
+	return int64(n), nil
+}
+
+// Close closes src.
+func (s *ChunkedStream) Close() error {
+	return s.src.Close()
+}
+
+// adapt adjusts the size of the next chunk, based on the time the consumer took to process the previous one.
+// The size is only halved if the consumer would keep up with halved chunks, to avoid oscillating.
+func (s *ChunkedStream) adapt() {
+	if s.lastRead.IsZero() {
+		return
+	}
+
+	switch t := time.Since(s.lastRead); {
+	case t > s.size/2:
+		s.size = clampDuration(s.size*2, s.min, s.max)
+	case t < s.size/4:
+		s.size = clampDuration(s.size/2, s.min, s.max)
+	}
+}
+
+func clampDuration(d, lo, hi time.Duration) time.Duration {
+	switch {
+	case d < lo:
+		return lo
+	case d > hi:
+		return hi
+	default:
+		return d
+	}
+}
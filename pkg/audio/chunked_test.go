@@ -0,0 +1,207 @@
+package audio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+// writeChunks writes all chunks of s, calling pause before each of them if it is not nil,
+// and returns their sizes and the audio written.
+func writeChunks(t *testing.T, s EncodedSource, pause func(chunk int)) ([]int, []byte) {
+	t.Helper()
+
+	var (
+		buf    bytes.Buffer
+		chunks []int
+	)
+
+	for {
+		if pause != nil {
+			pause(len(chunks))
+		}
+
+		n, err := s.WriteTo(&buf)
+		chunks = append(chunks, int(n))
+
+		if err == io.EOF {
+			return chunks, buf.Bytes()
+		}
+
+		if err != nil {
+			t.Fatalf("WriteTo() error = %v", err)
+		}
+
+		if len(chunks) > 1000 {
+			t.Fatal("WriteTo() did not return io.EOF")
+		}
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+func TestChunkSize(t *testing.T) {
+	tests := []struct {
+		rate     int32
+		channels int32
+		d        time.Duration
+		want     int
+	}{
+		{rate: 8000, channels: 1, d: 20 * time.Millisecond, want: 160},
+		{rate: 16000, channels: 1, d: 20 * time.Millisecond, want: 320},
+		{rate: 8000, channels: 2, d: 20 * time.Millisecond, want: 320},
+		{rate: 16000, channels: 2, d: 100 * time.Millisecond, want: 3200},
+		{rate: 44100, channels: 1, d: 10 * time.Millisecond, want: 441},
+		{rate: 16000, channels: 2, d: time.Microsecond, want: 2},
+	}
+
+	for _, tt := range tests {
+		f := Format{NumChannels: tt.channels, SampleRateHertz: tt.rate, BitDepth: BitDepth16}
+
+		if got := ChunkSize(f, tt.d); got != tt.want {
+			t.Errorf("ChunkSize(%d Hz, %d channels, %s) = %d, want %d", tt.rate, tt.channels, tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestChunkedStreamFixed(t *testing.T) {
+	tests := []struct {
+		rate     int32
+		channels int32
+		want     int // Bytes in a chunk of 20ms of 16-bit audio.
+	}{
+		{rate: 8000, channels: 1, want: 320},
+		{rate: 16000, channels: 1, want: 640},
+		{rate: 8000, channels: 2, want: 640},
+		{rate: 16000, channels: 2, want: 1280},
+	}
+
+	for _, tt := range tests {
+		var (
+			f = Format{NumChannels: tt.channels, SampleRateHertz: tt.rate, BitDepth: BitDepth16}
+			// Three and a half chunks, written by the source in chunks that are not aligned to frames.
+			in  = make([]byte, 3*tt.want+tt.want/2)
+			src = &chunkSource{data: in, size: 101}
+		)
+
+		for i := range in {
+			in[i] = byte(i)
+		}
+
+		s, err := NewChunkedStream(src, f, 20*time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		chunks, out := writeChunks(t, s, nil)
+
+		if want := []int{tt.want, tt.want, tt.want, tt.want / 2}; !equalInts(chunks, want) {
+			t.Errorf("%d Hz, %d channels: chunks = %v, want %v", tt.rate, tt.channels, chunks, want)
+		}
+
+		if !bytes.Equal(out, in) {
+			t.Errorf("%d Hz, %d channels: written audio differs from source audio", tt.rate, tt.channels)
+		}
+	}
+}
+
+func TestChunkedStreamGrowing(t *testing.T) {
+	var (
+		f   = Format{NumChannels: 1, SampleRateHertz: 16000, BitDepth: BitDepth16}
+		src = &chunkSource{data: make([]byte, 10000), size: 320}
+	)
+
+	s, err := NewChunkedStream(src, f, 20*time.Millisecond, WithGrowingChunks(80*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 20ms, 40ms and then 80ms chunks, followed by the rest of the audio.
+	chunks, _ := writeChunks(t, s, nil)
+	if want := []int{640, 1280, 2560, 2560, 2560, 400}; !equalInts(chunks, want) {
+		t.Errorf("chunks = %v, want %v", chunks, want)
+	}
+}
+
+func TestChunkedStreamAdaptive(t *testing.T) {
+	var (
+		f   = Format{NumChannels: 1, SampleRateHertz: 8000, BitDepth: BitDepth16}
+		src = &chunkSource{data: make([]byte, 10000), size: 320} // 20ms chunks of audio.
+	)
+
+	s, err := NewChunkedStream(src, f, 20*time.Millisecond, WithAdaptiveChunks(20*time.Millisecond, 80*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The consumer falls behind with the first chunks and then keeps up easily.
+	chunks, _ := writeChunks(t, s, func(chunk int) {
+		switch chunk {
+		case 1:
+			time.Sleep(15 * time.Millisecond)
+		case 2:
+			time.Sleep(25 * time.Millisecond)
+		case 3:
+			time.Sleep(50 * time.Millisecond)
+		}
+	})
+
+	// Chunks grow from 20ms up to 80ms, and then shrink back down to 20ms.
+	want := []int{320, 640, 1280, 1280, 640, 320}
+	if len(chunks) < len(want) || !equalInts(chunks[:len(want)], want) {
+		t.Errorf("chunks = %v, want %v at first", chunks, want)
+	}
+}
+
+func TestNewChunkedStreamInvalid(t *testing.T) {
+	f := Format{NumChannels: 1, SampleRateHertz: 16000, BitDepth: BitDepth16}
+
+	tests := []struct {
+		name string
+		f    Format
+		d    time.Duration
+		opts []ChunkOption
+		want error
+	}{
+		{name: "zero duration", f: f, d: 0, want: ErrInvalidChunkSize},
+		{
+			name: "maximum below minimum",
+			f:    f,
+			d:    20 * time.Millisecond,
+			opts: []ChunkOption{WithAdaptiveChunks(40*time.Millisecond, 20*time.Millisecond)},
+			want: ErrInvalidChunkSize,
+		},
+		{
+			name: "invalid bit depth",
+			f:    Format{NumChannels: 1, SampleRateHertz: 16000},
+			d:    time.Second,
+			want: ErrInvalidBitDepth,
+		},
+		{
+			name: "invalid sample rate",
+			f:    Format{NumChannels: 1, BitDepth: BitDepth16},
+			d:    time.Second,
+			want: ErrInvalidSampleRate,
+		},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewChunkedStream(&chunkSource{}, tt.f, tt.d, tt.opts...); !errors.Is(err, tt.want) {
+			t.Errorf("%s: NewChunkedStream() error = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
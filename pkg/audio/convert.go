@@ -0,0 +1,94 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+)
+
+// ConvertedStream is an EncodedSource that converts the bit depth of audio written by another EncodedSource,
+// e.g. into 16-bit samples, which is the only encoding that SLU API accepts.
+// Samples are scaled, so that the full scale of the source maps to the full scale of the target bit depth.
+type ConvertedStream struct {
+	src     EncodedSource
+	fmt     Format
+	ord     binary.ByteOrder
+	depth   BitDepth
+	pending bytes.Buffer
+	out     []byte
+}
+
+// NewConvertedStream returns a new ConvertedStream that converts audio written by src,
+// encoded in format f with byte order ord, into samples of bit depth d with the same byte order.
+func NewConvertedStream(src EncodedSource, f Format, ord binary.ByteOrder, d BitDepth) (*ConvertedStream, error) {
+	if sampleBytes(f.BitDepth) == 0 || sampleBytes(d) == 0 {
+		return nil, ErrInvalidBitDepth
+	}
+
+	return &ConvertedStream{
+		src:   src,
+		fmt:   f,
+		ord:   ord,
+		depth: d,
+	}, nil
+}
+
+// Format returns the audio format of converted audio.
+func (s *ConvertedStream) Format() Format {
+	f := s.fmt
+	f.BitDepth = s.depth
+
+	return f
+}
+
+// WriteTo writes the next chunk of audio from src into w, after converting it.
+// It returns the number of bytes written to w and io.EOF with the last chunk.
+// If the last chunk ends with an incomplete sample, io.ErrUnexpectedEOF is returned instead.
+func (s *ConvertedStream) WriteTo(w io.Writer) (int64, error) {
+	_, srcErr := s.src.WriteTo(&s.pending)
+	if srcErr != nil && srcErr != io.EOF {
+		return 0, srcErr
+	}
+
+	var (
+		inSize  = sampleBytes(s.fmt.BitDepth)
+		outSize = sampleBytes(s.depth)
+		data    = s.pending.Next(s.pending.Len() / inSize * inSize)
+		n       = len(data) / inSize
+		shift   = int(s.depth) - int(s.fmt.BitDepth)
+	)
+
+	if cap(s.out) < n*outSize {
+		s.out = make([]byte, n*outSize)
+	}
+
+	out := s.out[:n*outSize]
+
+	for i := 0; i < n; i++ {
+		v := decodeSample(s.ord, s.fmt.BitDepth, data[i*inSize:])
+
+		if shift > 0 {
+			v <<= uint(shift)
+		} else {
+			v >>= uint(-shift)
+		}
+
+		encodeSample(s.ord, s.depth, out[i*outSize:], v)
+	}
+
+	written, err := w.Write(out)
+	if err != nil {
+		return int64(written), err
+	}
+
+	if srcErr == io.EOF && s.pending.Len() > 0 {
+		return int64(written), io.ErrUnexpectedEOF
+	}
+
+	return int64(written), srcErr
+}
+
+// Close closes src.
+func (s *ConvertedStream) Close() error {
+	return s.src.Close()
+}
@@ -0,0 +1,98 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestConvertedStream(t *testing.T) {
+	tests := []struct {
+		name  string
+		from  BitDepth
+		to    BitDepth
+		in    []int64
+		want  []int64
+		chunk int
+	}{
+		{
+			name: "8 to 16 bits", from: BitDepth8, to: BitDepth16, chunk: 3,
+			in: []int64{0, 1, -1, 127, -128}, want: []int64{0, 256, -256, 32512, -32768},
+		},
+		{
+			name: "32 to 16 bits", from: BitDepth32, to: BitDepth16, chunk: 5,
+			in: []int64{0, 1 << 16, -1 << 16, 1<<31 - 1, -1 << 31}, want: []int64{0, 1, -1, 32767, -32768},
+		},
+		{
+			name: "64 to 16 bits", from: BitDepth64, to: BitDepth16, chunk: 11,
+			in: []int64{0, 1 << 48, 1<<63 - 1, -1 << 63}, want: []int64{0, 1, 32767, -32768},
+		},
+		{
+			name: "16 to 16 bits", from: BitDepth16, to: BitDepth16, chunk: 1,
+			in: []int64{0, 1, -1, 32767, -32768}, want: []int64{0, 1, -1, 32767, -32768},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			src := &chunkSource{data: encodeSamples(tt.from, tt.in...), size: tt.chunk}
+
+			f := Format{NumChannels: 1, SampleRateHertz: 16000, BitDepth: tt.from}
+
+			s, err := NewConvertedStream(src, f, binary.LittleEndian, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := s.Format().BitDepth; got != tt.to {
+				t.Errorf("Format().BitDepth = %d, want %d", got, tt.to)
+			}
+
+			got, err := readAll(s)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if want := encodeSamples(tt.to, tt.want...); !bytes.Equal(got, want) {
+				t.Errorf("converted audio = %v, want %v", got, want)
+			}
+
+			if err := s.Close(); err != nil || !src.closed {
+				t.Errorf("Close() error = %v, source closed = %v", err, src.closed)
+			}
+		})
+	}
+}
+
+func TestConvertedStreamIncompleteSample(t *testing.T) {
+	var (
+		src = &chunkSource{data: append(encodeSamples(BitDepth32, 1<<16), 0, 0), size: 4}
+		f   = Format{NumChannels: 1, SampleRateHertz: 16000, BitDepth: BitDepth32}
+	)
+
+	s, err := NewConvertedStream(src, f, binary.LittleEndian, BitDepth16)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readAll(s)
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	if want := encodeSamples(BitDepth16, 1); !bytes.Equal(got, want) {
+		t.Errorf("converted audio = %v, want %v", got, want)
+	}
+}
+
+func TestNewConvertedStreamInvalidBitDepth(t *testing.T) {
+	f := Format{NumChannels: 1, SampleRateHertz: 16000, BitDepth: BitDepth16}
+
+	if _, err := NewConvertedStream(&chunkSource{}, f, binary.LittleEndian, BitDepth(24)); err != ErrInvalidBitDepth {
+		t.Errorf("error = %v, want %v", err, ErrInvalidBitDepth)
+	}
+}
@@ -0,0 +1,307 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gordonklaus/portaudio"
+	"github.com/hashicorp/go-multierror"
+)
+
+const defaultProbeBufferSize = 1024
+
+// Device lookup errors.
+var (
+	ErrDeviceNotFound  = errors.New("audio device not found")
+	ErrAmbiguousDevice = errors.New("audio device name matches multiple devices")
+)
+
+// HostAPI is an audio host API of the OS (e.g. ALSA, CoreAudio or WASAPI) and the devices it provides.
+type HostAPI struct {
+	Name      string
+	IsDefault bool
+	Devices   []Device
+}
+
+// Device is an audio device of the OS.
+// Devices are identified by their index, which is stable as long as devices are not added or removed.
+type Device struct {
+	Index             int
+	Name              string
+	HostAPI           string
+	MaxInputChannels  int
+	MaxOutputChannels int
+	DefaultSampleRate float64
+	IsDefaultInput    bool
+	IsDefaultOutput   bool
+}
+
+// String returns the name of the device with its index.
+func (d Device) String() string {
+	return fmt.Sprintf("[%d] %s", d.Index, d.Name)
+}
+
+// IsInput returns true if the device can be used for recording audio.
+func (d Device) IsInput() bool {
+	return d.MaxInputChannels > 0
+}
+
+// IsOutput returns true if the device can be used for playing audio.
+func (d Device) IsOutput() bool {
+	return d.MaxOutputChannels > 0
+}
+
+// ListHostAPIs returns the audio host APIs of the OS and their devices.
+func ListHostAPIs() (apis []HostAPI, err error) {
+	err = withPortaudio(func() error {
+		hosts, err := portaudio.HostApis()
+		if err != nil {
+			return err
+		}
+
+		// Not all host APIs have default devices, so missing defaults are not errors.
+		def, _ := portaudio.DefaultHostApi()
+		devs, err := listDevices()
+		if err != nil {
+			return err
+		}
+
+		apis = make([]HostAPI, 0, len(hosts))
+
+		for _, h := range hosts {
+			a := HostAPI{
+				Name:      h.Name,
+				IsDefault: h == def,
+			}
+
+			for _, d := range devs {
+				if d.HostAPI == h.Name {
+					a.Devices = append(a.Devices, d)
+				}
+			}
+
+			apis = append(apis, a)
+		}
+
+		return nil
+	})
+
+	return apis, err
+}
+
+// ListDevices returns all audio devices of the OS, ordered by their index.
+func ListDevices() (devs []Device, err error) {
+	err = withPortaudio(func() error {
+		devs, err = listDevices()
+		return err
+	})
+
+	return devs, err
+}
+
+// FindInputDevice returns the input device specified by spec, which is either an index or a name of the device.
+// Names are matched case-insensitively, first exactly and then as a substring of device names.
+// If spec is empty, the default input device is returned.
+func FindInputDevice(spec string) (Device, error) {
+	return findDevice(spec, Device.IsInput, func(d Device) bool {
+		return d.IsDefaultInput
+	})
+}
+
+// FindOutputDevice returns the output device specified by spec, which is either an index or a name of the device.
+// Names are matched case-insensitively, first exactly and then as a substring of device names.
+// If spec is empty, the default output device is returned.
+func FindOutputDevice(spec string) (Device, error) {
+	return findDevice(spec, Device.IsOutput, func(d Device) bool {
+		return d.IsDefaultOutput
+	})
+}
+
+// ProbeDefaultInput checks that the default OS audio input device can be opened for recording audio in format f.
+// It returns the name of the device.
+func ProbeDefaultInput(f Format) (name string, err error) {
+	buf, err := NewBuffer(f.BitDepth, defaultProbeBufferSize)
+	if err != nil {
+		return "", err
+	}
+
+	err = withPortaudio(func() error {
+		dev, err := portaudio.DefaultInputDevice()
+		if err != nil {
+			return err
+		}
+
+		name = dev.Name
+
+		stream, err := openStream(nil, true, f, buf)
+		if err != nil {
+			return err
+		}
+
+		return stream.Close()
+	})
+
+	return name, err
+}
+
+func findDevice(spec string, usable, isDefault func(Device) bool) (Device, error) {
+	devs, err := ListDevices()
+	if err != nil {
+		return Device{}, err
+	}
+
+	if spec == "" {
+		for _, d := range devs {
+			if isDefault(d) {
+				return d, nil
+			}
+		}
+
+		return Device{}, fmt.Errorf("%w: no default device", ErrDeviceNotFound)
+	}
+
+	if i, err := strconv.Atoi(spec); err == nil {
+		if i < 0 || i >= len(devs) || !usable(devs[i]) {
+			return Device{}, fmt.Errorf("%w: %d", ErrDeviceNotFound, i)
+		}
+
+		return devs[i], nil
+	}
+
+	var (
+		name    = strings.ToLower(spec)
+		matches []Device
+	)
+
+	for _, d := range devs {
+		if !usable(d) {
+			continue
+		}
+
+		n := strings.ToLower(d.Name)
+		if n == name {
+			return d, nil
+		}
+
+		if strings.Contains(n, name) {
+			matches = append(matches, d)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return Device{}, fmt.Errorf("%w: %q", ErrDeviceNotFound, spec)
+	case 1:
+		return matches[0], nil
+	default:
+		names := make([]string, 0, len(matches))
+		for _, d := range matches {
+			names = append(names, d.String())
+		}
+
+		return Device{}, fmt.Errorf("%w: %s", ErrAmbiguousDevice, strings.Join(names, ", "))
+	}
+}
+
+// listDevices returns all audio devices. Portaudio must be initialised.
+func listDevices() ([]Device, error) {
+	infos, err := portaudio.Devices()
+	if err != nil {
+		return nil, err
+	}
+
+	// Not all host APIs have default devices, so missing defaults are not errors.
+	defIn, _ := portaudio.DefaultInputDevice()
+	defOut, _ := portaudio.DefaultOutputDevice()
+
+	devs := make([]Device, 0, len(infos))
+
+	for i, d := range infos {
+		dev := Device{
+			Index:             i,
+			Name:              d.Name,
+			MaxInputChannels:  d.MaxInputChannels,
+			MaxOutputChannels: d.MaxOutputChannels,
+			DefaultSampleRate: d.DefaultSampleRate,
+			IsDefaultInput:    d == defIn,
+			IsDefaultOutput:   d == defOut,
+		}
+
+		if d.HostApi != nil {
+			dev.HostAPI = d.HostApi.Name
+		}
+
+		devs = append(devs, dev)
+	}
+
+	return devs, nil
+}
+
+// deviceInfo returns the portaudio device info of dev, or the default device if dev is nil.
+// Portaudio must be initialised.
+func deviceInfo(dev *Device, input bool) (*portaudio.DeviceInfo, error) {
+	if dev == nil {
+		if input {
+			return portaudio.DefaultInputDevice()
+		}
+
+		return portaudio.DefaultOutputDevice()
+	}
+
+	infos, err := portaudio.Devices()
+	if err != nil {
+		return nil, err
+	}
+
+	// Devices may have been added or removed since dev was looked up, which changes device indices.
+	if dev.Index < 0 || dev.Index >= len(infos) || infos[dev.Index].Name != dev.Name {
+		return nil, fmt.Errorf("%w: %s", ErrDeviceNotFound, dev)
+	}
+
+	return infos[dev.Index], nil
+}
+
+// openStream opens a blocking input or output stream with format f on dev, or the default device if dev is nil.
+// Portaudio must be initialised.
+func openStream(dev *Device, input bool, f Format, buf Buffer) (*portaudio.Stream, error) {
+	// Samples of all channels are interleaved in the buffer.
+	if f.NumChannels < 1 || buf.Size()%int(f.NumChannels) != 0 {
+		return nil, fmt.Errorf("%w: size %d is not a multiple of %d channels", ErrInvalidBuffer, buf.Size(), f.NumChannels)
+	}
+
+	info, err := deviceInfo(dev, input)
+	if err != nil {
+		return nil, err
+	}
+
+	var p portaudio.StreamParameters
+	if input {
+		p = portaudio.HighLatencyParameters(info, nil)
+		p.Input.Channels = int(f.NumChannels)
+	} else {
+		p = portaudio.HighLatencyParameters(nil, info)
+		p.Output.Channels = int(f.NumChannels)
+	}
+
+	p.SampleRate = float64(f.SampleRateHertz)
+	p.FramesPerBuffer = buf.Size() / int(f.NumChannels)
+
+	return portaudio.OpenStream(p, buf.Data())
+}
+
+// withPortaudio calls fn with portaudio initialised.
+func withPortaudio(fn func() error) (err error) {
+	if err := portaudio.Initialize(); err != nil {
+		return err
+	}
+
+	defer func() {
+		if e := portaudio.Terminate(); e != nil {
+			err = multierror.Append(err, e).ErrorOrNil()
+		}
+	}()
+
+	return fn()
+}
@@ -0,0 +1,167 @@
+package audio
+
+import (
+	"encoding/binary"
+	"io"
+)
+
+// The functions below convert between samples and their binary representation in bulk.
+// Little- and big-endian byte orders are handled by concrete types, so that the conversions can be inlined,
+// other byte orders fall back to calling ord methods through the interface.
+
+// readSamples reads len(p) bytes of samples of specified size from r into p and returns the number of samples read.
+// It returns io.EOF if fewer bytes were read and io.ErrUnexpectedEOF if the last sample was incomplete.
+func readSamples(r io.Reader, p []byte, size int) (int, error) {
+	n, err := io.ReadFull(r, p)
+	if err == io.ErrUnexpectedEOF && n%size == 0 {
+		err = io.EOF
+	}
+
+	return n / size, err
+}
+
+func putInt8s(_ binary.ByteOrder, dst []byte, src []int8) {
+	for i, v := range src {
+		dst[i] = byte(v)
+	}
+}
+
+func getInt8s(_ binary.ByteOrder, dst []int8, src []byte) {
+	for i := range dst {
+		dst[i] = int8(src[i])
+	}
+}
+
+func putInt16s(ord binary.ByteOrder, dst []byte, src []int16) {
+	switch ord {
+	case binary.LittleEndian:
+		for i, v := range src {
+			binary.LittleEndian.PutUint16(dst[i*2:], uint16(v))
+		}
+	case binary.BigEndian:
+		for i, v := range src {
+			binary.BigEndian.PutUint16(dst[i*2:], uint16(v))
+		}
+	default:
+		for i, v := range src {
+			ord.PutUint16(dst[i*2:], uint16(v))
+		}
+	}
+}
+
+func getInt16s(ord binary.ByteOrder, dst []int16, src []byte) {
+	switch ord {
+	case binary.LittleEndian:
+		for i := range dst {
+			dst[i] = int16(binary.LittleEndian.Uint16(src[i*2:]))
+		}
+	case binary.BigEndian:
+		for i := range dst {
+			dst[i] = int16(binary.BigEndian.Uint16(src[i*2:]))
+		}
+	default:
+		for i := range dst {
+			dst[i] = int16(ord.Uint16(src[i*2:]))
+		}
+	}
+}
+
+func putInt32s(ord binary.ByteOrder, dst []byte, src []int32) {
+	switch ord {
+	case binary.LittleEndian:
+		for i, v := range src {
+			binary.LittleEndian.PutUint32(dst[i*4:], uint32(v))
+		}
+	case binary.BigEndian:
+		for i, v := range src {
+			binary.BigEndian.PutUint32(dst[i*4:], uint32(v))
+		}
+	default:
+		for i, v := range src {
+			ord.PutUint32(dst[i*4:], uint32(v))
+		}
+	}
+}
+
+func getInt32s(ord binary.ByteOrder, dst []int32, src []byte) {
+	switch ord {
+	case binary.LittleEndian:
+		for i := range dst {
+			dst[i] = int32(binary.LittleEndian.Uint32(src[i*4:]))
+		}
+	case binary.BigEndian:
+		for i := range dst {
+			dst[i] = int32(binary.BigEndian.Uint32(src[i*4:]))
+		}
+	default:
+		for i := range dst {
+			dst[i] = int32(ord.Uint32(src[i*4:]))
+		}
+	}
+}
+
+func putInt64s(ord binary.ByteOrder, dst []byte, src []int64) {
+	switch ord {
+	case binary.LittleEndian:
+		for i, v := range src {
+			binary.LittleEndian.PutUint64(dst[i*8:], uint64(v))
+		}
+	case binary.BigEndian:
+		for i, v := range src {
+			binary.BigEndian.PutUint64(dst[i*8:], uint64(v))
+		}
+	default:
+		for i, v := range src {
+			ord.PutUint64(dst[i*8:], uint64(v))
+		}
+	}
+}
+
+func getInt64s(ord binary.ByteOrder, dst []int64, src []byte) {
+	switch ord {
+	case binary.LittleEndian:
+		for i := range dst {
+			dst[i] = int64(binary.LittleEndian.Uint64(src[i*8:]))
+		}
+	case binary.BigEndian:
+		for i := range dst {
+			dst[i] = int64(binary.BigEndian.Uint64(src[i*8:]))
+		}
+	default:
+		for i := range dst {
+			dst[i] = int64(ord.Uint64(src[i*8:]))
+		}
+	}
+}
+
+// ReorderSamples converts samples of specified size in b from byte order from into byte order to, in place.
+func ReorderSamples(b []byte, size int, from, to binary.ByteOrder) {
+	switch {
+	case size < 2 || from == to:
+		return
+	case isBuiltinOrder(from) && isBuiltinOrder(to):
+		for i := 0; i+size <= len(b); i += size {
+			for l, r := i, i+size-1; l < r; l, r = l+1, r-1 {
+				b[l], b[r] = b[r], b[l]
+			}
+		}
+	default:
+		for i := 0; i+size <= len(b); i += size {
+			s := b[i : i+size]
+
+			switch size {
+			case 2:
+				to.PutUint16(s, from.Uint16(s))
+			case 4:
+				to.PutUint32(s, from.Uint32(s))
+			case 8:
+				to.PutUint64(s, from.Uint64(s))
+			}
+		}
+	}
+}
+
+// isBuiltinOrder returns true if ord is either binary.LittleEndian or binary.BigEndian.
+func isBuiltinOrder(ord binary.ByteOrder) bool {
+	return ord == binary.LittleEndian || ord == binary.BigEndian
+}
@@ -0,0 +1,414 @@
+package audio
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidFilter is returned when a filter specification or filter parameters are not valid.
+var ErrInvalidFilter = errors.New("invalid audio filter")
+
+// Names of filters supported by ParseFilterChain.
+const (
+	FilterGain      = "gain"
+	FilterHighPass  = "highpass"
+	FilterAGC       = "agc"
+	FilterNoiseGate = "gate"
+	FilterLimiter   = "limiter"
+)
+
+// Default filter parameters, used when a filter specification does not contain a value.
+const (
+	DefaultGainDB          = 6.0
+	DefaultHighPassHertz   = 80.0
+	DefaultAGCTargetDB     = -20.0
+	DefaultGateThresholdDB = -50.0
+	DefaultLimiterDB       = -1.0
+)
+
+const (
+	agcMaxGainDB    = 30.0
+	agcNoiseFloorDB = -60.0
+	agcWindow       = 0.1 // Seconds.
+	agcAttack       = 0.05
+	agcRelease      = 0.5
+	gateAttack      = 0.001
+	gateRelease     = 0.05
+	gateHold        = 0.1
+	gateDetector    = 0.01
+	limiterRelease  = 0.05
+)
+
+// Filter is a stage of audio processing.
+// Filters process interleaved samples normalised to range [-1, 1], which they modify in place.
+// Filters may keep state between calls, so a Filter must only be used for a single audio stream.
+type Filter interface {
+	Process(samples []float64)
+}
+
+// FilterChain is a sequence of filters, which are applied in order.
+type FilterChain []Filter
+
+// Process applies all filters of the chain to samples.
+func (c FilterChain) Process(samples []float64) {
+	for _, f := range c {
+		f.Process(samples)
+	}
+}
+
+// ParseFilterChain returns a new FilterChain for audio in format f from a comma-separated list of filters.
+// Each filter is specified as a name, optionally followed by '=' and a numeric parameter, e.g. 'agc,highpass=80'.
+// Supported filters and their parameters are:
+//
+//	gain     - fixed gain in dB (default 6)
+//	highpass - high-pass filter cutoff frequency in Hz, which also removes DC offset (default 80)
+//	agc      - automatic gain control target level in dBFS (default -20)
+//	gate     - noise gate threshold in dBFS (default -50)
+//	limiter  - limiter ceiling in dBFS (default -1)
+//
+// An empty spec returns an empty chain.
+func ParseFilterChain(spec string, f Format) (FilterChain, error) {
+	var c FilterChain
+
+	for _, s := range strings.Split(spec, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+
+		var (
+			name  = s
+			param *float64
+		)
+
+		if i := strings.IndexByte(s, '='); i >= 0 {
+			v, err := strconv.ParseFloat(strings.TrimSpace(s[i+1:]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %s: %s", ErrInvalidFilter, s, err)
+			}
+
+			name, param = strings.TrimSpace(s[:i]), &v
+		}
+
+		flt, err := newFilter(strings.ToLower(name), param, f)
+		if err != nil {
+			return nil, err
+		}
+
+		c = append(c, flt)
+	}
+
+	return c, nil
+}
+
+func newFilter(name string, param *float64, f Format) (Filter, error) {
+	value := func(def float64) float64 {
+		if param != nil {
+			return *param
+		}
+
+		return def
+	}
+
+	switch name {
+	case FilterGain:
+		return NewGain(value(DefaultGainDB)), nil
+	case FilterHighPass:
+		return NewHighPass(f, value(DefaultHighPassHertz))
+	case FilterAGC:
+		return NewAGC(f, value(DefaultAGCTargetDB))
+	case FilterNoiseGate:
+		return NewNoiseGate(f, value(DefaultGateThresholdDB))
+	case FilterLimiter:
+		return NewLimiter(f, value(DefaultLimiterDB))
+	default:
+		return nil, fmt.Errorf("%w: unknown filter %q", ErrInvalidFilter, name)
+	}
+}
+
+type gain struct {
+	factor float64
+}
+
+// NewGain returns a new Filter that applies a fixed gain of db decibels.
+// Samples that exceed full scale are clipped when the audio is encoded.
+func NewGain(db float64) Filter {
+	return gain{factor: fromDB(db)}
+}
+
+func (g gain) Process(samples []float64) {
+	for i := range samples {
+		samples[i] *= g.factor
+	}
+}
+
+type highPass struct {
+	channels           int
+	b0, b1, b2, a1, a2 float64
+	state              []biquadState
+}
+
+type biquadState struct {
+	x1, x2, y1, y2 float64
+}
+
+// NewHighPass returns a new second-order Butterworth high-pass Filter with specified cutoff frequency in Hz.
+// Since it removes all content below the cutoff, it also removes DC offset.
+func NewHighPass(f Format, cutoff float64) (Filter, error) {
+	if err := checkFilterFormat(f); err != nil {
+		return nil, err
+	}
+
+	if cutoff <= 0 || cutoff >= float64(f.SampleRateHertz)/2 {
+		return nil, fmt.Errorf("%w: high-pass cutoff %g Hz is out of range", ErrInvalidFilter, cutoff)
+	}
+
+	var (
+		w0    = 2 * math.Pi * cutoff / float64(f.SampleRateHertz)
+		cos   = math.Cos(w0)
+		alpha = math.Sin(w0) / math.Sqrt2 // Q is 1/sqrt(2) for Butterworth response.
+		a0    = 1 + alpha
+	)
+
+	return &highPass{
+		channels: int(f.NumChannels),
+		b0:       (1 + cos) / 2 / a0,
+		b1:       -(1 + cos) / a0,
+		b2:       (1 + cos) / 2 / a0,
+		a1:       -2 * cos / a0,
+		a2:       (1 - alpha) / a0,
+		state:    make([]biquadState, f.NumChannels),
+	}, nil
+}
+
+func (h *highPass) Process(samples []float64) {
+	for i, x := range samples {
+		s := &h.state[i%h.channels]
+		y := h.b0*x + h.b1*s.x1 + h.b2*s.x2 - h.a1*s.y1 - h.a2*s.y2
+
+		s.x2, s.x1 = s.x1, x
+		s.y2, s.y1 = s.y1, y
+		samples[i] = y
+	}
+}
+
+type agc struct {
+	channels   int
+	target     float64
+	maxGain    float64
+	noiseFloor float64
+	levelCoef  float64
+	attack     float64
+	release    float64
+	level      float64 // Mean square of the signal.
+	gain       float64
+}
+
+// NewAGC returns a new automatic gain control Filter, which adjusts gain to keep RMS level of the signal
+// at target dBFS. The gain is reduced quickly and increased slowly, and it is kept unchanged when the signal
+// is below the noise floor, so that silence is not amplified. All channels share the same gain.
+func NewAGC(f Format, target float64) (Filter, error) {
+	if err := checkFilterFormat(f); err != nil {
+		return nil, err
+	}
+
+	if target >= 0 {
+		return nil, fmt.Errorf("%w: AGC target %g dBFS must be below 0", ErrInvalidFilter, target)
+	}
+
+	rate := float64(f.SampleRateHertz)
+
+	return &agc{
+		channels:   int(f.NumChannels),
+		target:     fromDB(target),
+		maxGain:    fromDB(agcMaxGainDB),
+		noiseFloor: fromDB(agcNoiseFloorDB),
+		levelCoef:  smoothing(agcWindow, rate),
+		attack:     smoothing(agcAttack, rate),
+		release:    smoothing(agcRelease, rate),
+		gain:       1,
+	}, nil
+}
+
+func (a *agc) Process(samples []float64) {
+	for i := 0; i+a.channels <= len(samples); i += a.channels {
+		frame := samples[i : i+a.channels]
+
+		a.level += a.levelCoef * (meanSquare(frame) - a.level)
+
+		if rms := math.Sqrt(a.level); rms > a.noiseFloor {
+			want := math.Min(a.target/rms, a.maxGain)
+
+			if want < a.gain {
+				a.gain += a.attack * (want - a.gain)
+			} else {
+				a.gain += a.release * (want - a.gain)
+			}
+		}
+
+		for j := range frame {
+			frame[j] *= a.gain
+		}
+	}
+}
+
+type noiseGate struct {
+	channels  int
+	threshold float64
+	detector  float64
+	attack    float64
+	release   float64
+	hold      int
+	env       float64
+	held      int
+	gain      float64
+}
+
+// NewNoiseGate returns a new noise gate Filter, which mutes the signal while its level is below threshold dBFS.
+// The gate opens quickly and closes smoothly after the level has stayed below the threshold for a while,
+// so that quiet ends of words are not cut off. All channels share the same gate.
+func NewNoiseGate(f Format, threshold float64) (Filter, error) {
+	if err := checkFilterFormat(f); err != nil {
+		return nil, err
+	}
+
+	if threshold >= 0 {
+		return nil, fmt.Errorf("%w: noise gate threshold %g dBFS must be below 0", ErrInvalidFilter, threshold)
+	}
+
+	rate := float64(f.SampleRateHertz)
+
+	return &noiseGate{
+		channels:  int(f.NumChannels),
+		threshold: fromDB(threshold),
+		detector:  smoothing(gateDetector, rate),
+		attack:    smoothing(gateAttack, rate),
+		release:   smoothing(gateRelease, rate),
+		hold:      int(gateHold * rate),
+	}, nil
+}
+
+func (g *noiseGate) Process(samples []float64) {
+	for i := 0; i+g.channels <= len(samples); i += g.channels {
+		frame := samples[i : i+g.channels]
+
+		if p := peak(frame); p > g.env {
+			g.env = p
+		} else {
+			g.env += g.detector * (p - g.env)
+		}
+
+		want := 0.0
+
+		switch {
+		case g.env >= g.threshold:
+			g.held = g.hold
+			want = 1
+		case g.held > 0:
+			g.held--
+			want = 1
+		}
+
+		if want > g.gain {
+			g.gain += g.attack * (want - g.gain)
+		} else {
+			g.gain += g.release * (want - g.gain)
+		}
+
+		for j := range frame {
+			frame[j] *= g.gain
+		}
+	}
+}
+
+type limiter struct {
+	channels int
+	ceiling  float64
+	release  float64
+	env      float64
+}
+
+// NewLimiter returns a new peak limiter Filter, which keeps the signal below ceiling dBFS.
+// The gain is reduced instantly when a peak exceeds the ceiling, and then restored smoothly.
+// All channels share the same gain.
+func NewLimiter(f Format, ceiling float64) (Filter, error) {
+	if err := checkFilterFormat(f); err != nil {
+		return nil, err
+	}
+
+	if ceiling > 0 {
+		return nil, fmt.Errorf("%w: limiter ceiling %g dBFS must not be above 0", ErrInvalidFilter, ceiling)
+	}
+
+	return &limiter{
+		channels: int(f.NumChannels),
+		ceiling:  fromDB(ceiling),
+		release:  smoothing(limiterRelease, float64(f.SampleRateHertz)),
+	}, nil
+}
+
+func (l *limiter) Process(samples []float64) {
+	for i := 0; i+l.channels <= len(samples); i += l.channels {
+		frame := samples[i : i+l.channels]
+
+		if p := peak(frame); p > l.env {
+			l.env = p
+		} else {
+			l.env += l.release * (p - l.env)
+		}
+
+		if l.env <= l.ceiling {
+			continue
+		}
+
+		// Since the envelope is never below the peak of current frame, the frame is always below the ceiling.
+		g := l.ceiling / l.env
+		for j := range frame {
+			frame[j] *= g
+		}
+	}
+}
+
+func checkFilterFormat(f Format) error {
+	if f.NumChannels < 1 || f.SampleRateHertz < 1 {
+		return fmt.Errorf("%w: unsupported audio format %+v", ErrInvalidFilter, f)
+	}
+
+	return nil
+}
+
+// meanSquare returns the mean of squared values in frame.
+func meanSquare(frame []float64) float64 {
+	var sum float64
+	for _, v := range frame {
+		sum += v * v
+	}
+
+	return sum / float64(len(frame))
+}
+
+// peak returns the largest absolute value in frame.
+func peak(frame []float64) float64 {
+	var p float64
+
+	for _, v := range frame {
+		if v = math.Abs(v); v > p {
+			p = v
+		}
+	}
+
+	return p
+}
+
+// fromDB converts decibels into a linear factor.
+func fromDB(db float64) float64 {
+	return math.Pow(10, db/20)
+}
+
+// smoothing returns the coefficient of a one-pole smoothing filter with time constant t seconds.
+func smoothing(t, rate float64) float64 {
+	return 1 - math.Exp(-1/(t*rate))
+}
@@ -0,0 +1,389 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"testing"
+)
+
+// chunkSource is an EncodedSource that writes data in chunks of size bytes.
+type chunkSource struct {
+	data   []byte
+	size   int
+	closed bool
+}
+
+func (s *chunkSource) WriteTo(w io.Writer) (int64, error) {
+	n := s.size
+	if n > len(s.data) {
+		n = len(s.data)
+	}
+
+	written, err := w.Write(s.data[:n])
+	s.data = s.data[n:]
+
+	if err != nil {
+		return int64(written), err
+	}
+
+	if len(s.data) == 0 {
+		return int64(written), io.EOF
+	}
+
+	return int64(written), nil
+}
+
+func (s *chunkSource) Close() error {
+	s.closed = true
+	return nil
+}
+
+// encodeSamples encodes samples of bit depth d in little-endian byte order.
+func encodeSamples(d BitDepth, samples ...int64) []byte {
+	size := sampleBytes(d)
+	b := make([]byte, len(samples)*size)
+
+	for i, v := range samples {
+		encodeSample(binary.LittleEndian, d, b[i*size:], v)
+	}
+
+	return b
+}
+
+// readAll writes all audio from src into a buffer.
+func readAll(src EncodedSource) ([]byte, error) {
+	var buf bytes.Buffer
+
+	for {
+		_, err := src.WriteTo(&buf)
+		if err == io.EOF {
+			return buf.Bytes(), nil
+		}
+
+		if err != nil {
+			return buf.Bytes(), err
+		}
+	}
+}
+
+const testRate = 16000
+
+var testFormat = Format{NumChannels: 1, SampleRateHertz: testRate, BitDepth: BitDepth16}
+
+// sine returns d seconds of a sine wave with frequency freq in Hz and amplitude amp, sampled at testRate.
+func sine(freq, amp, d float64) []float64 {
+	s := make([]float64, int(d*testRate))
+	for i := range s {
+		s[i] = amp * math.Sin(2*math.Pi*freq*float64(i)/testRate)
+	}
+
+	return s
+}
+
+// constant returns d seconds of samples with value v.
+func constant(v, d float64) []float64 {
+	s := make([]float64, int(d*testRate))
+	for i := range s {
+		s[i] = v
+	}
+
+	return s
+}
+
+// tail returns the last d seconds of samples.
+func tail(samples []float64, d float64) []float64 {
+	return samples[len(samples)-int(d*testRate):]
+}
+
+func rms(samples []float64) float64 {
+	var sum float64
+	for _, v := range samples {
+		sum += v * v
+	}
+
+	return math.Sqrt(sum / float64(len(samples)))
+}
+
+func toDB(v float64) float64 {
+	return 20 * math.Log10(v)
+}
+
+// process processes samples with f in chunks of 160 samples, like audio of a stream would be.
+func process(f Filter, samples []float64) []float64 {
+	for i := 0; i < len(samples); i += 160 {
+		end := i + 160
+		if end > len(samples) {
+			end = len(samples)
+		}
+
+		f.Process(samples[i:end])
+	}
+
+	return samples
+}
+
+func TestGain(t *testing.T) {
+	tests := []struct {
+		db   float64
+		want float64
+	}{
+		{db: 0, want: 0.25},
+		{db: 6, want: 0.25 * math.Pow(10, 6.0/20)},
+		{db: -20, want: 0.025},
+	}
+
+	for _, tt := range tests {
+		out := process(NewGain(tt.db), sine(1000, 0.25, 0.1))
+
+		if got := peak(out); math.Abs(got-tt.want) > 1e-3 {
+			t.Errorf("NewGain(%g) amplitude = %g, want %g", tt.db, got, tt.want)
+		}
+	}
+}
+
+func TestHighPass(t *testing.T) {
+	tests := []struct {
+		name     string
+		in       []float64
+		maxRatio float64
+		minRatio float64
+	}{
+		{name: "DC offset", in: constant(0.5, 1), maxRatio: 0.001},
+		{name: "low tone", in: sine(20, 0.5, 1), maxRatio: 0.1},
+		{name: "speech band tone", in: sine(1000, 0.5, 1), minRatio: 0.95, maxRatio: 1.05},
+	}
+
+	for _, tt := range tests {
+		f, err := NewHighPass(testFormat, DefaultHighPassHertz)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		inRMS := rms(tail(tt.in, 0.5))
+		ratio := rms(tail(process(f, tt.in), 0.5)) / inRMS
+
+		if ratio < tt.minRatio || ratio > tt.maxRatio {
+			t.Errorf("%s: output to input RMS ratio = %g, want [%g, %g]", tt.name, ratio, tt.minRatio, tt.maxRatio)
+		}
+	}
+}
+
+func TestAGC(t *testing.T) {
+	tests := []struct {
+		name string
+		amp  float64
+	}{
+		{name: "quiet", amp: 0.01},
+		{name: "loud", amp: 0.9},
+	}
+
+	for _, tt := range tests {
+		f, err := NewAGC(testFormat, DefaultAGCTargetDB)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got := toDB(rms(tail(process(f, sine(440, tt.amp, 5)), 0.5)))
+
+		if math.Abs(got-DefaultAGCTargetDB) > 1 {
+			t.Errorf("%s: RMS level = %.2f dBFS, want %g dBFS", tt.name, got, DefaultAGCTargetDB)
+		}
+	}
+}
+
+func TestAGCStereo(t *testing.T) {
+	f, err := NewAGC(Format{NumChannels: 2, SampleRateHertz: testRate, BitDepth: BitDepth16}, DefaultAGCTargetDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the left channel has signal, so the level of the frames is below the level of that channel.
+	left := sine(440, 0.05, 5)
+	in := make([]float64, 2*len(left))
+
+	for i, v := range left {
+		in[2*i] = v
+	}
+
+	// The last half a second of both channels.
+	if got := toDB(rms(process(f, in)[len(in)-testRate:])); math.Abs(got-DefaultAGCTargetDB) > 1 {
+		t.Errorf("RMS level = %.2f dBFS, want %g dBFS", got, DefaultAGCTargetDB)
+	}
+}
+
+func TestAGCDoesNotAmplifySilence(t *testing.T) {
+	f, err := NewAGC(testFormat, DefaultAGCTargetDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := sine(440, 0.0001, 2)
+	out := process(f, append([]float64(nil), in...))
+
+	if got, want := rms(tail(out, 0.5)), rms(tail(in, 0.5)); math.Abs(got-want) > 1e-9 {
+		t.Errorf("RMS of silence = %g, want %g", got, want)
+	}
+}
+
+func TestNoiseGate(t *testing.T) {
+	f, err := NewNoiseGate(testFormat, DefaultGateThresholdDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		quiet = 0.0005 // Below the threshold of -50 dBFS.
+		in    []float64
+	)
+
+	in = append(in, sine(440, quiet, 0.5)...)
+	in = append(in, sine(440, 0.5, 0.5)...)
+	in = append(in, sine(440, quiet, 1)...)
+
+	out := process(f, append([]float64(nil), in...))
+
+	if got := peak(out[:testRate/2]); got > 1e-6 {
+		t.Errorf("peak of leading silence = %g, want muted", got)
+	}
+
+	// Skip the attack of the gate.
+	tone := out[testRate/2+testRate/100 : testRate]
+	if got := rms(tone) / rms(in[testRate/2+testRate/100:testRate]); math.Abs(got-1) > 0.01 {
+		t.Errorf("tone output to input RMS ratio = %g, want 1", got)
+	}
+
+	if got := peak(tail(out, 0.5)); got > 1e-6 {
+		t.Errorf("peak of trailing silence = %g, want muted", got)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []float64
+	}{
+		{name: "loud tone", in: sine(440, 1.5, 1)},
+		{name: "peaks", in: append(sine(440, 0.5, 0.5), 0.99, -2, 4, 0.3, -0.95)},
+		{name: "below ceiling", in: sine(440, 0.5, 0.5)},
+	}
+
+	ceiling := math.Pow(10, DefaultLimiterDB/20)
+
+	for _, tt := range tests {
+		f, err := NewLimiter(testFormat, DefaultLimiterDB)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for i, v := range process(f, tt.in) {
+			if math.Abs(v) > ceiling+1e-12 {
+				t.Errorf("%s: sample %d = %g exceeds ceiling %g", tt.name, i, v, ceiling)
+				break
+			}
+		}
+	}
+}
+
+func TestLimiterKeepsQuietSignal(t *testing.T) {
+	f, err := NewLimiter(testFormat, DefaultLimiterDB)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		in  = sine(440, 0.5, 0.5)
+		out = process(f, append([]float64(nil), in...))
+	)
+
+	for i := range in {
+		if in[i] != out[i] {
+			t.Fatalf("sample %d = %g, want %g", i, out[i], in[i])
+		}
+	}
+}
+
+func TestParseFilterChain(t *testing.T) {
+	tests := []struct {
+		spec    string
+		wantLen int
+		wantErr bool
+	}{
+		{spec: "", wantLen: 0},
+		{spec: "gain", wantLen: 1},
+		{spec: "highpass=100, agc , gate=-40,limiter", wantLen: 4},
+		{spec: "GAIN=-3", wantLen: 1},
+		{spec: "echo", wantErr: true},
+		{spec: "gain=loud", wantErr: true},
+		{spec: "highpass=8000", wantErr: true},
+		{spec: "agc=0", wantErr: true},
+		{spec: "gate=1", wantErr: true},
+		{spec: "limiter=3", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		c, err := ParseFilterChain(tt.spec, testFormat)
+		if tt.wantErr {
+			if !errors.Is(err, ErrInvalidFilter) {
+				t.Errorf("ParseFilterChain(%q) error = %v, want %v", tt.spec, err, ErrInvalidFilter)
+			}
+
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("ParseFilterChain(%q) error = %v", tt.spec, err)
+		} else if len(c) != tt.wantLen {
+			t.Errorf("ParseFilterChain(%q) returned %d filters, want %d", tt.spec, len(c), tt.wantLen)
+		}
+	}
+}
+
+func TestFilteredStreamIncompleteFrame(t *testing.T) {
+	var (
+		f     = Format{NumChannels: 2, SampleRateHertz: testRate, BitDepth: BitDepth16}
+		in    = encodeSamples(BitDepth16, 100, -100, 200, -200, 300)
+		src   = &chunkSource{data: in, size: 4}
+		chain = FilterChain{NewGain(-6.0206)} // Halves the amplitude.
+	)
+
+	s, err := NewFilteredStream(src, f, binary.LittleEndian, chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readAll(s)
+	if err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+
+	// The last sample does not form a whole frame, so it is filtered padded with silence.
+	if want := encodeSamples(BitDepth16, 50, -50, 100, -100, 150); string(got) != string(want) {
+		t.Errorf("WriteTo() = %v, want %v", got, want)
+	}
+}
+
+func TestFilteredStreamIncompleteSample(t *testing.T) {
+	var (
+		f   = Format{NumChannels: 2, SampleRateHertz: testRate, BitDepth: BitDepth16}
+		in  = append(encodeSamples(BitDepth16, 100, -100, 200), 1)
+		src = &chunkSource{data: in, size: 3}
+	)
+
+	s, err := NewFilteredStream(src, f, binary.LittleEndian, FilterChain{NewGain(-6.0206)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readAll(s)
+	if err != io.ErrUnexpectedEOF {
+		t.Errorf("WriteTo() error = %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	// Whole samples of the incomplete frame are filtered and the incomplete sample is dropped.
+	if want := encodeSamples(BitDepth16, 50, -50, 100); string(got) != string(want) {
+		t.Errorf("WriteTo() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,249 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math"
+)
+
+// EncodedSource is a source of binary encoded audio, such as RecordStream.
+// Every call to WriteTo writes the next chunk of audio to a writer and io.EOF is returned with the last chunk.
+type EncodedSource interface {
+	io.WriterTo
+	io.Closer
+}
+
+// FilteredSource is an audio Source that applies a FilterChain to audio read from another Source.
+type FilteredSource struct {
+	src     Source
+	fmt     Format
+	chain   FilterChain
+	ints    []int
+	samples []float64
+}
+
+// NewFilteredSource returns a new FilteredSource that reads audio from src and processes it with c.
+func NewFilteredSource(src Source, c FilterChain) *FilteredSource {
+	return &FilteredSource{
+		src:   src,
+		fmt:   src.Format(),
+		chain: c,
+	}
+}
+
+// Format returns the audio format of src.
+func (s *FilteredSource) Format() Format {
+	return s.fmt
+}
+
+// Buffer returns a buffer that can be used for calling ReadBuffer.
+func (s *FilteredSource) Buffer() Buffer {
+	return s.src.Buffer()
+}
+
+// ReadBuffer reads next chunk of audio from src into b and processes it with the filter chain.
+func (s *FilteredSource) ReadBuffer(b Buffer) (int, error) {
+	n, err := s.src.ReadBuffer(b)
+	if n <= 0 || len(s.chain) == 0 {
+		return n, err
+	}
+
+	if cap(s.ints) < n {
+		s.ints = make([]int, n)
+	}
+
+	ints := s.ints[:n]
+	if _, err := b.Read(ints, s.fmt.BitDepth); err != nil {
+		return 0, err
+	}
+
+	s.samples = s.filter(ints, s.samples)
+
+	if _, err := b.Write(ints, s.fmt.BitDepth); err != nil {
+		return 0, err
+	}
+
+	return n, err
+}
+
+// Close closes src.
+func (s *FilteredSource) Close() error {
+	return s.src.Close()
+}
+
+func (s *FilteredSource) filter(ints []int, samples []float64) []float64 {
+	scale := sampleScale(s.fmt.BitDepth)
+
+	if cap(samples) < len(ints) {
+		samples = make([]float64, len(ints))
+	}
+
+	samples = samples[:len(ints)]
+
+	for i, v := range ints {
+		samples[i] = float64(v) / scale
+	}
+
+	s.chain.Process(samples)
+
+	for i, v := range samples {
+		ints[i] = int(denormalise(v, s.fmt.BitDepth))
+	}
+
+	return samples
+}
+
+// FilteredStream is an EncodedSource that applies a FilterChain to audio written by another EncodedSource.
+// Chunks written by the source are processed in whole frames,
+// any incomplete frame at the end of a chunk is kept until the next chunk.
+// Since filters only process whole frames, an incomplete frame at the end of audio is padded with silence
+// for filtering and only its own samples are written.
+type FilteredStream struct {
+	src     EncodedSource
+	fmt     Format
+	ord     binary.ByteOrder
+	chain   FilterChain
+	pending bytes.Buffer
+	samples []float64
+	out     []byte
+}
+
+// NewFilteredStream returns a new FilteredStream that processes audio written by src with c.
+// The audio must be encoded in format f with byte order ord.
+func NewFilteredStream(src EncodedSource, f Format, ord binary.ByteOrder, c FilterChain) (*FilteredStream, error) {
+	if sampleBytes(f.BitDepth) == 0 {
+		return nil, ErrInvalidBitDepth
+	}
+
+	return &FilteredStream{
+		src:   src,
+		fmt:   f,
+		ord:   ord,
+		chain: c,
+	}, nil
+}
+
+// WriteTo writes the next chunk of audio from src into w, after processing it with the filter chain.
+// It returns the number of bytes written to w and io.EOF with the last chunk.
+// If the last chunk ends with an incomplete sample, io.ErrUnexpectedEOF is returned instead.
+func (s *FilteredStream) WriteTo(w io.Writer) (int64, error) {
+	_, srcErr := s.src.WriteTo(&s.pending)
+	if srcErr != nil && srcErr != io.EOF {
+		return 0, srcErr
+	}
+
+	var (
+		size  = sampleBytes(s.fmt.BitDepth)
+		frame = size * int(s.fmt.NumChannels)
+		scale = sampleScale(s.fmt.BitDepth)
+		avail = s.pending.Len() / frame * frame
+	)
+
+	// At the end of audio, whole samples of an incomplete frame are processed as well.
+	if srcErr == io.EOF {
+		avail = s.pending.Len() / size * size
+	}
+
+	var (
+		data   = s.pending.Next(avail)
+		n      = len(data) / size
+		padded = (len(data) + frame - 1) / frame * frame / size
+	)
+
+	if cap(s.samples) < padded {
+		s.samples = make([]float64, padded)
+		s.out = make([]byte, padded*size)
+	}
+
+	samples, out := s.samples[:padded], s.out[:n*size]
+
+	for i := range samples {
+		samples[i] = 0
+
+		if i < n {
+			samples[i] = float64(decodeSample(s.ord, s.fmt.BitDepth, data[i*size:])) / scale
+		}
+	}
+
+	s.chain.Process(samples)
+
+	for i, v := range samples[:n] {
+		encodeSample(s.ord, s.fmt.BitDepth, out[i*size:], denormalise(v, s.fmt.BitDepth))
+	}
+
+	written, err := w.Write(out)
+	if err != nil {
+		return int64(written), err
+	}
+
+	if srcErr == io.EOF && s.pending.Len() > 0 {
+		return int64(written), io.ErrUnexpectedEOF
+	}
+
+	return int64(written), srcErr
+}
+
+// Close closes src.
+func (s *FilteredStream) Close() error {
+	return s.src.Close()
+}
+
+// sampleBytes returns the number of bytes in an encoded sample of bit depth d, or 0 if d is not supported.
+func sampleBytes(d BitDepth) int {
+	switch d {
+	case BitDepth8, BitDepth16, BitDepth32, BitDepth64:
+		return int(d) / 8
+	default:
+		return 0
+	}
+}
+
+// sampleScale returns the full scale value of samples of bit depth d.
+func sampleScale(d BitDepth) float64 {
+	return math.Exp2(float64(d) - 1)
+}
+
+// denormalise converts normalised sample v into an integer sample of bit depth d, clipping it if necessary.
+func denormalise(v float64, d BitDepth) int64 {
+	var (
+		hi = int64(1)<<(uint(d)-1) - 1
+		lo = -hi - 1
+		s  = math.Round(v * sampleScale(d))
+	)
+
+	switch {
+	case s >= float64(hi):
+		return hi
+	case s <= float64(lo):
+		return lo
+	default:
+		return int64(s)
+	}
+}
+
+func decodeSample(ord binary.ByteOrder, d BitDepth, b []byte) int64 {
+	switch d {
+	case BitDepth8:
+		return int64(int8(b[0]))
+	case BitDepth16:
+		return int64(int16(ord.Uint16(b)))
+	case BitDepth32:
+		return int64(int32(ord.Uint32(b)))
+	default:
+		return int64(ord.Uint64(b))
+	}
+}
+
+func encodeSample(ord binary.ByteOrder, d BitDepth, b []byte, v int64) {
+	switch d {
+	case BitDepth8:
+		b[0] = byte(int8(v))
+	case BitDepth16:
+		ord.PutUint16(b, uint16(int16(v)))
+	case BitDepth32:
+		ord.PutUint32(b, uint32(int32(v)))
+	default:
+		ord.PutUint64(b, uint64(v))
+	}
+}
@@ -0,0 +1,89 @@
+package audio
+
+// Companding is a G.711 companding law, used e.g. by telephony audio.
+// Companded samples are 8 bits wide and expand into 16-bit linear PCM.
+type Companding int8
+
+// Supported companding laws.
+const (
+	CompandingNone  = Companding(0)
+	CompandingMuLaw = Companding(1)
+	CompandingALaw  = Companding(2)
+)
+
+var (
+	muLawTable = newCompandingTable(decodeMuLaw)
+	aLawTable  = newCompandingTable(decodeALaw)
+)
+
+// String returns the name of the companding law.
+func (c Companding) String() string {
+	switch c {
+	case CompandingNone:
+		return "none"
+	case CompandingMuLaw:
+		return "mu-law"
+	case CompandingALaw:
+		return "A-law"
+	default:
+		return "unknown"
+	}
+}
+
+// Decode expands companded sample b into a 16-bit linear PCM sample.
+// If c is CompandingNone, b is treated as a signed 8-bit sample and scaled to 16 bits.
+func (c Companding) Decode(b byte) int16 {
+	switch c {
+	case CompandingMuLaw:
+		return muLawTable[b]
+	case CompandingALaw:
+		return aLawTable[b]
+	default:
+		return int16(int8(b)) << 8
+	}
+}
+
+func newCompandingTable(decode func(byte) int16) (t [256]int16) {
+	for i := range t {
+		t[i] = decode(byte(i))
+	}
+
+	return t
+}
+
+// decodeMuLaw implements mu-law expansion as specified in ITU-T G.711.
+func decodeMuLaw(b byte) int16 {
+	const bias = 0x84
+
+	u := ^b
+	t := (int16(u&0x0F) << 3) + bias
+	t <<= (u & 0x70) >> 4
+
+	if u&0x80 != 0 {
+		return bias - t
+	}
+
+	return t - bias
+}
+
+// decodeALaw implements A-law expansion as specified in ITU-T G.711.
+func decodeALaw(b byte) int16 {
+	a := b ^ 0x55
+	t := int16(a&0x0F) << 4
+
+	switch seg := (a & 0x70) >> 4; seg {
+	case 0:
+		t += 8
+	case 1:
+		t += 0x108
+	default:
+		t += 0x108
+		t <<= seg - 1
+	}
+
+	if a&0x80 != 0 {
+		return t
+	}
+
+	return -t
+}
@@ -0,0 +1,48 @@
+package audio
+
+import "testing"
+
+func TestCompandingDecode(t *testing.T) {
+	// Reference values from ITU-T G.711.
+	tests := []struct {
+		c    Companding
+		code byte
+		want int16
+	}{
+		{c: CompandingMuLaw, code: 0xFF, want: 0},
+		{c: CompandingMuLaw, code: 0x7F, want: 0},
+		{c: CompandingMuLaw, code: 0xFE, want: 8},
+		{c: CompandingMuLaw, code: 0x7E, want: -8},
+		{c: CompandingMuLaw, code: 0xF0, want: 120},
+		{c: CompandingMuLaw, code: 0xEF, want: 132},
+		{c: CompandingMuLaw, code: 0x8F, want: 16764},
+		{c: CompandingMuLaw, code: 0x80, want: 32124},
+		{c: CompandingMuLaw, code: 0x00, want: -32124},
+		{c: CompandingALaw, code: 0xD5, want: 8},
+		{c: CompandingALaw, code: 0x55, want: -8},
+		{c: CompandingALaw, code: 0xD4, want: 24},
+		{c: CompandingALaw, code: 0xC5, want: 264},
+		{c: CompandingALaw, code: 0xFA, want: 1008},
+		{c: CompandingALaw, code: 0xAA, want: 32256},
+		{c: CompandingALaw, code: 0x2A, want: -32256},
+		{c: CompandingNone, code: 0x7F, want: 32512},
+		{c: CompandingNone, code: 0x80, want: -32768},
+	}
+
+	for _, tt := range tests {
+		if got := tt.c.Decode(tt.code); got != tt.want {
+			t.Errorf("%s Decode(%#x) = %d, want %d", tt.c, tt.code, got, tt.want)
+		}
+	}
+}
+
+func TestCompandingDecodeSymmetry(t *testing.T) {
+	for _, c := range []Companding{CompandingMuLaw, CompandingALaw} {
+		// The most significant bit is the sign, so codes that differ only by it decode into opposite values.
+		for b := 0; b < 0x80; b++ {
+			if pos, neg := c.Decode(byte(b|0x80)), c.Decode(byte(b)); pos != -neg {
+				t.Errorf("%s Decode(%#x) = %d, Decode(%#x) = %d, want opposite values", c, b|0x80, pos, b, neg)
+			}
+		}
+	}
+}
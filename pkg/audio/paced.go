@@ -0,0 +1,145 @@
+package audio
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrInvalidSpeed is returned when the speed of a PacedStream is not positive.
+var ErrInvalidSpeed = errors.New("invalid pacing speed")
+
+// PaceOption is an option of a PacedStream.
+type PaceOption func(*PacedStream)
+
+// WithSpeed sets the speed multiplier of a PacedStream, e.g. 2 releases audio twice as fast as real time.
+func WithSpeed(s float64) PaceOption {
+	return func(p *PacedStream) {
+		p.speed = s
+	}
+}
+
+// WithJitter makes a PacedStream delay every chunk by a random duration of up to d,
+// to simulate the irregular delivery of audio by devices and networks.
+// The delays do not accumulate, so the audio is still released at the configured speed on average.
+func WithJitter(d time.Duration) PaceOption {
+	return func(p *PacedStream) {
+		p.jitter = d
+	}
+}
+
+// PacedStream is an EncodedSource that releases audio written by another EncodedSource at real-time speed,
+// i.e. every chunk is released once its duration has elapsed since the previous one, like audio from a microphone.
+// It can be used for simulating live audio with audio files.
+type PacedStream struct {
+	src     EncodedSource
+	fmt     Format
+	speed   float64
+	jitter  time.Duration
+	rand    *rand.Rand
+	buf     bytes.Buffer
+	start   time.Time
+	written int64
+	done    chan struct{}
+	closed  sync.Once
+}
+
+// NewPacedStream returns a new PacedStream that releases audio written by src, encoded in format f.
+func NewPacedStream(src EncodedSource, f Format, opts ...PaceOption) (*PacedStream, error) {
+	if sampleBytes(f.BitDepth) == 0 {
+		return nil, ErrInvalidBitDepth
+	}
+
+	if f.SampleRateHertz < 1 || f.NumChannels < 1 {
+		return nil, ErrInvalidSampleRate
+	}
+
+	p := &PacedStream{
+		src:   src,
+		fmt:   f,
+		speed: 1,
+		done:  make(chan struct{}),
+	}
+
+	for _, o := range opts {
+		o(p)
+	}
+
+	if p.speed <= 0 {
+		return nil, ErrInvalidSpeed
+	}
+
+	if p.jitter > 0 {
+		p.rand = rand.New(rand.NewSource(time.Now().UnixNano())) // nolint: gosec // Jitter does not need to be secure.
+	}
+
+	return p, nil
+}
+
+// WriteTo reads the next chunk of audio from src, waits until it's due and writes it into w.
+// It returns the number of bytes written to w and io.EOF with the last chunk.
+// If the stream is closed while waiting, the chunk is written immediately.
+func (p *PacedStream) WriteTo(w io.Writer) (int64, error) {
+	if p.start.IsZero() {
+		p.start = time.Now()
+	}
+
+	p.buf.Reset()
+
+	_, srcErr := p.src.WriteTo(&p.buf)
+	if srcErr != nil && srcErr != io.EOF {
+		return 0, srcErr
+	}
+
+	p.written += int64(p.buf.Len())
+	p.wait(p.due())
+
+	n, err := p.buf.WriteTo(w)
+	if err != nil {
+		return n, err
+	}
+
+	return n, srcErr
+}
+
+// Close closes src and stops waiting for the current chunk, if any.
+func (p *PacedStream) Close() error {
+	p.closed.Do(func() {
+		close(p.done)
+	})
+
+	return p.src.Close()
+}
+
+// due returns the time when all audio written so far is due.
+func (p *PacedStream) due() time.Time {
+	var (
+		frame = sampleBytes(p.fmt.BitDepth) * int(p.fmt.NumChannels)
+		secs  = float64(p.written) / float64(frame) / float64(p.fmt.SampleRateHertz) / p.speed
+		t     = p.start.Add(time.Duration(secs * float64(time.Second)))
+	)
+
+	if p.rand != nil {
+		t = t.Add(time.Duration(p.rand.Int63n(int64(p.jitter))))
+	}
+
+	return t
+}
+
+func (p *PacedStream) wait(t time.Time) {
+	d := time.Until(t)
+	if d <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+	case <-p.done:
+	}
+}
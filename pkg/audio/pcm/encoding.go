@@ -0,0 +1,109 @@
+// Package pcm implements reading of headerless PCM and G.711 audio.
+package pcm
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/speechly/slu-client/pkg/audio"
+)
+
+// ErrInvalidEncoding is returned when a PCM sample encoding is not valid or not supported.
+var ErrInvalidEncoding = errors.New("invalid PCM encoding")
+
+// Encoding describes how PCM samples are encoded.
+// For companded encodings, BitDepth and Order describe the decoded samples, which are always 16 bits wide,
+// while the encoded samples are 8 bits wide.
+type Encoding struct {
+	BitDepth   audio.BitDepth
+	Order      binary.ByteOrder
+	Unsigned   bool // Only supported for 8-bit samples.
+	Companding audio.Companding
+}
+
+// Common encodings.
+var (
+	EncodingU8    = Encoding{BitDepth: audio.BitDepth8, Order: binary.LittleEndian, Unsigned: true}
+	EncodingS8    = Encoding{BitDepth: audio.BitDepth8, Order: binary.LittleEndian}
+	EncodingS16LE = Encoding{BitDepth: audio.BitDepth16, Order: binary.LittleEndian}
+	EncodingS16BE = Encoding{BitDepth: audio.BitDepth16, Order: binary.BigEndian}
+	EncodingS32LE = Encoding{BitDepth: audio.BitDepth32, Order: binary.LittleEndian}
+	EncodingS32BE = Encoding{BitDepth: audio.BitDepth32, Order: binary.BigEndian}
+	EncodingS64LE = Encoding{BitDepth: audio.BitDepth64, Order: binary.LittleEndian}
+	EncodingS64BE = Encoding{BitDepth: audio.BitDepth64, Order: binary.BigEndian}
+	EncodingMuLaw = Encoding{BitDepth: audio.BitDepth16, Order: binary.LittleEndian, Companding: audio.CompandingMuLaw}
+	EncodingALaw  = Encoding{BitDepth: audio.BitDepth16, Order: binary.LittleEndian, Companding: audio.CompandingALaw}
+)
+
+var encodings = map[string]Encoding{
+	"u8":    EncodingU8,
+	"s8":    EncodingS8,
+	"s16le": EncodingS16LE,
+	"s16be": EncodingS16BE,
+	"s32le": EncodingS32LE,
+	"s32be": EncodingS32BE,
+	"s64le": EncodingS64LE,
+	"s64be": EncodingS64BE,
+	"mulaw": EncodingMuLaw,
+	"alaw":  EncodingALaw,
+}
+
+// ParseEncoding returns the Encoding with specified name, using the same names as ffmpeg and sox (e.g. 's16le').
+func ParseEncoding(s string) (Encoding, error) {
+	e, ok := encodings[strings.ToLower(s)]
+	if !ok {
+		return Encoding{}, fmt.Errorf("%w: %q", ErrInvalidEncoding, s)
+	}
+
+	return e, nil
+}
+
+// String returns the name of the encoding.
+func (e Encoding) String() string {
+	for n, v := range encodings {
+		if v == e {
+			return n
+		}
+	}
+
+	// Converting to a type without methods avoids infinite recursion through String.
+	type encoding Encoding
+
+	return fmt.Sprintf("%+v", encoding(e))
+}
+
+// SampleSize returns the size of an encoded sample in bytes.
+func (e Encoding) SampleSize() int {
+	if e.Companding != audio.CompandingNone {
+		return 1
+	}
+
+	return e.decodedSize()
+}
+
+// decodedSize returns the size of a decoded sample in bytes.
+func (e Encoding) decodedSize() int {
+	return int(e.BitDepth) / 8
+}
+
+func (e Encoding) validate() error {
+	switch {
+	case e.Order == nil:
+		return fmt.Errorf("%w: byte order is not set", ErrInvalidEncoding)
+	case e.Unsigned && e.BitDepth != audio.BitDepth8:
+		return fmt.Errorf("%w: unsigned %d-bit samples are not supported", ErrInvalidEncoding, e.BitDepth)
+	case e.Companding != audio.CompandingNone && (e.Unsigned || e.BitDepth != audio.BitDepth16):
+		return fmt.Errorf("%w: %s samples must be decoded as signed 16-bit", ErrInvalidEncoding, e.Companding)
+	case e.Companding > audio.CompandingALaw || e.Companding < audio.CompandingNone:
+		return fmt.Errorf("%w: unsupported companding", ErrInvalidEncoding)
+	}
+
+	switch e.BitDepth {
+	case audio.BitDepth8, audio.BitDepth16, audio.BitDepth32, audio.BitDepth64:
+		return nil
+	default:
+		return audio.ErrInvalidBitDepth
+	}
+}
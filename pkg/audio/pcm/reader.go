@@ -0,0 +1,202 @@
+package pcm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/speechly/slu-client/pkg/audio"
+)
+
+// ErrShortWrite is returned when decoded data does not fit into the buffer passed to ReadBuffer.
+var ErrShortWrite = errors.New("short write")
+
+// Source is an interface for PCM data source. It does not need to support seeking, so it can be e.g. a pipe.
+type Source interface {
+	io.Reader
+	io.Closer
+}
+
+// Reader is a reader of headerless PCM audio.
+// Reader implements audio.Source and io.WriterTo interfaces.
+// Data can be read either by calling ReadBuffer, or using WriteTo.
+// In the latter case, data will be serialised to binary using specified byte order.
+type Reader struct {
+	fmt      audio.Format
+	enc      Encoding
+	ord      binary.ByteOrder
+	closed   sync.Once
+	closeErr error
+	buf      audio.Buffer
+	src      Source
+	pcm      io.Reader
+	raw      []byte
+	rawRead  bytes.Reader
+}
+
+// NewReader returns a new Reader that reads PCM data encoded with e from src.
+// numChannels and sampleRate specify the format of the data, since headerless PCM does not contain it.
+// bufSize controls the number of samples read at once, rounded down to whole frames,
+// and ord is the byte order used by WriteTo.
+func NewReader(src Source, e Encoding, numChannels, sampleRate, bufSize int, ord binary.ByteOrder) (*Reader, error) {
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+
+	if numChannels < 1 || sampleRate < 1 {
+		return nil, ErrInvalidEncoding
+	}
+
+	fmt, err := audio.NewFormat(numChannels, sampleRate, int(e.BitDepth))
+	if err != nil {
+		return nil, err
+	}
+
+	// Chunks are read in whole frames, so that samples of a chunk always start from the first channel.
+	if bufSize = bufSize / numChannels * numChannels; bufSize < numChannels {
+		bufSize = numChannels
+	}
+
+	buf, err := audio.NewBuffer(fmt.BitDepth, bufSize)
+	if err != nil {
+		return nil, err
+	}
+
+	var pcm io.Reader = src
+
+	switch {
+	case e.Unsigned:
+		pcm = unsignedReader{src}
+	case e.Companding != audio.CompandingNone:
+		pcm = &compandedReader{r: src, c: e.Companding, ord: e.Order}
+	}
+
+	return &Reader{
+		fmt: fmt,
+		enc: e,
+		ord: ord,
+		buf: buf,
+		src: src,
+		pcm: pcm,
+		raw: make([]byte, bufSize*e.decodedSize()),
+	}, nil
+}
+
+// Format returns the audio format of the data.
+func (r *Reader) Format() audio.Format {
+	return r.fmt
+}
+
+// Buffer returns a copy of buffer used for reading the data.
+// Returned buffer can be used for calling ReadBuffer,
+// since it's guaranteed to have the same parameters (e.g. bit depth).
+func (r *Reader) Buffer() audio.Buffer {
+	return r.buf.Clone()
+}
+
+// Close closes the reader by closing the underlying src.
+// Close can be called multiple times, but only the first time it will actually close src.
+// All following calls will simply return the error returned by src.Close, if any.
+func (r *Reader) Close() error {
+	r.closed.Do(func() {
+		r.closeErr = r.src.Close()
+	})
+
+	return r.closeErr
+}
+
+// ReadBuffer reads and decodes next chunk of data from src, into b.
+func (r *Reader) ReadBuffer(b audio.Buffer) (int, error) {
+	n, err := r.readNext()
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+
+	r.rawRead.Reset(r.raw[:n])
+
+	bn, derr := b.Decode(r.enc.Order, &r.rawRead)
+	if derr != nil && derr != io.EOF {
+		return 0, derr
+	} else if bn != n/r.enc.decodedSize() {
+		return 0, ErrShortWrite
+	}
+
+	return bn, err
+}
+
+// WriteTo implements io.WriterTo interface.
+// It reads next chunk of data from src, encodes it using Reader byte order and writes it into w.
+// It returns the number of bytes written to w and io.EOF when src has been exhausted.
+func (r *Reader) WriteTo(w io.Writer) (int64, error) {
+	n, err := r.readNext()
+	if err != nil && err != io.EOF {
+		return 0, err
+	}
+
+	size := r.enc.decodedSize()
+	audio.ReorderSamples(r.raw[:n], size, r.enc.Order, r.ord)
+
+	written, werr := w.Write(r.raw[:n])
+	if werr != nil {
+		return int64(written), werr
+	}
+
+	return int64(written), err
+}
+
+// readNext reads next chunk of decoded PCM data into raw buffer and returns the number of bytes read.
+// It returns io.EOF if the chunk is the last one. An incomplete frame at the end of data is discarded.
+func (r *Reader) readNext() (int, error) {
+	n, err := io.ReadFull(r.pcm, r.raw)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+
+	frame := r.enc.decodedSize() * int(r.fmt.NumChannels)
+
+	return n - n%frame, err
+}
+
+// unsignedReader converts unsigned 8-bit PCM samples into signed ones.
+type unsignedReader struct {
+	r io.Reader
+}
+
+func (u unsignedReader) Read(p []byte) (int, error) {
+	n, err := u.r.Read(p)
+
+	for i := range p[:n] {
+		p[i] ^= 0x80
+	}
+
+	return n, err
+}
+
+// compandedReader expands G.711 companded samples into 16-bit linear PCM samples encoded with ord.
+type compandedReader struct {
+	r       io.Reader
+	c       audio.Companding
+	ord     binary.ByteOrder
+	encoded []byte
+}
+
+func (c *compandedReader) Read(p []byte) (int, error) {
+	// Each encoded byte expands into two bytes, so there must be room for at least one sample.
+	if len(p) < 2 {
+		return 0, io.ErrShortBuffer
+	}
+
+	if n := len(p) / 2; cap(c.encoded) < n {
+		c.encoded = make([]byte, n)
+	}
+
+	n, err := c.r.Read(c.encoded[:len(p)/2])
+
+	for i, v := range c.encoded[:n] {
+		c.ord.PutUint16(p[i*2:], uint16(c.c.Decode(v)))
+	}
+
+	return n * 2, err
+}
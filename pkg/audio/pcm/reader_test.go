@@ -0,0 +1,295 @@
+package pcm
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/speechly/slu-client/pkg/audio"
+)
+
+// nopCloser is a Source that reads from memory.
+type nopCloser struct {
+	io.Reader
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
+
+var testEncodings = []Encoding{
+	EncodingU8, EncodingS8,
+	EncodingS16LE, EncodingS16BE,
+	EncodingS32LE, EncodingS32BE,
+	EncodingS64LE, EncodingS64BE,
+	EncodingMuLaw, EncodingALaw,
+}
+
+// testSamples returns n distinct samples that fit into 8 bits.
+func testSamples(n int) []int {
+	s := make([]int, n)
+	for i := range s {
+		s[i] = (i*37)%256 - 128
+	}
+
+	return s
+}
+
+// encodeSamples encodes samples with e and returns the encoded data,
+// as well as the samples that the Reader is expected to decode from it.
+func encodeSamples(e Encoding, samples []int) (data []byte, want []int) {
+	var buf bytes.Buffer
+
+	for _, v := range samples {
+		switch {
+		case e.Companding != audio.CompandingNone:
+			buf.WriteByte(byte(v))
+			v = int(e.Companding.Decode(byte(v)))
+		case e.Unsigned:
+			buf.WriteByte(byte(v) ^ 0x80)
+		case e.BitDepth == audio.BitDepth8:
+			buf.WriteByte(byte(v))
+		case e.BitDepth == audio.BitDepth16:
+			_ = binary.Write(&buf, e.Order, int16(v))
+		case e.BitDepth == audio.BitDepth32:
+			_ = binary.Write(&buf, e.Order, int32(v))
+		default:
+			_ = binary.Write(&buf, e.Order, int64(v))
+		}
+
+		want = append(want, v)
+	}
+
+	return buf.Bytes(), want
+}
+
+// decodeLE decodes little-endian signed samples of bit depth d from data.
+func decodeLE(t *testing.T, d audio.BitDepth, data []byte) []int {
+	t.Helper()
+
+	var (
+		r   = bytes.NewReader(data)
+		out []int
+	)
+
+	for r.Len() > 0 {
+		var v int64
+
+		switch d {
+		case audio.BitDepth8:
+			var s int8
+			_ = binary.Read(r, binary.LittleEndian, &s)
+			v = int64(s)
+		case audio.BitDepth16:
+			var s int16
+			_ = binary.Read(r, binary.LittleEndian, &s)
+			v = int64(s)
+		case audio.BitDepth32:
+			var s int32
+			_ = binary.Read(r, binary.LittleEndian, &s)
+			v = int64(s)
+		default:
+			_ = binary.Read(r, binary.LittleEndian, &v)
+		}
+
+		out = append(out, int(v))
+	}
+
+	return out
+}
+
+func equalSamples(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// partialChunkTests are cases where the number of samples is not a multiple of the buffer size,
+// with and without an incomplete sample at the end of data.
+var partialChunkTests = []struct {
+	name       string
+	numSamples int
+	bufSize    int
+	trailing   int
+	chunks     []int // Number of samples in each chunk.
+}{
+	{name: "multiple of buffer size", numSamples: 6, bufSize: 3, chunks: []int{3, 3, 0}},
+	{name: "partial final chunk", numSamples: 7, bufSize: 3, chunks: []int{3, 3, 1}},
+	{
+		name:       "partial final chunk with incomplete sample",
+		numSamples: 7,
+		bufSize:    3,
+		trailing:   1,
+		chunks:     []int{3, 3, 1},
+	},
+	{name: "shorter than buffer", numSamples: 2, bufSize: 5, chunks: []int{2}},
+	{name: "empty", numSamples: 0, bufSize: 4, chunks: []int{0}},
+}
+
+func TestReaderWriteToPartialChunk(t *testing.T) {
+	for _, e := range testEncodings {
+		for _, tt := range partialChunkTests {
+			e, tt := e, tt
+
+			if tt.trailing >= e.SampleSize() {
+				continue
+			}
+
+			t.Run(e.String()+"/"+tt.name, func(t *testing.T) {
+				data, want := encodeSamples(e, testSamples(tt.numSamples))
+				data = append(data, make([]byte, tt.trailing)...)
+
+				r, err := NewReader(nopCloser{bytes.NewReader(data)}, e, 1, 16000, tt.bufSize, binary.LittleEndian)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var (
+					out    bytes.Buffer
+					chunks []int
+				)
+
+				for {
+					n, err := r.WriteTo(&out)
+					chunks = append(chunks, int(n))
+
+					if err == io.EOF {
+						break
+					}
+
+					if err != nil {
+						t.Fatalf("WriteTo() error = %v", err)
+					}
+
+					if len(chunks) > len(tt.chunks) {
+						t.Fatalf("WriteTo() did not return io.EOF after %d chunks", len(chunks))
+					}
+				}
+
+				// WriteTo returns the number of bytes of decoded samples.
+				wantChunks := make([]int, len(tt.chunks))
+				for i, n := range tt.chunks {
+					wantChunks[i] = n * e.decodedSize()
+				}
+
+				if !equalSamples(chunks, wantChunks) {
+					t.Errorf("WriteTo() chunks = %v, want %v", chunks, wantChunks)
+				}
+
+				if got := decodeLE(t, e.BitDepth, out.Bytes()); !equalSamples(got, want) {
+					t.Errorf("WriteTo() samples = %v, want %v", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestReaderReadBufferPartialChunk(t *testing.T) {
+	for _, e := range testEncodings {
+		for _, tt := range partialChunkTests {
+			e, tt := e, tt
+
+			if tt.trailing >= e.SampleSize() {
+				continue
+			}
+
+			t.Run(e.String()+"/"+tt.name, func(t *testing.T) {
+				data, want := encodeSamples(e, testSamples(tt.numSamples))
+				data = append(data, make([]byte, tt.trailing)...)
+
+				r, err := NewReader(nopCloser{bytes.NewReader(data)}, e, 1, 16000, tt.bufSize, binary.LittleEndian)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var (
+					b      = r.Buffer()
+					ints   = make([]int, tt.bufSize)
+					got    []int
+					chunks []int
+				)
+
+				for {
+					n, err := r.ReadBuffer(b)
+					if err != nil && err != io.EOF {
+						t.Fatalf("ReadBuffer() error = %v", err)
+					}
+
+					if b.Len() != n {
+						t.Errorf("Len() = %d after reading %d samples", b.Len(), n)
+					}
+
+					// Samples past Len must not be returned, even if the previous chunk filled the buffer.
+					rn, rerr := b.Read(ints, e.BitDepth)
+					if rerr != nil && rerr != io.EOF {
+						t.Fatalf("Read() error = %v", rerr)
+					}
+
+					got = append(got, ints[:rn]...)
+					chunks = append(chunks, n)
+
+					if err == io.EOF {
+						break
+					}
+
+					if len(chunks) > len(tt.chunks) {
+						t.Fatalf("ReadBuffer() did not return io.EOF after %d chunks", len(chunks))
+					}
+				}
+
+				if !equalSamples(chunks, tt.chunks) {
+					t.Errorf("ReadBuffer() chunks = %v, want %v", chunks, tt.chunks)
+				}
+
+				if !equalSamples(got, want) {
+					t.Errorf("ReadBuffer() samples = %v, want %v", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestReaderWholeFrames(t *testing.T) {
+	// The buffer size is rounded down to 2 stereo frames, and the incomplete last frame is discarded.
+	data, want := encodeSamples(EncodingS16LE, testSamples(7))
+
+	r, err := NewReader(nopCloser{bytes.NewReader(data)}, EncodingS16LE, 2, 16000, 5, binary.LittleEndian)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		out    bytes.Buffer
+		chunks []int
+	)
+
+	for {
+		n, err := r.WriteTo(&out)
+		chunks = append(chunks, int(n))
+
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatalf("WriteTo() error = %v", err)
+		}
+	}
+
+	if want := []int{8, 4}; !equalSamples(chunks, want) {
+		t.Errorf("WriteTo() chunks = %v, want %v", chunks, want)
+	}
+
+	if got := decodeLE(t, audio.BitDepth16, out.Bytes()); !equalSamples(got, want[:6]) {
+		t.Errorf("WriteTo() samples = %v, want %v", got, want[:6])
+	}
+}
@@ -11,21 +11,29 @@ import (
 )
 
 // Player is an audio player that reads data from specified audio source,
-// and plays it using OS audio stack through default output device.
+// and plays it using OS audio stack through an output device.
 // It is based on portaudio, so it will use whatever audio stack implementation
 // that portaudio implements for current OS.
+// Underruns of the output buffer of the audio device are logged and counted, but they are not treated as errors.
 // It is not safe for concurrent use.
 type Player struct {
-	stream  *portaudio.Stream
-	src     Source
-	buf     Buffer
-	log     logger.Logger
-	done    chan struct{}
-	doneAck chan struct{}
+	stream    *portaudio.Stream
+	src       Source
+	buf       Buffer
+	log       logger.Logger
+	done      chan struct{}
+	doneAck   chan struct{}
+	underruns uint64
 }
 
 // NewPlayer returns a new Player that will use src as source of audio data.
 func NewPlayer(src Source, log logger.Logger) (*Player, error) {
+	return NewDevicePlayer(nil, src, log)
+}
+
+// NewDevicePlayer returns a new Player that will play audio from src through dev.
+// If dev is nil, default OS audio output device is used.
+func NewDevicePlayer(dev *Device, src Source, log logger.Logger) (*Player, error) {
 	err := portaudio.Initialize()
 	if err != nil {
 		return nil, err
@@ -36,9 +44,7 @@ func NewPlayer(src Source, log logger.Logger) (*Player, error) {
 		buf    = src.Buffer()
 	)
 
-	stream, err := portaudio.OpenDefaultStream(
-		0, int(format.NumChannels), float64(format.SampleRateHertz), buf.Size(), buf.Data(),
-	)
+	stream, err := openStream(dev, false, format, buf)
 	if err != nil {
 		if err := portaudio.Terminate(); err != nil {
 			log.Warn("Error terminating portaudio:", err)
@@ -88,7 +94,12 @@ func (p *Player) Play(ctx context.Context) error {
 			}
 
 			if err := p.stream.Write(); err != nil {
-				return err
+				if err != portaudio.OutputUnderflowed {
+					return err
+				}
+
+				p.underruns++
+				p.log.Warnf("Audio output underflowed, playback was interrupted (%d underruns so far)", p.underruns)
 			}
 		}
 	}
@@ -96,6 +107,11 @@ func (p *Player) Play(ctx context.Context) error {
 	return nil
 }
 
+// Underruns returns the number of times the output buffer of the audio device ran out of audio during playback.
+func (p *Player) Underruns() uint64 {
+	return p.underruns
+}
+
 // Close closes the player by closing the source, the audio stream and terminating the audio stack.
 // Close MUST be called before program exits,
 // otherwise the audio devices of the OS may be unusable until the audio system is restarted.
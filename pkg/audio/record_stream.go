@@ -1,9 +1,12 @@
 package audio
 
 import (
+	"bytes"
 	"encoding/binary"
+	"errors"
 	"io"
 	"sync"
+	"time"
 
 	"github.com/gordonklaus/portaudio"
 	"github.com/hashicorp/go-multierror"
@@ -11,19 +14,90 @@ import (
 	"github.com/speechly/slu-client/pkg/logger"
 )
 
+// ErrStreamClosed is returned when reading from a closed RecordStream.
+var ErrStreamClosed = errors.New("record stream is closed")
+
+// CaptureStats are the statistics of audio captured by a RecordStream.
+type CaptureStats struct {
+	// Overflows is the number of times the input buffer of the audio device overflowed,
+	// because audio was not read from it fast enough. Some audio is lost with every overflow.
+	Overflows uint64
+
+	// Captured is the duration of audio read from the audio device.
+	Captured time.Duration
+
+	// Sent is the duration of captured audio that was sent onwards. It is not counted by RecordStream,
+	// since audio written by WriteTo may still be buffered by later stages, e.g. a ChunkedStream.
+	// It is up to the consumer of audio to set it, so that Gap reflects the audio that was actually sent.
+	Sent time.Duration
+
+	// Dropped is the duration of audio dropped because the capture buffer was full.
+	Dropped time.Duration
+}
+
+// Gap returns the difference between the duration of captured and sent audio,
+// which is the sum of dropped audio and audio waiting in the capture buffer.
+func (s CaptureStats) Gap() time.Duration {
+	return s.Captured - s.Sent
+}
+
+// RecordOption is an option of a RecordStream.
+type RecordOption func(*RecordStream)
+
+// WithCaptureBuffer makes a RecordStream capture audio in the background and buffer up to d of it,
+// until it is read with WriteTo. This avoids losing audio when WriteTo is not called for a while,
+// e.g. because of a network stall. Once the buffer is full, the oldest audio in it is dropped.
+func WithCaptureBuffer(d time.Duration) RecordOption {
+	return func(r *RecordStream) {
+		r.capBuf = d
+	}
+}
+
+// inputStream is an audio input stream, which reads audio into the buffer it was opened with.
+// It is implemented by portaudio.Stream.
+type inputStream interface {
+	Read() error
+	Stop() error
+	Close() error
+}
+
 // RecordStream is an audio stream that implements io.WriterTo interface,
 // by using an audio buffer and encoding it into binary data using specified audio format and byte order.
+// Overflows of the input buffer of the audio device are logged and counted, but they are not treated as errors.
 type RecordStream struct {
-	stream   *portaudio.Stream
+	stream   inputStream
 	buf      Buffer
 	ord      binary.ByteOrder
 	log      logger.Logger
+	chunkDur time.Duration
+	capBuf   time.Duration
 	closed   sync.Once
 	closeErr error
+
+	statsLock sync.Mutex
+	stats     CaptureStats
+
+	// Only used with a capture buffer.
+	queue    chan []byte
+	free     chan []byte
+	captured chan struct{}
+	done     chan struct{}
+	capErr   error
 }
 
 // NewRecordStream returns a new record stream with specified format, byte order and size of underlying buffer.
-func NewRecordStream(fmt Format, ord binary.ByteOrder, bufSize int, log logger.Logger) (*RecordStream, error) {
+// The stream records audio from default OS audio input device.
+func NewRecordStream(
+	fmt Format, ord binary.ByteOrder, bufSize int, log logger.Logger, opts ...RecordOption,
+) (*RecordStream, error) {
+	return NewDeviceRecordStream(nil, fmt, ord, bufSize, log, opts...)
+}
+
+// NewDeviceRecordStream returns a new record stream that records audio from dev,
+// or from default OS audio input device if dev is nil.
+func NewDeviceRecordStream(
+	dev *Device, fmt Format, ord binary.ByteOrder, bufSize int, log logger.Logger, opts ...RecordOption,
+) (*RecordStream, error) {
 	buf, err := NewBuffer(fmt.BitDepth, bufSize)
 	if err != nil {
 		return nil, err
@@ -33,12 +107,10 @@ func NewRecordStream(fmt Format, ord binary.ByteOrder, bufSize int, log logger.L
 		return nil, err
 	}
 
-	stream, err := portaudio.OpenDefaultStream(
-		int(fmt.NumChannels), 0, float64(fmt.SampleRateHertz), buf.Size(), buf.Data(),
-	)
+	stream, err := openStream(dev, true, fmt, buf)
 	if err != nil {
 		if err := portaudio.Terminate(); err != nil {
-			log.Warn("Error terminating portaudio", log)
+			log.Warn("Error terminating portaudio", err)
 		}
 
 		return nil, err
@@ -46,32 +118,69 @@ func NewRecordStream(fmt Format, ord binary.ByteOrder, bufSize int, log logger.L
 
 	if err := stream.Start(); err != nil {
 		if err := stream.Close(); err != nil {
-			log.Warn("Error closing audio stream", log)
+			log.Warn("Error closing audio stream", err)
 		}
 
 		if err := portaudio.Terminate(); err != nil {
-			log.Warn("Error terminating portaudio", log)
+			log.Warn("Error terminating portaudio", err)
 		}
 
 		return nil, err
 	}
 
-	return &RecordStream{
+	return newRecordStream(stream, buf, fmt, ord, log, opts...), nil
+}
+
+// newRecordStream returns a new RecordStream that reads audio in format fmt from a started stream into buf,
+// starting to capture it in the background if it's configured with a capture buffer.
+func newRecordStream(
+	stream inputStream, buf Buffer, fmt Format, ord binary.ByteOrder, log logger.Logger, opts ...RecordOption,
+) *RecordStream {
+	r := &RecordStream{
 		stream: stream,
 		buf:    buf,
 		ord:    ord,
 		log:    log,
-	}, nil
+	}
+
+	for _, o := range opts {
+		o(r)
+	}
+
+	if fmt.NumChannels > 0 && fmt.SampleRateHertz > 0 {
+		frames := time.Duration(buf.Size() / int(fmt.NumChannels))
+		r.chunkDur = frames * time.Second / time.Duration(fmt.SampleRateHertz)
+	}
+
+	if r.capBuf > 0 && r.chunkDur > 0 {
+		r.startCapture(buf.Size() * sampleBytes(fmt.BitDepth))
+	}
+
+	return r
+}
+
+// Stats returns current capture statistics of the stream.
+func (r *RecordStream) Stats() CaptureStats {
+	r.statsLock.Lock()
+	defer r.statsLock.Unlock()
+
+	return r.stats
 }
 
 // WriteTo implements io.WriterTo by reading from stream and then encoding and writing audio to w.
+// With a capture buffer, it writes the oldest chunk of buffered audio, waiting for one if the buffer is empty.
 func (r *RecordStream) WriteTo(w io.Writer) (int64, error) {
-	if err := r.stream.Read(); err != nil {
+	if r.queue != nil {
+		return r.writeBuffered(w)
+	}
+
+	if err := r.read(); err != nil {
 		return 0, err
 	}
 
 	n, err := r.buf.Encode(r.ord, w)
-	return int64(n), err
+
+	return int64(n * sampleBytes(r.buf.BitDepth())), err
 }
 
 // Close closes RecordStream by closing the audio stream and terminating the audio stack.
@@ -79,6 +188,11 @@ func (r *RecordStream) WriteTo(w io.Writer) (int64, error) {
 // otherwise the audio devices of the OS may be unusable until the audio system is restarted.
 func (r *RecordStream) Close() error {
 	r.closed.Do(func() {
+		if r.done != nil {
+			close(r.done)
+			<-r.captured
+		}
+
 		errs := &multierror.Error{}
 
 		if err := r.stream.Stop(); err != nil {
@@ -98,3 +212,128 @@ func (r *RecordStream) Close() error {
 
 	return r.closeErr
 }
+
+// read reads the next chunk of audio from the audio device into the buffer.
+func (r *RecordStream) read() error {
+	err := r.stream.Read()
+	if err != nil && err != portaudio.InputOverflowed {
+		return err
+	}
+
+	r.buf.SetLen(r.buf.Size())
+
+	var overflows uint64
+
+	r.updateStats(func(s *CaptureStats) {
+		s.Captured += r.chunkDur
+
+		if err != nil {
+			s.Overflows++
+			overflows = s.Overflows
+		}
+	})
+
+	if overflows > 0 {
+		r.log.Warnf("Audio input overflowed, some audio was lost (%d overflows so far)", overflows)
+	}
+
+	return nil
+}
+
+// startCapture starts capturing audio in the background into a queue of chunks of chunkSize bytes.
+func (r *RecordStream) startCapture(chunkSize int) {
+	n := int((r.capBuf + r.chunkDur - 1) / r.chunkDur)
+
+	r.queue = make(chan []byte, n)
+	r.captured = make(chan struct{})
+	r.done = make(chan struct{})
+
+	// Besides queued chunks, one chunk can be being captured and one being written.
+	r.free = make(chan []byte, n+2)
+	for i := 0; i < n+2; i++ {
+		r.free <- make([]byte, 0, chunkSize)
+	}
+
+	go r.capture()
+}
+
+// capture reads audio from the audio device into the queue, until the stream is closed or reading fails.
+// If the queue is full, the oldest chunk in it is dropped.
+func (r *RecordStream) capture() {
+	defer close(r.captured)
+	defer close(r.queue)
+
+	for {
+		select {
+		case <-r.done:
+			return
+		default:
+		}
+
+		if err := r.read(); err != nil {
+			r.capErr = err
+			return
+		}
+
+		w := bytes.NewBuffer((<-r.free)[:0])
+		if _, err := r.buf.Encode(r.ord, w); err != nil {
+			r.capErr = err
+			return
+		}
+
+		r.enqueue(w.Bytes())
+	}
+}
+
+// enqueue adds chunk b to the queue, dropping the oldest chunk in the queue if it's full.
+func (r *RecordStream) enqueue(b []byte) {
+	for {
+		select {
+		case r.queue <- b:
+			return
+		default:
+		}
+
+		select {
+		case old := <-r.queue:
+			r.free <- old
+		default:
+			continue // The queue has been read in the meantime.
+		}
+
+		var dropped time.Duration
+
+		r.updateStats(func(s *CaptureStats) {
+			s.Dropped += r.chunkDur
+			dropped = s.Dropped
+		})
+
+		r.log.Warnf("Audio capture buffer is full, dropped oldest audio (%s dropped so far)", dropped)
+	}
+}
+
+func (r *RecordStream) writeBuffered(w io.Writer) (int64, error) {
+	b, ok := <-r.queue
+	if !ok {
+		if r.capErr != nil {
+			return 0, r.capErr
+		}
+
+		return 0, ErrStreamClosed
+	}
+
+	defer func() {
+		r.free <- b
+	}()
+
+	n, err := w.Write(b)
+
+	return int64(n), err
+}
+
+func (r *RecordStream) updateStats(f func(*CaptureStats)) {
+	r.statsLock.Lock()
+	defer r.statsLock.Unlock()
+
+	f(&r.stats)
+}
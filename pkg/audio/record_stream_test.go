@@ -0,0 +1,169 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/gordonklaus/portaudio"
+
+	"github.com/speechly/slu-client/pkg/logger"
+)
+
+// errInputStopped is returned by fakeInput once it runs out of reads.
+var errInputStopped = errors.New("input stopped")
+
+// recordFormat is the format of test record streams, in which a single stereo frame takes 1ms.
+var recordFormat = Format{NumChannels: 2, SampleRateHertz: 1000, BitDepth: BitDepth16}
+
+// fakeInput is an inputStream that fills the buffer with the number of the read in every read
+// and returns the errors of reads one at a time.
+type fakeInput struct {
+	buf   Buffer
+	reads []error
+	n     int
+}
+
+func (f *fakeInput) Read() error {
+	if len(f.reads) == 0 {
+		return errInputStopped
+	}
+
+	err := f.reads[0]
+	f.reads = f.reads[1:]
+	f.n++
+
+	if _, err := f.buf.Write([]int{f.n, f.n}, recordFormat.BitDepth); err != nil {
+		return err
+	}
+
+	return err
+}
+
+func (f *fakeInput) Stop() error {
+	return nil
+}
+
+func (f *fakeInput) Close() error {
+	return nil
+}
+
+func newTestRecordStream(t *testing.T, reads []error, opts ...RecordOption) *RecordStream {
+	t.Helper()
+
+	buf, err := NewBuffer(recordFormat.BitDepth, int(recordFormat.NumChannels))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	in := &fakeInput{buf: buf, reads: reads}
+
+	return newRecordStream(in, buf, recordFormat, binary.LittleEndian, logger.NewStdLogger(io.Discard), opts...)
+}
+
+// frames returns encoded stereo frames with both samples set to each of values.
+func frames(values ...int64) []byte {
+	var samples []int64
+	for _, v := range values {
+		samples = append(samples, v, v)
+	}
+
+	return encodeSamples(recordFormat.BitDepth, samples...)
+}
+
+func TestRecordStream(t *testing.T) {
+	r := newTestRecordStream(t, []error{nil, portaudio.InputOverflowed, nil})
+
+	var buf bytes.Buffer
+
+	for {
+		n, err := r.WriteTo(&buf)
+		if err != nil {
+			if !errors.Is(err, errInputStopped) {
+				t.Fatalf("WriteTo() error = %v, want %v", err, errInputStopped)
+			}
+
+			break
+		}
+
+		if n != 4 {
+			t.Errorf("WriteTo() = %d, want 4", n)
+		}
+	}
+
+	if want := frames(1, 2, 3); !bytes.Equal(buf.Bytes(), want) {
+		t.Errorf("written audio = %v, want %v", buf.Bytes(), want)
+	}
+
+	want := CaptureStats{Overflows: 1, Captured: 3 * time.Millisecond}
+	if got := r.Stats(); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRecordStreamCaptureBuffer(t *testing.T) {
+	tests := []struct {
+		name   string
+		capBuf time.Duration
+		reads  []error
+		want   []byte
+		stats  CaptureStats
+	}{
+		{
+			name:   "buffer not full",
+			capBuf: 3 * time.Millisecond,
+			reads:  []error{nil, nil},
+			want:   frames(1, 2),
+			stats:  CaptureStats{Captured: 2 * time.Millisecond},
+		},
+		{
+			name:   "oldest chunks dropped",
+			capBuf: 3 * time.Millisecond,
+			reads:  []error{nil, nil, portaudio.InputOverflowed, nil, nil},
+			want:   frames(3, 4, 5),
+			stats:  CaptureStats{Overflows: 1, Captured: 5 * time.Millisecond, Dropped: 2 * time.Millisecond},
+		},
+		{
+			name:   "buffer rounded up to whole chunks",
+			capBuf: 1500 * time.Microsecond,
+			reads:  []error{portaudio.InputOverflowed, nil, portaudio.InputOverflowed, nil},
+			want:   frames(3, 4),
+			stats:  CaptureStats{Overflows: 2, Captured: 4 * time.Millisecond, Dropped: 2 * time.Millisecond},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRecordStream(t, tt.reads, WithCaptureBuffer(tt.capBuf))
+
+			// Wait for the capture to stop at the end of reads, so that the queue is not read concurrently.
+			<-r.captured
+
+			if got := r.Stats(); got != tt.stats {
+				t.Errorf("Stats() = %+v, want %+v", got, tt.stats)
+			}
+
+			var buf bytes.Buffer
+
+			for {
+				_, err := r.WriteTo(&buf)
+				if err != nil {
+					if !errors.Is(err, errInputStopped) {
+						t.Fatalf("WriteTo() error = %v, want %v", err, errInputStopped)
+					}
+
+					break
+				}
+			}
+
+			if !bytes.Equal(buf.Bytes(), tt.want) {
+				t.Errorf("written audio = %v, want %v", buf.Bytes(), tt.want)
+			}
+		})
+	}
+}
@@ -9,21 +9,30 @@ import (
 	"github.com/speechly/slu-client/pkg/logger"
 )
 
-// Recorder is an audio recorder that reads data from default OS audio input and writes data to specified  destination.
+// Recorder is an audio recorder that reads data from an OS audio input device
+// and writes data to specified destination.
 // It is based on portaudio, so it will use whatever audio stack implementation
 // that portaudio implements for current OS.
+// Overflows of the input buffer of the audio device are logged and counted, but they are not treated as errors.
 // It is not safe for concurrent use.
 type Recorder struct {
-	stream  *portaudio.Stream
-	dst     Sink
-	buf     Buffer
-	log     logger.Logger
-	done    chan struct{}
-	doneAck chan struct{}
+	stream    *portaudio.Stream
+	dst       Sink
+	buf       Buffer
+	log       logger.Logger
+	done      chan struct{}
+	doneAck   chan struct{}
+	overflows uint64
 }
 
-// NewRecorder returns a new Recorder that will write audio to dst.
+// NewRecorder returns a new Recorder that will write audio from default OS audio input device to dst.
 func NewRecorder(dst Sink, log logger.Logger) (*Recorder, error) {
+	return NewDeviceRecorder(nil, dst, log)
+}
+
+// NewDeviceRecorder returns a new Recorder that will write audio from dev to dst.
+// If dev is nil, default OS audio input device is used.
+func NewDeviceRecorder(dev *Device, dst Sink, log logger.Logger) (*Recorder, error) {
 	err := portaudio.Initialize()
 	if err != nil {
 		return nil, err
@@ -34,9 +43,7 @@ func NewRecorder(dst Sink, log logger.Logger) (*Recorder, error) {
 		buf = dst.Buffer()
 	)
 
-	stream, err := portaudio.OpenDefaultStream(
-		int(fmt.NumChannels), 0, float64(fmt.SampleRateHertz), buf.Size(), buf.Data(),
-	)
+	stream, err := openStream(dev, true, fmt, buf)
 	if err != nil {
 		if err := portaudio.Terminate(); err != nil {
 			log.Warn("Error terminating portaudio", err)
@@ -49,6 +56,7 @@ func NewRecorder(dst Sink, log logger.Logger) (*Recorder, error) {
 		stream:  stream,
 		dst:     dst,
 		buf:     buf,
+		log:     log,
 		done:    make(chan struct{}),
 		doneAck: make(chan struct{}),
 	}, nil
@@ -76,9 +84,16 @@ func (r *Recorder) Record(ctx context.Context) error {
 			return nil
 		default:
 			if err := r.stream.Read(); err != nil {
-				return err
+				if err != portaudio.InputOverflowed {
+					return err
+				}
+
+				r.overflows++
+				r.log.Warnf("Audio input overflowed, some audio was lost (%d overflows so far)", r.overflows)
 			}
 
+			r.buf.SetLen(r.buf.Size())
+
 			if _, err := r.dst.WriteBuffer(r.buf); err != nil {
 				return err
 			}
@@ -86,6 +101,11 @@ func (r *Recorder) Record(ctx context.Context) error {
 	}
 }
 
+// Overflows returns the number of times the input buffer of the audio device overflowed during recording.
+func (r *Recorder) Overflows() uint64 {
+	return r.overflows
+}
+
 // Close closes the recorder by closing the destination, the audio stream and terminating the audio stack.
 // Close MUST be called before program exits,
 // otherwise the audio devices of the OS may be unusable until the audio system is restarted.
@@ -0,0 +1,280 @@
+package audio
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+)
+
+// ErrInvalidSampleRate is returned when a sample rate is not valid for resampling.
+var ErrInvalidSampleRate = errors.New("invalid sample rate")
+
+const (
+	// resampleZeroCrossings is the number of zero crossings of the interpolation kernel on each side,
+	// which controls the trade-off between resampling quality and speed.
+	resampleZeroCrossings = 16
+
+	// resampleMaxTableSize is the maximum number of precomputed kernel values,
+	// kernels for rates with too many phases are computed on the fly.
+	resampleMaxTableSize = 1 << 16
+)
+
+// ResampledStream is an EncodedSource that converts the sample rate of audio written by another EncodedSource.
+// It uses band-limited interpolation with a windowed sinc kernel, so it's suitable for both upsampling
+// (e.g. 8 kHz telephony audio to 16 kHz) and downsampling.
+type ResampledStream struct {
+	src     EncodedSource
+	in      Format
+	out     Format
+	ord     binary.ByteOrder
+	kernel  resampleKernel
+	pending bytes.Buffer
+	history [][]float64 // Input samples of each channel, starting from frame base.
+	base    int64
+	next    int64 // Index of the next output frame.
+	flushed bool
+	buf     []byte
+}
+
+// NewResampledStream returns a new ResampledStream that converts audio written by src to sampleRate.
+// The audio must be encoded in format f with byte order ord, and the output uses the same encoding.
+func NewResampledStream(src EncodedSource, f Format, ord binary.ByteOrder, sampleRate int) (*ResampledStream, error) {
+	if sampleBytes(f.BitDepth) == 0 {
+		return nil, ErrInvalidBitDepth
+	}
+
+	if f.SampleRateHertz < 1 || sampleRate < 1 || f.NumChannels < 1 {
+		return nil, ErrInvalidSampleRate
+	}
+
+	out := f
+	out.SampleRateHertz = int32(sampleRate)
+
+	s := &ResampledStream{
+		src:     src,
+		in:      f,
+		out:     out,
+		ord:     ord,
+		kernel:  newResampleKernel(int64(f.SampleRateHertz), int64(sampleRate)),
+		history: make([][]float64, f.NumChannels),
+	}
+
+	// Output starts at the first input frame, so the history is primed with silence preceding it.
+	s.base = -int64(s.kernel.width)
+	for i := range s.history {
+		s.history[i] = make([]float64, s.kernel.width)
+	}
+
+	return s, nil
+}
+
+// Format returns the audio format of resampled audio.
+func (s *ResampledStream) Format() Format {
+	return s.out
+}
+
+// WriteTo writes the next chunk of resampled audio from src into w.
+// It returns the number of bytes written to w and io.EOF with the last chunk.
+func (s *ResampledStream) WriteTo(w io.Writer) (int64, error) {
+	if s.flushed {
+		return 0, io.EOF
+	}
+
+	_, srcErr := s.src.WriteTo(&s.pending)
+	if srcErr != nil && srcErr != io.EOF {
+		return 0, srcErr
+	}
+
+	s.decode()
+
+	var (
+		end   = s.base + int64(len(s.history[0]))
+		limit = end - int64(s.kernel.width) - 1 // Last input frame followed by enough frames for interpolation.
+	)
+
+	if srcErr == io.EOF {
+		// Remaining output frames are interpolated against silence following the last input frame.
+		for i := range s.history {
+			s.history[i] = append(s.history[i], make([]float64, s.kernel.width)...)
+		}
+
+		limit = end - 1
+		s.flushed = true
+	}
+
+	data := s.resample(limit)
+
+	written, err := w.Write(data)
+	if err != nil {
+		return int64(written), err
+	}
+
+	return int64(written), srcErr
+}
+
+// Close closes src.
+func (s *ResampledStream) Close() error {
+	return s.src.Close()
+}
+
+// decode decodes all complete input frames from pending data into history.
+func (s *ResampledStream) decode() {
+	var (
+		size     = sampleBytes(s.in.BitDepth)
+		channels = int(s.in.NumChannels)
+		frame    = size * channels
+		data     = s.pending.Next(s.pending.Len() / frame * frame)
+		scale    = sampleScale(s.in.BitDepth)
+	)
+
+	for i := 0; i < len(data); i += frame {
+		for c := 0; c < channels; c++ {
+			v := float64(decodeSample(s.ord, s.in.BitDepth, data[i+c*size:])) / scale
+			s.history[c] = append(s.history[c], v)
+		}
+	}
+}
+
+// resample interpolates output frames that fall onto input frames up to and including limit,
+// returns them encoded, and discards the history that is no longer needed.
+func (s *ResampledStream) resample(limit int64) []byte {
+	var (
+		inRate   = int64(s.in.SampleRateHertz)
+		outRate  = int64(s.out.SampleRateHertz)
+		size     = sampleBytes(s.out.BitDepth)
+		channels = len(s.history)
+		width    = s.kernel.width
+	)
+
+	s.buf = s.buf[:0]
+
+	for ; ; s.next++ {
+		// Position of the output frame in input frames is pos + phase / outRate.
+		pos := s.next * inRate / outRate
+		if pos > limit {
+			break
+		}
+
+		var (
+			phase = s.next * inRate % outRate
+			taps  = s.kernel.taps(phase)
+			first = int(pos-s.base) - width + 1
+		)
+
+		for c := 0; c < channels; c++ {
+			var (
+				h = s.history[c][first : first+len(taps)]
+				v float64
+			)
+
+			for i, t := range taps {
+				v += h[i] * t
+			}
+
+			n := len(s.buf)
+			s.buf = append(s.buf, make([]byte, size)...)
+			encodeSample(s.ord, s.out.BitDepth, s.buf[n:], denormalise(v, s.out.BitDepth))
+		}
+	}
+
+	// The next output frame needs width frames preceding its position.
+	if drop := int(s.next*inRate/outRate-s.base) - width + 1; drop > 0 {
+		for c := range s.history {
+			n := copy(s.history[c], s.history[c][drop:])
+			s.history[c] = s.history[c][:n]
+		}
+
+		s.base += int64(drop)
+	}
+
+	return s.buf
+}
+
+// resampleKernel is a windowed sinc interpolation kernel for converting between two sample rates.
+// Each output frame is computed from 2*width input frames around it,
+// weighted by kernel values for the phase of the output frame, i.e. its fractional offset from input frames.
+type resampleKernel struct {
+	width   int
+	cutoff  float64 // Cut-off frequency relative to input Nyquist frequency.
+	outRate int64
+	step    int64 // Phases are multiples of step.
+	table   [][]float64
+	scratch []float64
+}
+
+func newResampleKernel(inRate, outRate int64) resampleKernel {
+	k := resampleKernel{
+		cutoff:  1,
+		outRate: outRate,
+		step:    gcd(inRate, outRate),
+	}
+
+	// When downsampling, frequencies above the output Nyquist frequency have to be filtered out,
+	// which requires a proportionally wider kernel.
+	if outRate < inRate {
+		k.cutoff = float64(outRate) / float64(inRate)
+	}
+
+	k.width = int(math.Ceil(resampleZeroCrossings / k.cutoff))
+	k.scratch = make([]float64, 2*k.width)
+
+	if phases := outRate / k.step; phases*int64(2*k.width) <= resampleMaxTableSize {
+		k.table = make([][]float64, phases)
+
+		for i := range k.table {
+			k.table[i] = make([]float64, 2*k.width)
+			k.compute(int64(i)*k.step, k.table[i])
+		}
+	}
+
+	return k
+}
+
+// taps returns kernel values for output frames with specified phase.
+func (k *resampleKernel) taps(phase int64) []float64 {
+	if k.table != nil {
+		return k.table[phase/k.step]
+	}
+
+	k.compute(phase, k.scratch)
+
+	return k.scratch
+}
+
+func (k *resampleKernel) compute(phase int64, dst []float64) {
+	frac := float64(phase) / float64(k.outRate)
+
+	for i := range dst {
+		// Distance from the input frame to the output frame, in input frames.
+		x := float64(i-k.width+1) - frac
+		dst[i] = k.cutoff * sinc(k.cutoff*x) * blackman(x/float64(k.width))
+	}
+}
+
+// sinc returns the normalised sinc function of x.
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// blackman returns the Blackman window function of x, which is non-zero for x in (-1, 1).
+func blackman(x float64) float64 {
+	if x <= -1 || x >= 1 {
+		return 0
+	}
+
+	return 0.42 + 0.5*math.Cos(math.Pi*x) + 0.08*math.Cos(2*math.Pi*x)
+}
+
+func gcd(a, b int64) int64 {
+	for b != 0 {
+		a, b = b, a%b
+	}
+
+	return a
+}
@@ -0,0 +1,151 @@
+package audio
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+)
+
+// tone returns n 16-bit samples of a sine wave with frequency freq in Hz and amplitude amp, sampled at rate.
+func tone(freq, amp float64, rate, n int) []int64 {
+	s := make([]int64, n)
+	for i := range s {
+		s[i] = int64(math.Round(amp * 32767 * math.Sin(2*math.Pi*freq*float64(i)/float64(rate))))
+	}
+
+	return s
+}
+
+// resample resamples 16-bit samples with numChannels channels from inRate to outRate,
+// reading them from a source that writes chunks of chunkSize bytes.
+func resample(t *testing.T, samples []int64, numChannels, inRate, outRate, chunkSize int) []int64 {
+	t.Helper()
+
+	var (
+		f   = Format{NumChannels: int32(numChannels), SampleRateHertz: int32(inRate), BitDepth: BitDepth16}
+		src = &chunkSource{data: encodeSamples(BitDepth16, samples...), size: chunkSize}
+	)
+
+	s, err := NewResampledStream(src, f, binary.LittleEndian, outRate)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := s.Format().SampleRateHertz; got != int32(outRate) {
+		t.Errorf("Format().SampleRateHertz = %d, want %d", got, outRate)
+	}
+
+	data, err := readAll(s)
+	if err != nil {
+		t.Fatalf("WriteTo() error = %v", err)
+	}
+
+	out := make([]int64, len(data)/2)
+	for i := range out {
+		out[i] = decodeSample(binary.LittleEndian, BitDepth16, data[i*2:])
+	}
+
+	return out
+}
+
+func TestResampledStreamLength(t *testing.T) {
+	tests := []struct {
+		inRate      int
+		outRate     int
+		numChannels int
+		numFrames   int
+		want        int
+	}{
+		{inRate: 8000, outRate: 16000, numChannels: 1, numFrames: 1, want: 2},
+		{inRate: 8000, outRate: 16000, numChannels: 1, numFrames: 160, want: 320},
+		{inRate: 8000, outRate: 16000, numChannels: 1, numFrames: 8000, want: 16000},
+		{inRate: 8000, outRate: 16000, numChannels: 2, numFrames: 161, want: 322},
+		{inRate: 8000, outRate: 16000, numChannels: 1, numFrames: 0, want: 0},
+		{inRate: 16000, outRate: 8000, numChannels: 1, numFrames: 160, want: 80},
+		{inRate: 16000, outRate: 8000, numChannels: 2, numFrames: 161, want: 81},
+		{inRate: 44100, outRate: 16000, numChannels: 1, numFrames: 441, want: 160},
+		{inRate: 11025, outRate: 16000, numChannels: 1, numFrames: 11025, want: 16000},
+	}
+
+	for _, tt := range tests {
+		// Chunks of the source are not aligned to frames or samples.
+		for _, chunkSize := range []int{3, 100, 1 << 20} {
+			samples := make([]int64, tt.numFrames*tt.numChannels)
+
+			got := len(resample(t, samples, tt.numChannels, tt.inRate, tt.outRate, chunkSize)) / tt.numChannels
+			if got != tt.want {
+				t.Errorf("resampling %d frames from %d Hz to %d Hz in chunks of %d bytes returned %d frames, want %d",
+					tt.numFrames, tt.inRate, tt.outRate, chunkSize, got, tt.want)
+			}
+		}
+	}
+}
+
+func TestResampledStreamUpsampleSine(t *testing.T) {
+	var (
+		in  = tone(1000, 0.5, 8000, 8000)
+		out = resample(t, in, 1, 8000, 16000, 320)
+	)
+
+	// Output frames that fall onto input frames keep their values.
+	for i, v := range in {
+		if d := out[2*i] - v; d < -1 || d > 1 {
+			t.Fatalf("output frame %d = %d, want input frame %d = %d", 2*i, out[2*i], i, v)
+		}
+	}
+
+	// The amplitude is kept away from the edges, where the signal is interpolated against silence.
+	want := tone(1000, 0.5, 16000, 16000)
+
+	for i := 1000; i < len(out)-1000; i++ {
+		if d := out[i] - want[i]; d < -50 || d > 50 {
+			t.Fatalf("output frame %d = %d, want %d", i, out[i], want[i])
+		}
+	}
+}
+
+func TestResampledStreamDownsampleFiltersAliases(t *testing.T) {
+	var (
+		pass = resample(t, tone(1000, 0.5, 16000, 16000), 1, 16000, 8000, 320)
+		stop = resample(t, tone(6000, 0.5, 16000, 16000), 1, 16000, 8000, 320)
+	)
+
+	level := func(s []int64) float64 {
+		f := make([]float64, 0, len(s))
+		for _, v := range s[1000 : len(s)-1000] {
+			f = append(f, float64(v)/32768)
+		}
+
+		return toDB(rms(f))
+	}
+
+	// A 1 kHz tone with amplitude 0.5 has RMS level of -9 dBFS.
+	if got := level(pass); math.Abs(got+9.03) > 0.1 {
+		t.Errorf("level of 1 kHz tone = %.2f dBFS, want -9.03 dBFS", got)
+	}
+
+	// A 6 kHz tone is above the Nyquist frequency of 8 kHz audio, so it must not alias into it.
+	if got := level(stop); got > -60 {
+		t.Errorf("level of 6 kHz tone = %.2f dBFS, want below -60 dBFS", got)
+	}
+}
+
+func TestNewResampledStreamInvalidFormat(t *testing.T) {
+	tests := []struct {
+		f    Format
+		rate int
+		want error
+	}{
+		{f: Format{NumChannels: 1, SampleRateHertz: 8000, BitDepth: BitDepth(24)}, rate: 16000, want: ErrInvalidBitDepth},
+		{f: Format{NumChannels: 1, SampleRateHertz: 0, BitDepth: BitDepth16}, rate: 16000, want: ErrInvalidSampleRate},
+		{f: Format{NumChannels: 1, SampleRateHertz: 8000, BitDepth: BitDepth16}, rate: 0, want: ErrInvalidSampleRate},
+		{f: Format{NumChannels: 0, SampleRateHertz: 8000, BitDepth: BitDepth16}, rate: 16000, want: ErrInvalidSampleRate},
+	}
+
+	for _, tt := range tests {
+		if _, err := NewResampledStream(&chunkSource{}, tt.f, binary.LittleEndian, tt.rate); !errors.Is(err, tt.want) {
+			t.Errorf("NewResampledStream(%+v, %d) error = %v, want %v", tt.f, tt.rate, err, tt.want)
+		}
+	}
+}
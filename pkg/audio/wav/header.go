@@ -0,0 +1,166 @@
+package wav
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+)
+
+// WAV format codes.
+const (
+	formatPCM        = 1
+	formatALaw       = 6
+	formatMuLaw      = 7
+	formatExtensible = 0xFFFE
+)
+
+const (
+	chunkHeaderSize = 8
+	maxFmtChunkSize = 1024
+
+	// unknownSize is the size written into RIFF and data chunk headers by encoders that cannot seek back
+	// to patch the header, e.g. when writing to a pipe.
+	unknownSize = math.MaxUint32
+)
+
+// header is the part of WAV header that is needed for reading PCM data.
+type header struct {
+	format      uint16
+	numChannels int
+	sampleRate  int
+	bitDepth    int
+	dataSize    int64 // Negative if the size of data is not known.
+}
+
+// readHeader reads WAV headers from r, until the beginning of PCM data.
+// It only reads forward, so r does not need to support seeking.
+// RIFF and RF64 files are supported, as well as data chunks with unknown size (0xFFFFFFFF),
+// which are read until the end of r. A data chunk with size 0 is empty.
+func readHeader(r io.Reader) (header, error) {
+	var (
+		h      header
+		rf64   bool
+		ds64   int64 = -1
+		hasFmt bool
+		buf    [12]byte
+	)
+
+	if _, err := io.ReadFull(r, buf[:12]); err != nil {
+		return h, fmt.Errorf("%w: %s", ErrInvalidFile, err)
+	}
+
+	switch string(buf[0:4]) {
+	case "RIFF":
+	case "RF64":
+		rf64 = true
+	default:
+		return h, fmt.Errorf("%w: missing RIFF header", ErrInvalidFile)
+	}
+
+	if string(buf[8:12]) != "WAVE" {
+		return h, fmt.Errorf("%w: missing WAVE header", ErrInvalidFile)
+	}
+
+	for {
+		if _, err := io.ReadFull(r, buf[:chunkHeaderSize]); err != nil {
+			return h, fmt.Errorf("%w: missing data chunk", ErrInvalidFile)
+		}
+
+		var (
+			id   = string(buf[0:4])
+			size = int64(binary.LittleEndian.Uint32(buf[4:8]))
+		)
+
+		switch id {
+		case "fmt ":
+			if err := h.readFmt(r, size); err != nil {
+				return h, err
+			}
+
+			hasFmt = true
+		case "ds64":
+			if size < 16 {
+				return h, fmt.Errorf("%w: invalid ds64 chunk", ErrInvalidFile)
+			}
+
+			var ds [16]byte
+			if _, err := io.ReadFull(r, ds[:]); err != nil {
+				return h, fmt.Errorf("%w: %s", ErrInvalidFile, err)
+			}
+
+			ds64 = int64(binary.LittleEndian.Uint64(ds[8:16]))
+
+			if err := skip(r, size-16); err != nil {
+				return h, err
+			}
+		case "data":
+			if !hasFmt {
+				return h, fmt.Errorf("%w: data chunk before fmt chunk", ErrInvalidFile)
+			}
+
+			switch {
+			case rf64 && size == unknownSize && ds64 >= 0:
+				h.dataSize = ds64
+			case size == unknownSize:
+				h.dataSize = -1
+			default:
+				h.dataSize = size
+			}
+
+			return h, nil
+		default:
+			if err := skip(r, size); err != nil {
+				return h, err
+			}
+		}
+	}
+}
+
+func (h *header) readFmt(r io.Reader, size int64) error {
+	if size < 16 || size > maxFmtChunkSize {
+		return fmt.Errorf("%w: invalid fmt chunk size %d", ErrInvalidFile, size)
+	}
+
+	// Chunks are padded to even size.
+	b := make([]byte, size+size%2)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidFile, err)
+	}
+
+	code := binary.LittleEndian.Uint16(b[0:2])
+	if code == formatExtensible && size >= 26 {
+		// Extensible format stores the actual format code in the first bytes of the sub-format GUID.
+		code = binary.LittleEndian.Uint16(b[24:26])
+	}
+
+	h.format = code
+	h.numChannels = int(binary.LittleEndian.Uint16(b[2:4]))
+	h.sampleRate = int(binary.LittleEndian.Uint32(b[4:8]))
+	h.bitDepth = int(binary.LittleEndian.Uint16(b[14:16]))
+
+	switch code {
+	case formatPCM:
+	case formatALaw, formatMuLaw:
+		if h.bitDepth != 8 {
+			return fmt.Errorf("%w: invalid G.711 bit depth %d", ErrInvalidFile, h.bitDepth)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported format code %#x, only PCM and G.711 are supported", ErrInvalidFile, code)
+	}
+
+	if h.numChannels < 1 || h.sampleRate < 1 {
+		return fmt.Errorf("%w: invalid audio format", ErrInvalidFile)
+	}
+
+	return nil
+}
+
+// skip discards a chunk of specified size from r, including padding.
+func skip(r io.Reader, size int64) error {
+	if _, err := io.CopyN(io.Discard, r, size+size%2); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidFile, err)
+	}
+
+	return nil
+}
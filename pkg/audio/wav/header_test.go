@@ -0,0 +1,71 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"testing"
+
+	"github.com/speechly/slu-client/pkg/audio"
+)
+
+func TestReadHeaderDataSize(t *testing.T) {
+	data := encodeSamples(audio.BitDepth16, false, testSamples(4))
+
+	tests := []struct {
+		name     string
+		file     []byte
+		wantSize int64
+		wantData int
+	}{
+		{
+			name:     "known size",
+			file:     testFile(audio.BitDepth16, uint32(len(data)), data, listChunk),
+			wantSize: int64(len(data)),
+			wantData: len(data),
+		},
+		{
+			name:     "empty data chunk followed by chunk",
+			file:     testFile(audio.BitDepth16, 0, nil, listChunk),
+			wantSize: 0,
+			wantData: 0,
+		},
+		{
+			name:     "empty data chunk at end of file",
+			file:     testFile(audio.BitDepth16, 0, nil, nil),
+			wantSize: 0,
+			wantData: 0,
+		},
+		{
+			name:     "unknown size",
+			file:     testFile(audio.BitDepth16, unknownSize, data, nil),
+			wantSize: -1,
+			wantData: len(data),
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			h, err := readHeader(bytes.NewReader(tt.file))
+			if err != nil {
+				t.Fatalf("readHeader() error = %v", err)
+			}
+
+			if h.dataSize != tt.wantSize {
+				t.Errorf("readHeader() dataSize = %d, want %d", h.dataSize, tt.wantSize)
+			}
+
+			r, err := NewReader(io.NopCloser(bytes.NewReader(tt.file)), 16, binary.LittleEndian)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			out, _ := readAll(t, r, 2)
+			if len(out) != tt.wantData {
+				t.Errorf("read %d bytes of data, want %d", len(out), tt.wantData)
+			}
+		})
+	}
+}
@@ -10,12 +10,20 @@ import (
 // NewFilePlayer returns new audio.Player which uses a WAV reader as an audio source.
 // WAV reader will use the file specified by path as its data source.
 func NewFilePlayer(path string, bufSize int, ord binary.ByteOrder, l logger.Logger) (*audio.Player, error) {
+	return NewDeviceFilePlayer(nil, path, bufSize, ord, l)
+}
+
+// NewDeviceFilePlayer returns new audio.Player that plays a WAV file specified by path through dev.
+// If dev is nil, default OS audio output device is used.
+func NewDeviceFilePlayer(
+	dev *audio.Device, path string, bufSize int, ord binary.ByteOrder, l logger.Logger,
+) (*audio.Player, error) {
 	r, err := NewFileReader(path, bufSize, ord)
 	if err != nil {
 		return nil, err
 	}
 
-	return audio.NewPlayer(r, l)
+	return audio.NewDevicePlayer(dev, r, l)
 }
 
 // NewPlayer returns new audio.Player which uses a WAV reader as an audio source.
@@ -0,0 +1,351 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/speechly/slu-client/pkg/audio"
+)
+
+// memSource is a Source that reads from memory.
+type memSource struct {
+	*bytes.Reader
+}
+
+func (memSource) Close() error {
+	return nil
+}
+
+// testSamples returns n distinct samples that fit into 8 bits.
+func testSamples(n int) []int64 {
+	s := make([]int64, n)
+	for i := range s {
+		s[i] = int64((i*37)%256 - 128)
+	}
+
+	return s
+}
+
+// encodeSamples encodes samples as little-endian PCM data of bit depth d.
+// If unsigned is true, 8-bit samples are encoded as unsigned, like WAV stores them.
+func encodeSamples(d audio.BitDepth, unsigned bool, samples []int64) []byte {
+	var buf bytes.Buffer
+
+	for _, v := range samples {
+		switch d {
+		case audio.BitDepth8:
+			b := byte(v)
+			if unsigned {
+				b ^= 0x80
+			}
+
+			buf.WriteByte(b)
+		case audio.BitDepth16:
+			_ = binary.Write(&buf, binary.LittleEndian, int16(v))
+		case audio.BitDepth32:
+			_ = binary.Write(&buf, binary.LittleEndian, int32(v))
+		default:
+			_ = binary.Write(&buf, binary.LittleEndian, v)
+		}
+	}
+
+	return buf.Bytes()
+}
+
+// testFile returns a mono PCM WAV file of bit depth d, with a data chunk of dataSize containing data,
+// followed by trailer. Odd-sized data is padded before trailer.
+func testFile(d audio.BitDepth, dataSize uint32, data, trailer []byte) []byte {
+	var (
+		h  [44]byte
+		le = binary.LittleEndian
+	)
+
+	if len(data)%2 > 0 && len(trailer) > 0 {
+		trailer = append([]byte{0}, trailer...)
+	}
+
+	copy(h[0:4], "RIFF")
+	le.PutUint32(h[4:8], uint32(len(h)-8+len(data)+len(trailer)))
+	copy(h[8:12], "WAVE")
+
+	copy(h[12:16], "fmt ")
+	le.PutUint32(h[16:20], 16)
+	le.PutUint16(h[20:22], 1) // PCM.
+	le.PutUint16(h[22:24], 1)
+	le.PutUint32(h[24:28], 16000)
+	le.PutUint32(h[28:32], 16000*uint32(d)/8)
+	le.PutUint16(h[32:34], uint16(d)/8)
+	le.PutUint16(h[34:36], uint16(d))
+
+	copy(h[36:40], "data")
+	le.PutUint32(h[40:44], dataSize)
+
+	return append(append(h[:], data...), trailer...)
+}
+
+// listChunk is a chunk that commonly follows the data chunk.
+var listChunk = []byte("LIST\x04\x00\x00\x00INFO")
+
+// readAll reads all data from r using WriteTo and returns it, as well as the number of bytes of each chunk.
+func readAll(t *testing.T, r *Reader, maxChunks int) ([]byte, []int) {
+	t.Helper()
+
+	var (
+		out    bytes.Buffer
+		chunks []int
+	)
+
+	for {
+		n, err := r.WriteTo(&out)
+		chunks = append(chunks, int(n))
+
+		if err == io.EOF {
+			return out.Bytes(), chunks
+		}
+
+		if err != nil {
+			t.Fatalf("WriteTo() error = %v", err)
+		}
+
+		if len(chunks) > maxChunks {
+			t.Fatalf("WriteTo() did not return io.EOF after %d chunks", len(chunks))
+		}
+	}
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// chunkBytes returns the number of bytes in chunks of samples of size bytes each.
+func chunkBytes(chunks []int, size int) []int {
+	b := make([]int, len(chunks))
+	for i, n := range chunks {
+		b[i] = n * size
+	}
+
+	return b
+}
+
+var testBitDepths = []audio.BitDepth{audio.BitDepth8, audio.BitDepth16, audio.BitDepth32, audio.BitDepth64}
+
+// partialChunkTests are cases where the number of samples is not a multiple of the buffer size.
+var partialChunkTests = []struct {
+	name        string
+	numSamples  int
+	bufSize     int
+	trailing    int
+	unknownSize bool
+	trailer     []byte
+	chunks      []int // Number of samples in each chunk.
+}{
+	{name: "multiple of buffer size", numSamples: 6, bufSize: 3, chunks: []int{3, 3, 0}},
+	{name: "partial final chunk", numSamples: 7, bufSize: 3, chunks: []int{3, 3, 1}},
+	{
+		name:       "partial final chunk followed by chunk",
+		numSamples: 7,
+		bufSize:    3,
+		trailer:    listChunk,
+		chunks:     []int{3, 3, 1},
+	},
+	{
+		name:       "partial final chunk with incomplete sample",
+		numSamples: 7,
+		bufSize:    3,
+		trailing:   1,
+		chunks:     []int{3, 3, 1},
+	},
+	{
+		name:        "partial final chunk of unknown size",
+		numSamples:  7,
+		bufSize:     3,
+		unknownSize: true,
+		chunks:      []int{3, 3, 1},
+	},
+	{name: "shorter than buffer", numSamples: 2, bufSize: 5, chunks: []int{2}},
+}
+
+func TestReaderWriteToPartialChunk(t *testing.T) {
+	for _, d := range testBitDepths {
+		for _, tt := range partialChunkTests {
+			d, tt := d, tt
+
+			if tt.trailing >= int(d)/8 {
+				continue
+			}
+
+			t.Run(strconv.Itoa(int(d))+"/"+tt.name, func(t *testing.T) {
+				var (
+					samples = testSamples(tt.numSamples)
+					data    = append(encodeSamples(d, true, samples), make([]byte, tt.trailing)...)
+					size    = uint32(len(data))
+				)
+
+				if tt.unknownSize {
+					size = unknownSize
+				}
+
+				file := testFile(d, size, data, tt.trailer)
+
+				r, err := NewReader(memSource{bytes.NewReader(file)}, tt.bufSize, binary.LittleEndian)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if got := r.Format().BitDepth; got != d {
+					t.Fatalf("Format().BitDepth = %d, want %d", got, d)
+				}
+
+				out, chunks := readAll(t, r, len(tt.chunks))
+
+				if want := chunkBytes(tt.chunks, int(d)/8); !equalInts(chunks, want) {
+					t.Errorf("WriteTo() chunks = %v, want %v", chunks, want)
+				}
+
+				if want := encodeSamples(d, false, samples); !bytes.Equal(out, want) {
+					t.Errorf("WriteTo() data = %v, want %v", out, want)
+				}
+			})
+		}
+	}
+}
+
+func TestReaderReadBufferPartialChunk(t *testing.T) {
+	for _, d := range testBitDepths {
+		for _, tt := range partialChunkTests {
+			d, tt := d, tt
+
+			if tt.trailing >= int(d)/8 {
+				continue
+			}
+
+			t.Run(strconv.Itoa(int(d))+"/"+tt.name, func(t *testing.T) {
+				var (
+					samples = testSamples(tt.numSamples)
+					data    = append(encodeSamples(d, true, samples), make([]byte, tt.trailing)...)
+					size    = uint32(len(data))
+				)
+
+				if tt.unknownSize {
+					size = unknownSize
+				}
+
+				file := testFile(d, size, data, tt.trailer)
+
+				r, err := NewReader(memSource{bytes.NewReader(file)}, tt.bufSize, binary.LittleEndian)
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				var (
+					b      = r.Buffer()
+					ints   = make([]int, tt.bufSize)
+					got    []int
+					chunks []int
+				)
+
+				for {
+					n, err := r.ReadBuffer(b)
+					if err != nil && err != io.EOF {
+						t.Fatalf("ReadBuffer() error = %v", err)
+					}
+
+					if b.Len() != n {
+						t.Errorf("Len() = %d after reading %d samples", b.Len(), n)
+					}
+
+					// Samples past Len must not be returned, even if the previous chunk filled the buffer.
+					rn, rerr := b.Read(ints, d)
+					if rerr != nil && rerr != io.EOF {
+						t.Fatalf("Read() error = %v", rerr)
+					}
+
+					got = append(got, ints[:rn]...)
+					chunks = append(chunks, n)
+
+					if err == io.EOF {
+						break
+					}
+
+					if len(chunks) > len(tt.chunks) {
+						t.Fatalf("ReadBuffer() did not return io.EOF after %d chunks", len(chunks))
+					}
+				}
+
+				if !equalInts(chunks, tt.chunks) {
+					t.Errorf("ReadBuffer() chunks = %v, want %v", chunks, tt.chunks)
+				}
+
+				want := make([]int, len(samples))
+				for i, v := range samples {
+					want[i] = int(v)
+				}
+
+				if !equalInts(got, want) {
+					t.Errorf("ReadBuffer() samples = %v, want %v", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestReaderG711(t *testing.T) {
+	tests := []struct {
+		name       string
+		format     uint16
+		companding audio.Companding
+	}{
+		{name: "A-law", format: formatALaw, companding: audio.CompandingALaw},
+		{name: "mu-law", format: formatMuLaw, companding: audio.CompandingMuLaw},
+	}
+
+	// Codes of zero, the smallest and the largest values of both signs in both laws.
+	data := []byte{0x00, 0x2A, 0x55, 0x7E, 0x7F, 0x80, 0xAA, 0xD5, 0xFE, 0xFF, 0x01}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			file := testFile(audio.BitDepth8, uint32(len(data)), data, listChunk)
+			binary.LittleEndian.PutUint16(file[20:22], tt.format)
+
+			r, err := NewReader(memSource{bytes.NewReader(file)}, 4, binary.LittleEndian)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// G.711 samples are expanded into 16-bit linear PCM.
+			if got := r.Format().BitDepth; got != audio.BitDepth16 {
+				t.Fatalf("Format().BitDepth = %d, want 16", got)
+			}
+
+			out, chunks := readAll(t, r, 3)
+
+			if want := []int{8, 8, 6}; !equalInts(chunks, want) {
+				t.Errorf("WriteTo() chunks = %v, want %v", chunks, want)
+			}
+
+			want := make([]int64, len(data))
+			for i, b := range data {
+				want[i] = int64(tt.companding.Decode(b))
+			}
+
+			if !bytes.Equal(out, encodeSamples(audio.BitDepth16, false, want)) {
+				t.Errorf("WriteTo() data = %v, want samples %v", out, want)
+			}
+		})
+	}
+}
@@ -10,20 +10,43 @@ import (
 // NewFileRecorder returns a new audio.Recorder with a WAV writer set as destination.
 // The writer will write to a file specified by path.
 func NewFileRecorder(path string, fmt audio.Format, bufSize int, l logger.Logger) (*audio.Recorder, error) {
+	return NewDeviceFileRecorder(nil, path, fmt, bufSize, l)
+}
+
+// NewDeviceFileRecorder returns a new audio.Recorder that records audio from dev to a WAV file specified by path.
+// If dev is nil, default OS audio input device is used.
+func NewDeviceFileRecorder(
+	dev *audio.Device, path string, fmt audio.Format, bufSize int, l logger.Logger,
+) (*audio.Recorder, error) {
 	f, err := os.Create(path)
 	if err != nil {
 		return nil, err
 	}
 
-	return NewRecorder(f, fmt, bufSize, l)
+	r, err := NewDeviceRecorder(dev, f, fmt, bufSize, l)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return r, nil
 }
 
 // NewRecorder returns a new audio.Recorder with a WAV writer set as destination.
 func NewRecorder(dst Sink, fmt audio.Format, bufSize int, l logger.Logger) (*audio.Recorder, error) {
+	return NewDeviceRecorder(nil, dst, fmt, bufSize, l)
+}
+
+// NewDeviceRecorder returns a new audio.Recorder that records audio from dev to a WAV writer with dst as destination.
+// dst does not need to support seeking, so e.g. STDOUT can be used.
+// If dev is nil, default OS audio input device is used.
+func NewDeviceRecorder(
+	dev *audio.Device, dst Sink, fmt audio.Format, bufSize int, l logger.Logger,
+) (*audio.Recorder, error) {
 	w, err := NewWriter(dst, fmt, bufSize)
 	if err != nil {
 		return nil, err
 	}
 
-	return audio.NewRecorder(w, l)
+	return audio.NewDeviceRecorder(dev, w, l)
 }
@@ -1,10 +1,9 @@
 package wav
 
 import (
+	"encoding/binary"
 	"io"
 
-	gaudio "github.com/go-audio/audio"
-	"github.com/go-audio/wav"
 	"github.com/hashicorp/go-multierror"
 
 	"github.com/speechly/slu-client/pkg/audio"
@@ -12,12 +11,19 @@ import (
 
 // Supported WAV encodings.
 const (
-	EncodingPCM = 1
+	EncodingPCM = formatPCM
 )
 
-// Sink is an interface for a WAV data source.
+// Size of the header written by Writer, from the beginning of the file until the beginning of PCM data.
+const headerSize = 44
+
+// Sink is an interface for a WAV data destination.
+// If it also implements io.Seeker and supports seeking (i.e. it's a regular file),
+// WAV headers are updated with correct sizes when the Writer is closed.
+// Otherwise, e.g. for pipes and network connections, sizes are left unknown (0xFFFFFFFF),
+// which is understood by most WAV decoders as "read until the end of the stream".
 type Sink interface {
-	io.WriteSeeker
+	io.Writer
 	io.Closer
 }
 
@@ -25,36 +31,53 @@ type Sink interface {
 // serialisation into binary format and headers.
 // Writer implements audio.Sink and io.Closer interfaces.
 type Writer struct {
-	dst    io.Closer
-	fmt    audio.Format
-	buf    audio.Buffer
-	intBuf gaudio.IntBuffer
-	enc    *wav.Encoder
+	dst      Sink
+	fmt      audio.Format
+	buf      audio.Buffer
+	ints     []int
+	data     io.Writer
+	size     int64
+	seeker   io.Seeker
+	startPos int64
 }
 
 // NewWriter returns a new Writer that will write data to specified dst.
 // fmt tells the writer what format of audio to use (sample rate, number of channels, etc.).
 // and bufSize controls the size of internal buffer that will be used for writing the data.
+// The header is written to dst immediately, so that the data can be consumed while it's being written.
 func NewWriter(dst Sink, fmt audio.Format, bufSize int) (*Writer, error) {
 	buf, err := audio.NewBuffer(fmt.BitDepth, bufSize)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Writer{
-		fmt: fmt,
-		buf: buf,
-		dst: dst,
-		enc: wav.NewEncoder(dst, int(fmt.SampleRateHertz), int(fmt.BitDepth), int(fmt.NumChannels), EncodingPCM),
-		intBuf: gaudio.IntBuffer{
-			Data:           make([]int, bufSize),
-			SourceBitDepth: int(fmt.BitDepth),
-			Format: &gaudio.Format{
-				NumChannels: int(fmt.NumChannels),
-				SampleRate:  int(fmt.SampleRateHertz),
-			},
-		},
-	}, nil
+	w := &Writer{
+		dst:  dst,
+		fmt:  fmt,
+		buf:  buf,
+		ints: make([]int, bufSize),
+		data: dst,
+	}
+
+	// WAV stores 8-bit samples as unsigned.
+	if fmt.BitDepth == audio.BitDepth8 {
+		w.data = &unsignedWriter{w: dst}
+	}
+
+	// Files opened for writing can seek, but e.g. STDOUT redirected to a pipe will fail to do so,
+	// so it's not enough to check if dst implements io.Seeker.
+	if s, ok := dst.(io.Seeker); ok {
+		if pos, err := s.Seek(0, io.SeekCurrent); err == nil {
+			w.seeker = s
+			w.startPos = pos
+		}
+	}
+
+	if err := w.writeHeader(unknownSize, unknownSize); err != nil {
+		return nil, err
+	}
+
+	return w, nil
 }
 
 // Format returns the audio format of the writer.
@@ -71,30 +94,34 @@ func (w *Writer) Buffer() audio.Buffer {
 
 // WriteBuffer writes data from b into the writer.
 func (w *Writer) WriteBuffer(b audio.Buffer) (int, error) {
-	// Resize to max capacity before reading
-	w.intBuf.Data = w.intBuf.Data[:cap(w.intBuf.Data)]
+	src := b
 
-	n, err := b.Read(w.intBuf.Data, w.fmt.BitDepth)
-	if err != nil {
-		return 0, err
-	}
+	// Buffers with different bit depth have to be converted first.
+	if b.BitDepth() != w.fmt.BitDepth {
+		n, err := b.Read(w.ints, w.fmt.BitDepth)
+		if err != nil {
+			return 0, err
+		}
 
-	// Resize to valid length, since encoder.Write doesn't accept number of values to write
-	w.intBuf.Data = w.intBuf.Data[:n]
+		if _, err := w.buf.Write(w.ints[:n], w.fmt.BitDepth); err != nil {
+			return 0, err
+		}
 
-	// Write the buffer to encoder.
-	if err := w.enc.Write(&w.intBuf); err != nil {
-		return 0, err
+		src = w.buf
 	}
 
-	return n, nil
+	n, err := src.Encode(binary.LittleEndian, w.data)
+	w.size += int64(n * int(w.fmt.BitDepth) / 8)
+
+	return n, err
 }
 
-// Close closes the writer by closing the encoder and the underlying dst.
+// Close closes the writer by finalising WAV headers and closing the underlying dst.
+// Headers are only finalised if dst supports seeking.
 func (w *Writer) Close() error {
 	errs := &multierror.Error{}
 
-	if err := w.enc.Close(); err != nil {
+	if err := w.finalise(); err != nil {
 		errs = multierror.Append(errs, err)
 	}
 
@@ -104,3 +131,88 @@ func (w *Writer) Close() error {
 
 	return errs.ErrorOrNil()
 }
+
+func (w *Writer) finalise() error {
+	// Data of unknown size is read until the end of the stream, so there is nothing to finalise.
+	// In particular, it must not be padded, since the padding would be read as audio.
+	if w.seeker == nil {
+		return nil
+	}
+
+	// Chunks must have even size, so odd-sized data has to be padded.
+	pad := w.size % 2
+	if pad > 0 {
+		if _, err := w.dst.Write([]byte{0}); err != nil {
+			return err
+		}
+	}
+
+	var (
+		dataSize = uint64(w.size)
+		riffSize = uint64(headerSize-chunkHeaderSize) + dataSize + uint64(pad)
+	)
+
+	// Sizes that do not fit into a RIFF header are left unknown.
+	if riffSize > unknownSize {
+		return nil
+	}
+
+	if _, err := w.seeker.Seek(w.startPos, io.SeekStart); err != nil {
+		return err
+	}
+
+	if err := w.writeHeader(uint32(riffSize), uint32(dataSize)); err != nil {
+		return err
+	}
+
+	_, err := w.seeker.Seek(0, io.SeekEnd)
+
+	return err
+}
+
+func (w *Writer) writeHeader(riffSize, dataSize uint32) error {
+	var (
+		h          [headerSize]byte
+		le         = binary.LittleEndian
+		blockAlign = int(w.fmt.NumChannels) * int(w.fmt.BitDepth) / 8
+	)
+
+	copy(h[0:4], "RIFF")
+	le.PutUint32(h[4:8], riffSize)
+	copy(h[8:12], "WAVE")
+
+	copy(h[12:16], "fmt ")
+	le.PutUint32(h[16:20], 16)
+	le.PutUint16(h[20:22], EncodingPCM)
+	le.PutUint16(h[22:24], uint16(w.fmt.NumChannels))
+	le.PutUint32(h[24:28], uint32(w.fmt.SampleRateHertz))
+	le.PutUint32(h[28:32], uint32(int(w.fmt.SampleRateHertz)*blockAlign))
+	le.PutUint16(h[32:34], uint16(blockAlign))
+	le.PutUint16(h[34:36], uint16(w.fmt.BitDepth))
+
+	copy(h[36:40], "data")
+	le.PutUint32(h[40:44], dataSize)
+
+	_, err := w.dst.Write(h[:])
+
+	return err
+}
+
+// unsignedWriter converts signed 8-bit PCM samples into unsigned ones.
+type unsignedWriter struct {
+	w   io.Writer
+	buf []byte
+}
+
+func (u *unsignedWriter) Write(p []byte) (int, error) {
+	if cap(u.buf) < len(p) {
+		u.buf = make([]byte, len(p))
+	}
+
+	b := u.buf[:len(p)]
+	for i, v := range p {
+		b[i] = v ^ 0x80
+	}
+
+	return u.w.Write(b)
+}
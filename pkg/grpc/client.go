@@ -0,0 +1,268 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/status"
+
+	"github.com/speechly/slu-client/pkg/logger"
+)
+
+// ErrInvalidScheme is returned when provided URL has an unsupported scheme.
+// Supported schemes are "grpc" or "grpc+tls".
+var ErrInvalidScheme = errors.New("unsupported URL scheme")
+
+// Connection errors.
+var (
+	ErrNotDialled = errors.New("client has not been dialled")
+	ErrShutdown   = errors.New("connection has been shut down")
+	ErrNotServing = errors.New("server is not serving")
+)
+
+// StateChange is a change of connectivity state of the client connection.
+type StateChange struct {
+	From connectivity.State
+	To   connectivity.State
+	At   time.Time
+}
+
+// Client is a wrapper around gRPC connection, which manages the lifecycle of the connection.
+// By default, Dial does not block and the connection is established (and re-established) in the background,
+// using exponential backoff between attempts. Conn waits for a connection that is not yet ready,
+// Healthcheck can be used for checking connectivity and WithStateHandler for watching connectivity changes.
+type Client struct {
+	conn        *grpc.ClientConn
+	name        string
+	host        string
+	secure      bool
+	log         logger.Logger
+	opts        options
+	cancelWatch context.CancelFunc
+}
+
+// NewClient returns a new instance of GRPCClient.
+// All lines logged by the client are tagged with its name and host.
+func NewClient(name string, u url.URL, log logger.Logger, opts ...Option) (*Client, error) {
+	var secure bool
+	switch u.Scheme {
+	case "grpc+tls":
+		secure = true
+	case "grpc":
+		secure = false
+	default:
+		return nil, ErrInvalidScheme
+	}
+
+	return &Client{
+		name:   name,
+		host:   u.Host,
+		secure: secure,
+		log:    logger.WithFields(log, logger.Fields{"grpc_client": name, "host": u.Host}),
+		opts:   newOptions(opts),
+	}, nil
+}
+
+// Invoke invokes a unary gRPC call through the underlying connection.
+func (c *Client) Invoke(ctx context.Context, method string, args, res interface{}, opts ...grpc.CallOption) error {
+	conn, err := c.ConnContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	return conn.Invoke(ctx, method, args, res, opts...)
+}
+
+// Conn returns the underlying gRPC client connection, once it is ready.
+// If the connection is idle, connecting or recovering from a transient failure, Conn waits for it to become ready,
+// for up to the connect timeout (see WithConnectTimeout). If the connection is not ready by then,
+// has been shut down or was never dialled, an error is returned instead.
+func (c *Client) Conn() (*grpc.ClientConn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.opts.connectTimeout)
+	defer cancel()
+
+	return c.ConnContext(ctx)
+}
+
+// ConnContext is the same as Conn, but it waits for the connection to become ready until ctx is done.
+func (c *Client) ConnContext(ctx context.Context) (*grpc.ClientConn, error) {
+	if c.conn == nil {
+		return nil, fmt.Errorf("gRPC client %s: %w", c.name, ErrNotDialled)
+	}
+
+	if err := waitForReady(ctx, c.conn); err != nil {
+		return nil, fmt.Errorf("gRPC client %s is not connected: %w", c.name, err)
+	}
+
+	return c.conn, nil
+}
+
+// State returns current connectivity state of the client connection.
+// If the client has not been dialled, connectivity.Idle is returned.
+func (c *Client) State() connectivity.State {
+	if c.conn == nil {
+		return connectivity.Idle
+	}
+
+	return c.conn.GetState()
+}
+
+// Healthcheck checks that the client connection is ready, connecting it if necessary,
+// and that the server reports itself as serving using the standard gRPC health checking protocol.
+// Servers that do not implement the health service are considered healthy once the connection is ready.
+// It waits for the connection to become ready until ctx is done.
+func (c *Client) Healthcheck(ctx context.Context) error {
+	conn, err := c.ConnContext(ctx)
+	if err != nil {
+		return err
+	}
+
+	res, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	switch {
+	case status.Code(err) == codes.Unimplemented:
+		return nil
+	case err != nil:
+		return fmt.Errorf("gRPC client %s health check failed: %w", c.name, err)
+	case res.GetStatus() != healthpb.HealthCheckResponse_SERVING:
+		return fmt.Errorf("gRPC client %s: %w (status: %s)", c.name, ErrNotServing, res.GetStatus())
+	}
+
+	return nil
+}
+
+// Dial starts the client by creating gRPC connection.
+// Unless WithBlock option was used, Dial does not wait for the connection to be established.
+// If the client was configured to use an existing connection with WithConn,
+// Dial only waits for that connection to become ready when WithBlock is used.
+func (c *Client) Dial(ctx context.Context) error {
+	conn, err := c.dial(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to start gRPC client %s: %w", c.name, err)
+	}
+
+	watchCtx, cancel := context.WithCancel(context.Background())
+
+	c.conn = conn
+	c.cancelWatch = cancel
+
+	go func() {
+		defer cancel()
+		c.watch(watchCtx, conn)
+	}()
+
+	return nil
+}
+
+func (c *Client) dial(ctx context.Context) (*grpc.ClientConn, error) {
+	if c.opts.conn != nil {
+		if c.opts.block {
+			if err := waitForReady(ctx, c.opts.conn); err != nil {
+				return nil, err
+			}
+		}
+
+		return c.opts.conn, nil
+	}
+
+	var tlsOpt grpc.DialOption
+
+	if c.secure {
+		cfg, err := c.opts.tls.Build()
+		if err != nil {
+			return nil, err
+		}
+
+		tlsOpt = grpc.WithTransportCredentials(credentials.NewTLS(cfg))
+	} else {
+		tlsOpt = grpc.WithInsecure()
+	}
+
+	resolver.SetDefaultScheme("dns")
+
+	opts := []grpc.DialOption{
+		tlsOpt,
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           c.opts.backoff,
+			MinConnectTimeout: c.opts.minConnectTimeout,
+		}),
+	}
+
+	if c.opts.block {
+		opts = append(opts, grpc.WithBlock())
+	}
+
+	c.log.Debug("dialling gRPC connection")
+
+	conn, err := grpc.DialContext(ctx, c.host, append(opts, c.opts.dialOptions()...)...)
+	if err != nil {
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// watch reports connectivity state changes of conn, until it is shut down or ctx is done.
+func (c *Client) watch(ctx context.Context, conn *grpc.ClientConn) {
+	for s := conn.GetState(); s != connectivity.Shutdown; {
+		if !conn.WaitForStateChange(ctx, s) {
+			return
+		}
+
+		ch := StateChange{
+			From: s,
+			To:   conn.GetState(),
+			At:   time.Now(),
+		}
+
+		c.log.Debugf("gRPC connection state changed from %s to %s", ch.From, ch.To)
+
+		for _, h := range c.opts.stateHandlers {
+			h(ch)
+		}
+
+		s = ch.To
+	}
+}
+
+// Close closes the client by closing gRPC connection.
+// Connections provided with WithConn are not closed, since they are owned by the caller.
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+
+	if c.conn == c.opts.conn {
+		// Connection is not closed, so watching has to be stopped explicitly.
+		c.cancelWatch()
+		return nil
+	}
+
+	c.log.Debug("closing gRPC connection")
+	return c.conn.Close()
+}
+
+func waitForReady(ctx context.Context, conn *grpc.ClientConn) error {
+	for s := conn.GetState(); s != connectivity.Ready; s = conn.GetState() {
+		switch s {
+		case connectivity.Shutdown:
+			return ErrShutdown
+		case connectivity.Idle:
+			conn.Connect()
+		}
+
+		if !conn.WaitForStateChange(ctx, s) {
+			return fmt.Errorf("%w (last state: %s)", ctx.Err(), s)
+		}
+	}
+
+	return nil
+}
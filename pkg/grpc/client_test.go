@@ -0,0 +1,330 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/speechly/slu-client/pkg/logger"
+)
+
+// testURL has an IP address as its host, so that it is not resolved using DNS.
+// Connections are made through the dialer of testServer instead.
+var testURL = url.URL{Scheme: "grpc", Host: "127.0.0.1:1"}
+
+// testBackoff retries failed connection attempts quickly.
+var testBackoff = backoff.Config{BaseDelay: 10 * time.Millisecond, Multiplier: 1, MaxDelay: 10 * time.Millisecond}
+
+// testServer is an in-process gRPC server that serves the standard health service.
+// Connection attempts fail until the server is started.
+type testServer struct {
+	lis     *bufconn.Listener
+	srv     *grpc.Server
+	health  *health.Server
+	started int32
+	dials   int32
+}
+
+func newTestServer(t *testing.T, withHealth bool) *testServer {
+	t.Helper()
+
+	s := &testServer{
+		lis: bufconn.Listen(1 << 16),
+		srv: grpc.NewServer(),
+	}
+
+	if withHealth {
+		s.health = health.NewServer()
+		healthpb.RegisterHealthServer(s.srv, s.health)
+	}
+
+	t.Cleanup(s.srv.Stop)
+
+	return s
+}
+
+func (s *testServer) start() {
+	atomic.StoreInt32(&s.started, 1)
+
+	go func() {
+		_ = s.srv.Serve(s.lis)
+	}()
+}
+
+func (s *testServer) dial(ctx context.Context, _ string) (net.Conn, error) {
+	atomic.AddInt32(&s.dials, 1)
+
+	if atomic.LoadInt32(&s.started) == 0 {
+		return nil, errors.New("connection refused")
+	}
+
+	return s.lis.DialContext(ctx)
+}
+
+func (s *testServer) client(t *testing.T, opts ...Option) *Client {
+	t.Helper()
+
+	opts = append([]Option{
+		WithDialOptions(grpc.WithContextDialer(s.dial)),
+		WithBackoff(testBackoff),
+		WithMinConnectTimeout(time.Second),
+	}, opts...)
+
+	c, err := NewClient("test", testURL, logger.NewStdLogger(io.Discard), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = c.Close()
+	})
+
+	return c
+}
+
+func TestClientHealthcheck(t *testing.T) {
+	tests := []struct {
+		name       string
+		withHealth bool
+		status     healthpb.HealthCheckResponse_ServingStatus
+		want       error
+	}{
+		{name: "serving", withHealth: true, status: healthpb.HealthCheckResponse_SERVING},
+		{name: "not serving", withHealth: true, status: healthpb.HealthCheckResponse_NOT_SERVING, want: ErrNotServing},
+		{name: "no health service", withHealth: false},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			s := newTestServer(t, tt.withHealth)
+			if s.health != nil {
+				s.health.SetServingStatus("", tt.status)
+			}
+
+			s.start()
+
+			c := s.client(t)
+			if err := c.Dial(context.Background()); err != nil {
+				t.Fatalf("Dial() error = %v", err)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+
+			if err := c.Healthcheck(ctx); !errors.Is(err, tt.want) {
+				t.Errorf("Healthcheck() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestClientHealthcheckUnreachable(t *testing.T) {
+	s := newTestServer(t, true)
+
+	c := s.client(t)
+	if err := c.Dial(context.Background()); err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if err := c.Healthcheck(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Healthcheck() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestClientNotDialled(t *testing.T) {
+	c := newTestServer(t, true).client(t)
+
+	if _, err := c.Conn(); !errors.Is(err, ErrNotDialled) {
+		t.Errorf("Conn() error = %v, want %v", err, ErrNotDialled)
+	}
+
+	if err := c.Healthcheck(context.Background()); !errors.Is(err, ErrNotDialled) {
+		t.Errorf("Healthcheck() error = %v, want %v", err, ErrNotDialled)
+	}
+
+	if got := c.State(); got != connectivity.Idle {
+		t.Errorf("State() = %s, want %s", got, connectivity.Idle)
+	}
+}
+
+func TestClientReconnects(t *testing.T) {
+	var (
+		s       = newTestServer(t, true)
+		lock    sync.Mutex
+		changes []StateChange
+	)
+
+	c := s.client(t, WithConnectTimeout(5*time.Second), WithStateHandler(func(ch StateChange) {
+		lock.Lock()
+		defer lock.Unlock()
+
+		changes = append(changes, ch)
+	}))
+
+	// Dial does not block by default, so it succeeds even though the server is not reachable yet.
+	if err := c.Dial(context.Background()); err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+
+	// Conn waits for the recovering connection instead of failing at once.
+	errc := make(chan error, 1)
+
+	go func() {
+		_, err := c.Conn()
+		errc <- err
+	}()
+
+	// Let a few connection attempts fail before the server is started.
+	deadline := time.Now().Add(5 * time.Second)
+	for atomic.LoadInt32(&s.dials) < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("client did not retry connecting")
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	s.start()
+
+	if err := <-errc; err != nil {
+		t.Fatalf("Conn() error = %v", err)
+	}
+
+	if got := c.State(); got != connectivity.Ready {
+		t.Errorf("State() = %s, want %s", got, connectivity.Ready)
+	}
+
+	// State changes are reported asynchronously, so wait for the last one.
+	for {
+		lock.Lock()
+		n := len(changes)
+		last := StateChange{}
+
+		if n > 0 {
+			last = changes[n-1]
+		}
+
+		lock.Unlock()
+
+		if last.To == connectivity.Ready {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			t.Fatalf("state changes = %v, want last change to %s", changes, connectivity.Ready)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+
+	var failed bool
+
+	for i, ch := range changes {
+		if i > 0 && ch.From != changes[i-1].To {
+			t.Errorf("state change %d is from %s, want %s", i, ch.From, changes[i-1].To)
+		}
+
+		failed = failed || ch.To == connectivity.TransientFailure
+	}
+
+	if !failed {
+		t.Errorf("state changes = %v, want a change to %s", changes, connectivity.TransientFailure)
+	}
+}
+
+func TestClientDialBlock(t *testing.T) {
+	s := newTestServer(t, true)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	if err := s.client(t, WithBlock()).Dial(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Dial() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if n := atomic.LoadInt32(&s.dials); n < 2 {
+		t.Errorf("Dial() made %d connection attempts, want retries with backoff", n)
+	}
+
+	s.start()
+
+	ctx, cancel = context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	c := s.client(t, WithBlock())
+	if err := c.Dial(ctx); err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+
+	if got := c.State(); got != connectivity.Ready {
+		t.Errorf("State() = %s, want %s", got, connectivity.Ready)
+	}
+}
+
+func TestClientConnTimeout(t *testing.T) {
+	c := newTestServer(t, true).client(t, WithConnectTimeout(100*time.Millisecond))
+	if err := c.Dial(context.Background()); err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+
+	if _, err := c.Conn(); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Conn() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestClientWithConn(t *testing.T) {
+	s := newTestServer(t, true)
+	s.start()
+
+	conn, err := grpc.Dial(testURL.Host, grpc.WithContextDialer(s.dial), grpc.WithInsecure())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	c := s.client(t, WithConn(conn))
+	if err := c.Dial(context.Background()); err != nil {
+		t.Fatalf("Dial() error = %v", err)
+	}
+
+	if err := c.Healthcheck(context.Background()); err != nil {
+		t.Fatalf("Healthcheck() error = %v", err)
+	}
+
+	// The connection is owned by the caller, so it is not closed with the client.
+	if err := c.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if got := conn.GetState(); got == connectivity.Shutdown {
+		t.Errorf("connection state = %s after closing the client", got)
+	}
+
+	// Once the connection is shut down, waiting for it fails at once.
+	_ = conn.Close()
+
+	if _, err := c.ConnContext(context.Background()); !errors.Is(err, ErrShutdown) {
+		t.Errorf("ConnContext() error = %v, want %v", err, ErrShutdown)
+	}
+}
@@ -0,0 +1,164 @@
+package grpc
+
+import (
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/keepalive"
+)
+
+// Default connection parameters.
+const (
+	DefaultMinConnectTimeout = 20 * time.Second
+	DefaultConnectTimeout    = 30 * time.Second
+)
+
+// Option configures a Client.
+type Option func(*options)
+
+type options struct {
+	tls       TLSConfig
+	conn      *grpc.ClientConn
+	dialOpts  []grpc.DialOption
+	unary     []grpc.UnaryClientInterceptor
+	stream    []grpc.StreamClientInterceptor
+	keepalive *keepalive.ClientParameters
+	userAgent string
+
+	block             bool
+	backoff           backoff.Config
+	minConnectTimeout time.Duration
+	connectTimeout    time.Duration
+	stateHandlers     []func(StateChange)
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		backoff:           backoff.DefaultConfig,
+		minConnectTimeout: DefaultMinConnectTimeout,
+		connectTimeout:    DefaultConnectTimeout,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// dialOptions returns the dial options configured by all options, apart from transport credentials.
+func (o options) dialOptions() []grpc.DialOption {
+	var res []grpc.DialOption
+
+	if len(o.unary) > 0 {
+		res = append(res, grpc.WithChainUnaryInterceptor(o.unary...))
+	}
+
+	if len(o.stream) > 0 {
+		res = append(res, grpc.WithChainStreamInterceptor(o.stream...))
+	}
+
+	if o.keepalive != nil {
+		res = append(res, grpc.WithKeepaliveParams(*o.keepalive))
+	}
+
+	if o.userAgent != "" {
+		res = append(res, grpc.WithUserAgent(o.userAgent))
+	}
+
+	// Custom dial options go last, so that they can override any of the above.
+	return append(res, o.dialOpts...)
+}
+
+// WithTLS sets the TLS configuration that is used for "grpc+tls" URLs,
+// e.g. a custom CA bundle, a client certificate for mutual TLS or pinned server public keys.
+func WithTLS(c TLSConfig) Option {
+	return func(o *options) {
+		o.tls = c
+	}
+}
+
+// WithDialOptions adds custom gRPC dial options, which are applied after all other options.
+func WithDialOptions(opts ...grpc.DialOption) Option {
+	return func(o *options) {
+		o.dialOpts = append(o.dialOpts, opts...)
+	}
+}
+
+// WithUnaryInterceptor adds unary client interceptors, which are chained in the order they are added.
+func WithUnaryInterceptor(i ...grpc.UnaryClientInterceptor) Option {
+	return func(o *options) {
+		o.unary = append(o.unary, i...)
+	}
+}
+
+// WithStreamInterceptor adds stream client interceptors, which are chained in the order they are added.
+func WithStreamInterceptor(i ...grpc.StreamClientInterceptor) Option {
+	return func(o *options) {
+		o.stream = append(o.stream, i...)
+	}
+}
+
+// WithKeepalive sets the keepalive parameters of the connection.
+func WithKeepalive(p keepalive.ClientParameters) Option {
+	return func(o *options) {
+		o.keepalive = &p
+	}
+}
+
+// WithUserAgent sets the user agent string, which is prepended to the default gRPC user agent.
+func WithUserAgent(ua string) Option {
+	return func(o *options) {
+		o.userAgent = ua
+	}
+}
+
+// WithConn makes the client use provided connection instead of dialling its own, e.g. a connection to bufconn in tests.
+// All other connection options are ignored in this case.
+// The connection is owned by the caller, so it is not closed when the client is closed.
+func WithConn(conn *grpc.ClientConn) Option {
+	return func(o *options) {
+		o.conn = conn
+	}
+}
+
+// WithBlock makes Dial block until the connection is established or the context passed to Dial is done.
+// Failed connection attempts are retried according to the backoff configuration until then.
+func WithBlock() Option {
+	return func(o *options) {
+		o.block = true
+	}
+}
+
+// WithBackoff sets the backoff configuration that is used between failed connection attempts.
+// Default is backoff.DefaultConfig.
+func WithBackoff(c backoff.Config) Option {
+	return func(o *options) {
+		o.backoff = c
+	}
+}
+
+// WithMinConnectTimeout sets the minimum time that a single connection attempt is given to complete.
+// Default is DefaultMinConnectTimeout.
+func WithMinConnectTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.minConnectTimeout = d
+	}
+}
+
+// WithConnectTimeout sets how long Conn waits for a connection that is not ready yet.
+// Default is DefaultConnectTimeout.
+func WithConnectTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.connectTimeout = d
+	}
+}
+
+// WithStateHandler adds a handler that is called on every connectivity state change of the connection.
+// Handlers are called sequentially from a single goroutine, so they should not block.
+func WithStateHandler(h func(StateChange)) Option {
+	return func(o *options) {
+		o.stateHandlers = append(o.stateHandlers, h)
+	}
+}
@@ -0,0 +1,174 @@
+package grpc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// TLS configuration errors.
+var (
+	ErrInvalidCABundle = errors.New("no valid certificates found in CA bundle")
+	ErrInvalidKeyPair  = errors.New("both client certificate and key must be provided")
+	ErrInvalidPin      = errors.New("invalid SPKI pin, must be a base64-encoded SHA-256 hash")
+	ErrPinMismatch     = errors.New("server certificate does not match any of pinned public keys")
+)
+
+const pinPrefix = "sha256/"
+
+// TLSConfig is the TLS configuration of a gRPC client.
+// Zero value is a valid configuration, which verifies the server using system certificate pool.
+type TLSConfig struct {
+	// CAFile is the path to a PEM bundle of CA certificates that are used for verifying the server.
+	// If empty, system certificate pool is used instead.
+	CAFile string
+
+	// CertFile and KeyFile are the paths to PEM-encoded client certificate and its private key,
+	// which are presented to the server for mutual TLS. Either both or neither must be set.
+	CertFile string
+	KeyFile  string
+
+	// ServerName overrides the name that is used for verifying the server certificate.
+	ServerName string
+
+	// PinnedSPKI is a list of base64-encoded SHA-256 hashes of Subject Public Key Info of trusted certificates,
+	// optionally prefixed with "sha256/". If not empty, at least one certificate of the verified chain
+	// must match one of the pins.
+	PinnedSPKI []string
+
+	// InsecureSkipVerify disables the verification of server certificate chain and name.
+	// Public key pinning is still enforced, if configured, but only the server certificate itself can match the pins,
+	// since the rest of the chain is not verified. This should only be used for development.
+	InsecureSkipVerify bool
+}
+
+// Build builds a tls.Config from c, loading all certificates from disk.
+func (c TLSConfig) Build() (*tls.Config, error) {
+	cfg := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		ServerName:         c.ServerName,
+		InsecureSkipVerify: c.InsecureSkipVerify, // nolint: gosec // It's an explicit opt-in for development.
+	}
+
+	if c.CAFile != "" {
+		pool, err := loadCABundle(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.RootCAs = pool
+	} else {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.RootCAs = pool
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		if c.CertFile == "" || c.KeyFile == "" {
+			return nil, ErrInvalidKeyPair
+		}
+
+		cert, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if len(c.PinnedSPKI) > 0 {
+		pins, err := parsePins(c.PinnedSPKI)
+		if err != nil {
+			return nil, err
+		}
+
+		cfg.VerifyPeerCertificate = verifyPins(pins)
+	}
+
+	return cfg, nil
+}
+
+func loadCABundle(path string) (*x509.CertPool, error) {
+	// nolint: gosec // It's expected that the end user of this package ensures the path is safe.
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidCABundle, path)
+	}
+
+	return pool, nil
+}
+
+func parsePins(pins []string) ([][]byte, error) {
+	res := make([][]byte, 0, len(pins))
+
+	for _, p := range pins {
+		h, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(strings.TrimSpace(p), pinPrefix))
+		if err != nil || len(h) != sha256.Size {
+			return nil, fmt.Errorf("%w: '%s'", ErrInvalidPin, p)
+		}
+
+		res = append(res, h)
+	}
+
+	return res, nil
+}
+
+// verifyPins returns a function that checks that the server presents a certificate matching one of pins.
+// If the chain has been verified, any certificate in it can match, e.g. the CA certificate.
+// Otherwise only the leaf certificate is known to belong to the server, since anyone can append certificates
+// to the chain they present, so it must match.
+func verifyPins(pins [][]byte) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, verifiedChains [][]*x509.Certificate) error {
+		if len(verifiedChains) == 0 {
+			if len(rawCerts) == 0 {
+				return ErrPinMismatch
+			}
+
+			cert, err := x509.ParseCertificate(rawCerts[0])
+			if err != nil {
+				return err
+			}
+
+			if matchesPin(cert, pins) {
+				return nil
+			}
+
+			return ErrPinMismatch
+		}
+
+		for _, chain := range verifiedChains {
+			for _, cert := range chain {
+				if matchesPin(cert, pins) {
+					return nil
+				}
+			}
+		}
+
+		return ErrPinMismatch
+	}
+}
+
+func matchesPin(cert *x509.Certificate, pins [][]byte) bool {
+	h := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	for _, p := range pins {
+		if bytes.Equal(h[:], p) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,313 @@
+package grpc
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type testCert struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	der  []byte
+}
+
+func newTestCert(t *testing.T, name string, parent *testCert, isCA bool) *testCert {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		DNSNames:     []string{"localhost"},
+	}
+
+	if isCA {
+		tmpl.IsCA = true
+		tmpl.BasicConstraintsValid = true
+		tmpl.KeyUsage |= x509.KeyUsageCertSign
+	}
+
+	signer, signerKey := tmpl, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return &testCert{cert: cert, key: key, der: der}
+}
+
+// pin returns the SPKI pin of c.
+func (c *testCert) pin() string {
+	h := sha256.Sum256(c.cert.RawSubjectPublicKeyInfo)
+	return pinPrefix + base64.StdEncoding.EncodeToString(h[:])
+}
+
+// write writes c and its key into PEM files in dir and returns their paths.
+func (c *testCert) write(t *testing.T, dir, name string) (certFile, keyFile string) {
+	t.Helper()
+
+	key, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile = filepath.Join(dir, name+".crt")
+	keyFile = filepath.Join(dir, name+".key")
+
+	writePEM(t, certFile, "CERTIFICATE", c.der)
+	writePEM(t, keyFile, "EC PRIVATE KEY", key)
+
+	return certFile, keyFile
+}
+
+func writePEM(t *testing.T, path, typ string, data []byte) {
+	t.Helper()
+
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: data}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// serveTLS starts a TLS server presenting chain, which writes a byte to every client that completes the handshake.
+// If clientCAs is not nil, the server requires clients to present a certificate signed by one of them.
+func serveTLS(t *testing.T, chain []*testCert, clientCAs *x509.CertPool) string {
+	t.Helper()
+
+	cert := tls.Certificate{PrivateKey: chain[0].key}
+	for _, c := range chain {
+		cert.Certificate = append(cert.Certificate, c.der)
+	}
+
+	cfg := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+
+	if clientCAs != nil {
+		cfg.ClientCAs = clientCAs
+		cfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = l.Close()
+	})
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+
+				if err := conn.(*tls.Conn).Handshake(); err == nil {
+					_, _ = conn.Write([]byte{1})
+				}
+			}()
+		}
+	}()
+
+	return l.Addr().String()
+}
+
+// dial connects to addr with cfg and waits for the byte written by the server,
+// so that the errors of the server verifying the client are reported as well.
+func dial(addr string, cfg *tls.Config) error {
+	conn, err := tls.DialWithDialer(&net.Dialer{Timeout: 5 * time.Second}, "tcp", addr, cfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := conn.SetDeadline(time.Now().Add(5 * time.Second)); err != nil {
+		return err
+	}
+
+	_, err = conn.Read(make([]byte, 1))
+
+	return err
+}
+
+func TestTLSConfig(t *testing.T) {
+	var (
+		dir    = t.TempDir()
+		ca     = newTestCert(t, "Test CA", nil, true)
+		server = newTestCert(t, "localhost", ca, false)
+		client = newTestCert(t, "client", ca, false)
+
+		// An attacker can present its own certificate with the real CA certificate appended to the chain.
+		attacker = newTestCert(t, "localhost", nil, false)
+
+		caFile, _                 = ca.write(t, dir, "ca")
+		clientCertFile, clientKey = client.write(t, dir, "client")
+		clientCAs                 = x509.NewCertPool()
+	)
+
+	clientCAs.AddCert(ca.cert)
+
+	var (
+		plain     = serveTLS(t, []*testCert{server, ca}, nil)
+		mutual    = serveTLS(t, []*testCert{server, ca}, clientCAs)
+		malicious = serveTLS(t, []*testCert{attacker, ca}, nil)
+	)
+
+	tests := []struct {
+		name    string
+		addr    string
+		cfg     TLSConfig
+		wantErr bool
+		errIs   error
+	}{
+		{
+			name:    "system pool does not trust test CA",
+			addr:    plain,
+			cfg:     TLSConfig{ServerName: "localhost"},
+			wantErr: true,
+		},
+		{
+			name: "custom CA",
+			addr: plain,
+			cfg:  TLSConfig{CAFile: caFile, ServerName: "localhost"},
+		},
+		{
+			name:    "custom CA rejects wrong server name",
+			addr:    plain,
+			cfg:     TLSConfig{CAFile: caFile, ServerName: "example.com"},
+			wantErr: true,
+		},
+		{
+			name: "mutual TLS",
+			addr: mutual,
+			cfg:  TLSConfig{CAFile: caFile, ServerName: "localhost", CertFile: clientCertFile, KeyFile: clientKey},
+		},
+		{
+			name:    "mutual TLS without client certificate",
+			addr:    mutual,
+			cfg:     TLSConfig{CAFile: caFile, ServerName: "localhost"},
+			wantErr: true,
+		},
+		{
+			name: "skip verify",
+			addr: plain,
+			cfg:  TLSConfig{ServerName: "localhost", InsecureSkipVerify: true},
+		},
+		{
+			name: "pinned server certificate",
+			addr: plain,
+			cfg:  TLSConfig{CAFile: caFile, ServerName: "localhost", PinnedSPKI: []string{server.pin()}},
+		},
+		{
+			name: "pinned CA certificate",
+			addr: plain,
+			cfg:  TLSConfig{CAFile: caFile, ServerName: "localhost", PinnedSPKI: []string{ca.pin()}},
+		},
+		{
+			name:    "pin mismatch",
+			addr:    plain,
+			cfg:     TLSConfig{CAFile: caFile, ServerName: "localhost", PinnedSPKI: []string{client.pin()}},
+			wantErr: true,
+			errIs:   ErrPinMismatch,
+		},
+		{
+			name: "skip verify with pinned server certificate",
+			addr: plain,
+			cfg:  TLSConfig{ServerName: "localhost", InsecureSkipVerify: true, PinnedSPKI: []string{server.pin()}},
+		},
+		{
+			name:    "skip verify does not trust appended pinned certificate",
+			addr:    malicious,
+			cfg:     TLSConfig{ServerName: "localhost", InsecureSkipVerify: true, PinnedSPKI: []string{ca.pin()}},
+			wantErr: true,
+			errIs:   ErrPinMismatch,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := tt.cfg.Build()
+			if err != nil {
+				t.Fatalf("Build() error = %v", err)
+			}
+
+			err = dial(tt.addr, cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("dial() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.errIs != nil && !errors.Is(err, tt.errIs) {
+				t.Errorf("dial() error = %v, want %v", err, tt.errIs)
+			}
+		})
+	}
+}
+
+func TestTLSConfigBuildErrors(t *testing.T) {
+	dir := t.TempDir()
+
+	invalidCA := filepath.Join(dir, "invalid.pem")
+	if err := os.WriteFile(invalidCA, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		cfg  TLSConfig
+		want error
+	}{
+		{name: "invalid CA bundle", cfg: TLSConfig{CAFile: invalidCA}, want: ErrInvalidCABundle},
+		{name: "certificate without key", cfg: TLSConfig{CertFile: "client.crt"}, want: ErrInvalidKeyPair},
+		{name: "key without certificate", cfg: TLSConfig{KeyFile: "client.key"}, want: ErrInvalidKeyPair},
+		{name: "invalid pin", cfg: TLSConfig{PinnedSPKI: []string{"sha256/invalid"}}, want: ErrInvalidPin},
+		{name: "short pin", cfg: TLSConfig{PinnedSPKI: []string{"c2hvcnQ="}}, want: ErrInvalidPin},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.cfg.Build(); !errors.Is(err, tt.want) {
+				t.Errorf("Build() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Level is the level of a log line.
+type Level int8
+
+// Supported log levels, from the most verbose to the least verbose.
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// ParseLevel parses a string representation of Level (e.g. "debug" or "info").
+func ParseLevel(s string) (Level, error) {
+	if strings.EqualFold(s, "warn") {
+		return LevelWarn, nil
+	}
+
+	for _, l := range []Level{LevelDebug, LevelInfo, LevelWarn, LevelError} {
+		if strings.EqualFold(s, l.String()) {
+			return l, nil
+		}
+	}
+
+	return LevelInfo, fmt.Errorf("unsupported log level '%s'", s)
+}
+
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warning"
+	case LevelError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// Format is the output format of log lines.
+type Format int8
+
+// Supported log formats.
+const (
+	FormatText Format = iota
+	FormatJSON
+)
+
+// ParseFormat parses a string representation of Format, which is either "text" or "json".
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "text":
+		return FormatText, nil
+	case "json":
+		return FormatJSON, nil
+	default:
+		return FormatText, fmt.Errorf("unsupported log format '%s'", s)
+	}
+}
+
+func (f Format) String() string {
+	switch f {
+	case FormatText:
+		return "text"
+	case FormatJSON:
+		return "json"
+	default:
+		return "unknown"
+	}
+}
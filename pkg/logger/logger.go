@@ -11,3 +11,24 @@ type Logger interface {
 	Error(...interface{})
 	Errorf(string, ...interface{})
 }
+
+// Fields is a set of key-value pairs that can be attached to log lines.
+type Fields map[string]interface{}
+
+// FieldLogger is a Logger that can attach fields to all lines it logs.
+type FieldLogger interface {
+	Logger
+
+	// WithFields returns a new Logger that attaches f, in addition to any existing fields, to all lines it logs.
+	WithFields(f Fields) Logger
+}
+
+// WithFields returns a Logger that attaches f to all lines logged by l, if l implements FieldLogger.
+// Otherwise, l is returned as is, so it is always safe to call WithFields regardless of the logger implementation.
+func WithFields(l Logger, f Fields) Logger {
+	if fl, ok := l.(FieldLogger); ok {
+		return fl.WithFields(f)
+	}
+
+	return l
+}
@@ -1,57 +1,205 @@
 package logger
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
 )
 
-// stdLogger is a simple logger that uses fmt.Fprintln / fmt.Fprintf to log messages.
+const (
+	keyTime  = "time"
+	keyLevel = "level"
+	keyMsg   = "msg"
+
+	// fieldPrefix is prepended to the names of fields that would clash with built-in keys.
+	fieldPrefix = "fields."
+)
+
+// stdLogger is a simple leveled logger that formats lines as either text or JSON.
+// Text lines are formatted as space-separated key=value pairs, e.g.:
+//
+// time=2021-10-07T10:30:00Z level=info msg="Started streaming" stream_id=7f0c44a6-1b5b-4e3c-a8f2-1e0c2b3f4d5e
+//
 // Destination can be specified by the caller.
+// Loggers derived using WithFields share the destination and are safe to use concurrently.
 type stdLogger struct {
-	dst io.Writer
+	dst    io.Writer
+	lock   *sync.Mutex
+	level  Level
+	format Format
+	fields Fields
 }
 
-// NewStderrLogger returns a new StdLogger that prints to STDERR.
+// NewStderrLogger returns a new StdLogger that prints text lines with level Info and above to STDERR.
 func NewStderrLogger() Logger {
 	return NewStdLogger(os.Stderr)
 }
 
-// NewStdLogger returns a new StdLogger that prints to dst.
+// NewStdLogger returns a new StdLogger that prints text lines with level Info and above to dst.
 func NewStdLogger(dst io.Writer) Logger {
+	return NewLeveledLogger(dst, LevelInfo, FormatText)
+}
+
+// NewLeveledLogger returns a new StdLogger that prints lines with specified level and above to dst,
+// formatted using specified format.
+func NewLeveledLogger(dst io.Writer, level Level, format Format) FieldLogger {
+	return &stdLogger{
+		dst:    dst,
+		lock:   &sync.Mutex{},
+		level:  level,
+		format: format,
+	}
+}
+
+func (l *stdLogger) WithFields(f Fields) Logger {
+	fields := make(Fields, len(l.fields)+len(f))
+	for k, v := range l.fields {
+		fields[k] = v
+	}
+
+	for k, v := range f {
+		fields[k] = v
+	}
+
 	return &stdLogger{
-		dst: dst,
+		dst:    l.dst,
+		lock:   l.lock,
+		level:  l.level,
+		format: l.format,
+		fields: fields,
 	}
 }
 
 func (l *stdLogger) Debug(v ...interface{}) {
-	fmt.Fprintln(l.dst, v...)
+	l.log(LevelDebug, sprint(v...))
 }
 
 func (l *stdLogger) Debugf(f string, v ...interface{}) {
-	fmt.Fprintf(l.dst, f+"\n", v...)
+	l.log(LevelDebug, fmt.Sprintf(f, v...))
 }
 
 func (l *stdLogger) Info(v ...interface{}) {
-	fmt.Fprintln(l.dst, v...)
+	l.log(LevelInfo, sprint(v...))
 }
 
 func (l *stdLogger) Infof(f string, v ...interface{}) {
-	fmt.Fprintf(l.dst, f+"\n", v...)
+	l.log(LevelInfo, fmt.Sprintf(f, v...))
 }
 
 func (l *stdLogger) Warn(v ...interface{}) {
-	fmt.Fprintln(l.dst, v...)
+	l.log(LevelWarn, sprint(v...))
 }
 
 func (l *stdLogger) Warnf(f string, v ...interface{}) {
-	fmt.Fprintf(l.dst, f+"\n", v...)
+	l.log(LevelWarn, fmt.Sprintf(f, v...))
 }
 
 func (l *stdLogger) Error(v ...interface{}) {
-	fmt.Fprintln(l.dst, v...)
+	l.log(LevelError, sprint(v...))
 }
 
 func (l *stdLogger) Errorf(f string, v ...interface{}) {
-	fmt.Fprintf(l.dst, f+"\n", v...)
+	l.log(LevelError, fmt.Sprintf(f, v...))
+}
+
+func (l *stdLogger) log(level Level, msg string) {
+	if level < l.level {
+		return
+	}
+
+	var (
+		buf = bytes.Buffer{}
+		now = time.Now().Format(time.RFC3339)
+	)
+
+	if l.format == FormatJSON {
+		l.formatJSON(&buf, now, level, msg)
+	} else {
+		l.formatText(&buf, now, level, msg)
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	// There is nowhere to report the failure to write a log line to, so the error is ignored.
+	_, _ = buf.WriteTo(l.dst)
+}
+
+func (l *stdLogger) formatText(buf *bytes.Buffer, now string, level Level, msg string) {
+	fmt.Fprintf(buf, "%s=%s %s=%s %s=%s", keyTime, now, keyLevel, level, keyMsg, quote(msg))
+
+	for _, k := range l.sortedKeys() {
+		fmt.Fprintf(buf, " %s=%s", fieldKey(k), quote(fmt.Sprint(fieldValue(l.fields[k]))))
+	}
+
+	buf.WriteByte('\n')
+}
+
+func (l *stdLogger) formatJSON(buf *bytes.Buffer, now string, level Level, msg string) {
+	m := make(map[string]interface{}, len(l.fields)+3)
+	for k, v := range l.fields {
+		m[fieldKey(k)] = fieldValue(v)
+	}
+
+	m[keyTime] = now
+	m[keyLevel] = level.String()
+	m[keyMsg] = msg
+
+	if err := json.NewEncoder(buf).Encode(m); err != nil {
+		// Some field values cannot be marshalled, so fall back to their string representations.
+		buf.Reset()
+
+		for k, v := range m {
+			m[k] = fmt.Sprint(v)
+		}
+
+		_ = json.NewEncoder(buf).Encode(m)
+	}
+}
+
+func (l *stdLogger) sortedKeys() []string {
+	keys := make([]string, 0, len(l.fields))
+	for k := range l.fields {
+		keys = append(keys, k)
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func fieldKey(k string) string {
+	switch k {
+	case keyTime, keyLevel, keyMsg:
+		return fieldPrefix + k
+	default:
+		return k
+	}
+}
+
+func fieldValue(v interface{}) interface{} {
+	if err, ok := v.(error); ok {
+		return err.Error()
+	}
+
+	return v
+}
+
+func sprint(v ...interface{}) string {
+	return strings.TrimSuffix(fmt.Sprintln(v...), "\n")
+}
+
+func quote(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+
+	return s
 }
@@ -0,0 +1,221 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// timeRe matches the time key of text lines.
+var timeRe = regexp.MustCompile(`^time=(\S+) `)
+
+// textLines returns lines logged in text format with their times removed, checking that the times are valid.
+func textLines(t *testing.T, buf *bytes.Buffer) []string {
+	t.Helper()
+
+	var lines []string
+
+	for _, l := range strings.SplitAfter(buf.String(), "\n") {
+		if l == "" {
+			continue
+		}
+
+		m := timeRe.FindStringSubmatch(l)
+		if m == nil {
+			t.Fatalf("line %q does not start with time", l)
+		}
+
+		if _, err := time.Parse(time.RFC3339, m[1]); err != nil {
+			t.Errorf("line %q has invalid time: %v", l, err)
+		}
+
+		lines = append(lines, strings.TrimPrefix(l, m[0]))
+	}
+
+	return lines
+}
+
+func TestStdLoggerLevel(t *testing.T) {
+	tests := []struct {
+		level Level
+		want  []string
+	}{
+		{
+			level: LevelDebug,
+			want: []string{
+				"level=debug msg=d\n",
+				"level=info msg=i\n",
+				"level=warning msg=w\n",
+				"level=error msg=e\n",
+			},
+		},
+		{level: LevelInfo, want: []string{"level=info msg=i\n", "level=warning msg=w\n", "level=error msg=e\n"}},
+		{level: LevelWarn, want: []string{"level=warning msg=w\n", "level=error msg=e\n"}},
+		{level: LevelError, want: []string{"level=error msg=e\n"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.level.String(), func(t *testing.T) {
+			var (
+				buf bytes.Buffer
+				l   = NewLeveledLogger(&buf, tt.level, FormatText)
+			)
+
+			l.Debug("d")
+			l.Info("i")
+			l.Warn("w")
+			l.Error("e")
+
+			got := textLines(t, &buf)
+			if strings.Join(got, "") != strings.Join(tt.want, "") {
+				t.Errorf("logged lines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStdLoggerText(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields Fields
+		log    func(Logger)
+		want   string
+	}{
+		{
+			name: "arguments are separated by spaces",
+			log:  func(l Logger) { l.Info("Started", "streaming", 2) },
+			want: "level=info msg=\"Started streaming 2\"\n",
+		},
+		{
+			name: "format",
+			log:  func(l Logger) { l.Warnf("retrying in %ds", 5) },
+			want: "level=warning msg=\"retrying in 5s\"\n",
+		},
+		{
+			name: "special characters are escaped",
+			log:  func(l Logger) { l.Error("a=\"b\"\nc") },
+			want: `level=error msg="a=\"b\"\nc"` + "\n",
+		},
+		{
+			name: "empty message is quoted",
+			log:  func(l Logger) { l.Info("") },
+			want: "level=info msg=\"\"\n",
+		},
+		{
+			name:   "fields are sorted and escaped",
+			fields: Fields{"stream_id": "abc", "error": errors.New("bad thing"), "attempt": 3},
+			log:    func(l Logger) { l.Info("failed") },
+			want:   "level=info msg=failed attempt=3 error=\"bad thing\" stream_id=abc\n",
+		},
+		{
+			name:   "fields do not overwrite built-in keys",
+			fields: Fields{"msg": "field", "level": "field", "time": "field"},
+			log:    func(l Logger) { l.Info("message") },
+			want:   "level=info msg=message fields.level=field fields.msg=field fields.time=field\n",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+
+			tt.log(WithFields(NewLeveledLogger(&buf, LevelInfo, FormatText), tt.fields))
+
+			if got := textLines(t, &buf); len(got) != 1 || got[0] != tt.want {
+				t.Errorf("logged lines = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStdLoggerJSON(t *testing.T) {
+	var (
+		buf bytes.Buffer
+		l   = WithFields(NewLeveledLogger(&buf, LevelDebug, FormatJSON), Fields{
+			"stream_id": "abc",
+			"attempt":   3,
+			"error":     errors.New("bad thing"),
+			"msg":       "field",
+			"level":     1,
+			"time":      "never",
+		})
+	)
+
+	l.Debugf("line %q", "one")
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("logged line %q is not valid JSON: %v", buf.String(), err)
+	}
+
+	if _, err := time.Parse(time.RFC3339, got["time"].(string)); err != nil {
+		t.Errorf("logged line has invalid time: %v", err)
+	}
+
+	delete(got, "time")
+
+	want := map[string]interface{}{
+		"level":        "debug",
+		"msg":          `line "one"`,
+		"stream_id":    "abc",
+		"attempt":      float64(3),
+		"error":        "bad thing",
+		"fields.msg":   "field",
+		"fields.level": float64(1),
+		"fields.time":  "never",
+	}
+
+	if len(got) != len(want) {
+		t.Errorf("logged keys = %v, want %v", got, want)
+	}
+
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("logged %s = %v, want %v", k, got[k], v)
+		}
+	}
+}
+
+func TestStdLoggerJSONUnsupportedValue(t *testing.T) {
+	var buf bytes.Buffer
+
+	WithFields(NewLeveledLogger(&buf, LevelInfo, FormatJSON), Fields{"ch": make(chan int)}).Info("hello")
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("logged line %q is not valid JSON: %v", buf.String(), err)
+	}
+
+	if got["msg"] != "hello" {
+		t.Errorf("logged msg = %v, want hello", got["msg"])
+	}
+
+	if s, ok := got["ch"].(string); !ok || !strings.HasPrefix(s, "0x") {
+		t.Errorf("logged ch = %v, want the string representation of the channel", got["ch"])
+	}
+}
+
+func TestWithFieldsDoesNotModifyParent(t *testing.T) {
+	var (
+		buf    bytes.Buffer
+		parent = NewLeveledLogger(&buf, LevelInfo, FormatText)
+	)
+
+	child := WithFields(WithFields(parent, Fields{"a": 1}), Fields{"b": 2})
+
+	child.Info("child")
+	parent.Info("parent")
+
+	want := []string{"level=info msg=child a=1 b=2\n", "level=info msg=parent\n"}
+	if got := textLines(t, &buf); strings.Join(got, "") != strings.Join(want, "") {
+		t.Errorf("logged lines = %q, want %q", got, want)
+	}
+}
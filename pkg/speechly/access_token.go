@@ -2,6 +2,7 @@ package speechly
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	"github.com/dgrijalva/jwt-go"
@@ -14,9 +15,15 @@ var ErrTokenExpired = errors.New("JWT token has expired")
 
 // AccessToken is a JWT token used for accessing Speechly public APIs.
 // It can be obtained using Speechly Identity service.
+// Besides standard JWT claims, it contains Speechly-specific claims about the app and the device it was issued for.
 type AccessToken struct {
 	jwt.StandardClaims
-	rawToken string
+	AppID     string `json:"appId,omitempty"`
+	ProjectID string `json:"projectId,omitempty"`
+	DeviceID  string `json:"deviceId,omitempty"`
+	ConfigID  string `json:"configId,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	rawToken  string
 }
 
 // Parse parses the string representation of token into AccessToken.
@@ -36,6 +43,47 @@ func (a *AccessToken) Parse(s string) error {
 	return nil
 }
 
+// ParseVerified parses the string representation of token into AccessToken and verifies it with v.
+// Unlike Parse, it checks the signature of the token and, if v requires it, its issuer and audience.
+// If the token is invalid, one of token verification errors is returned.
+// The token is only stored in a if it is valid.
+func (a *AccessToken) ParseVerified(s string, v *Verifier) error {
+	var t AccessToken
+
+	if _, err := jwtParser.ParseWithClaims(s, &t, v.key); err != nil {
+		return verificationError(err)
+	}
+
+	// Claims validation of the parser does not require expiration time.
+	if !t.VerifyExpiresAt(time.Now().Unix(), true) {
+		return ErrTokenExpired
+	}
+
+	if err := v.verifyClaims(&t); err != nil {
+		return err
+	}
+
+	t.rawToken = s
+	*a = t
+
+	return nil
+}
+
+// Scopes returns the list of scopes that the token grants access to.
+func (a AccessToken) Scopes() []string {
+	return strings.Fields(a.Scope)
+}
+
+// IssuedAtTime returns the time when the token was issued.
+func (a AccessToken) IssuedAtTime() time.Time {
+	return time.Unix(a.IssuedAt, 0)
+}
+
+// ExpiresAtTime returns the time when the token expires.
+func (a AccessToken) ExpiresAtTime() time.Time {
+	return time.Unix(a.ExpiresAt, 0)
+}
+
 func (a AccessToken) String() string {
 	return a.rawToken
 }
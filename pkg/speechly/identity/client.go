@@ -2,13 +2,15 @@ package identity
 
 import (
 	"context"
+	"io"
 	"net/url"
 
 	"github.com/google/uuid"
 	identityv1 "github.com/speechly/api/go/speechly/identity/v1"
 	"google.golang.org/grpc"
 
-	pgrpc "github.com/speechly/slu-client/internal/grpc"
+	pgrpc "github.com/speechly/slu-client/pkg/grpc"
+	"github.com/speechly/slu-client/pkg/logger"
 	"github.com/speechly/slu-client/pkg/speechly"
 )
 
@@ -18,8 +20,12 @@ type Client struct {
 }
 
 // NewClient returns a new Client configured to use provided URL.
-func NewClient(u url.URL) (*Client, error) {
-	c, err := pgrpc.NewClient("speechly.identity.v1", u)
+func NewClient(u url.URL, opts ...pgrpc.Option) (*Client, error) {
+	return newClient(u, logger.NewStdLogger(io.Discard), opts...)
+}
+
+func newClient(u url.URL, log logger.Logger, opts ...pgrpc.Option) (*Client, error) {
+	c, err := pgrpc.NewClient("speechly.identity.v1", u, log, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -31,7 +37,7 @@ func NewClient(u url.URL) (*Client, error) {
 // It will parse returned token into speechly.AccessToken and return it or any error if it happens.
 // nolint: interfacer // linter wants to pass a Stringer instead of UUID, which defeats the purpose of type safety.
 func (c *Client) Login(ctx context.Context, appID, deviceID uuid.UUID) (t speechly.AccessToken, err error) {
-	conn, err := c.Conn()
+	conn, err := c.ConnContext(ctx)
 	if err != nil {
 		return t, err
 	}
@@ -6,15 +6,16 @@ import (
 
 	"github.com/google/uuid"
 
+	pgrpc "github.com/speechly/slu-client/pkg/grpc"
 	"github.com/speechly/slu-client/pkg/logger"
 	"github.com/speechly/slu-client/pkg/speechly"
 )
 
 // GetAccessToken is a convenience wrapper that instantiates a new identity client and calls Login on it.
 func GetAccessToken(
-	ctx context.Context, u url.URL, appID, deviceID uuid.UUID, log logger.Logger,
+	ctx context.Context, u url.URL, appID, deviceID uuid.UUID, log logger.Logger, opts ...pgrpc.Option,
 ) (t speechly.AccessToken, err error) {
-	cli, err := NewClient(u)
+	cli, err := newClient(u, log, opts...)
 	if err != nil {
 		return t, err
 	}
@@ -0,0 +1,278 @@
+package sal
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/speechly/slu-client/pkg/speechly/slu"
+)
+
+// Parse parses an annotated utterance into a list of finalised SLU segments.
+// Segment IDs are assigned in the order of appearance, starting from zero.
+func Parse(s string) ([]slu.Segment, error) {
+	p := parser{src: []rune(s)}
+	return p.parse()
+}
+
+type parser struct {
+	src  []rune
+	pos  int
+	word int32
+}
+
+func (p *parser) parse() ([]slu.Segment, error) {
+	var segs []slu.Segment
+
+	p.skipSpace()
+	if p.eof() {
+		return nil, ErrEmptyUtterance
+	}
+
+	for !p.eof() {
+		seg, err := p.segment(int32(len(segs)))
+		if err != nil {
+			return nil, err
+		}
+
+		segs = append(segs, seg)
+	}
+
+	return segs, nil
+}
+
+func (p *parser) segment(id int32) (slu.Segment, error) {
+	seg := slu.NewSegment(id)
+
+	if p.peek() != intentMark {
+		return seg, p.errorf(ErrMissingIntent)
+	}
+	p.pos++
+
+	intent, err := p.literal()
+	if err != nil {
+		return seg, err
+	}
+
+	if intent == "" {
+		return seg, p.errorf(ErrEmptyIntent)
+	}
+
+	if err := p.delimiter(); err != nil {
+		return seg, err
+	}
+
+	for p.skipSpace(); !p.eof() && p.peek() != intentMark; p.skipSpace() {
+		if p.peek() == entityStart {
+			err = p.entity(&seg)
+		} else {
+			err = p.transcript(&seg)
+		}
+
+		if err != nil {
+			return seg, err
+		}
+
+		if err := p.delimiter(); err != nil {
+			return seg, err
+		}
+	}
+
+	if len(seg.Transcripts) == 0 {
+		return seg, p.errorf(ErrEmptySegment)
+	}
+
+	seg.Intent = slu.Intent{Value: intent, IsFinalised: true}
+	seg.IsFinalised = true
+
+	return seg, nil
+}
+
+func (p *parser) transcript(seg *slu.Segment) error {
+	w, err := p.literal()
+	if err != nil {
+		return err
+	}
+
+	if w == "" {
+		return p.errorf(ErrUnexpectedChar)
+	}
+
+	seg.Transcripts[p.word] = slu.Transcript{
+		Word:        w,
+		Index:       p.word,
+		IsFinalised: true,
+	}
+	p.word++
+
+	return nil
+}
+
+func (p *parser) entity(seg *slu.Segment) error {
+	p.pos++ // Skip entityStart.
+
+	var (
+		start = p.word
+		words []string
+		value string
+	)
+
+	for p.skipSpace(); p.peek() != entityEnd && p.peek() != valueMark; p.skipSpace() {
+		if p.eof() {
+			return p.errorf(ErrUnexpectedEOF)
+		}
+
+		if err := p.transcript(seg); err != nil {
+			return err
+		}
+
+		words = append(words, seg.Transcripts[p.word-1].Word)
+	}
+
+	if len(words) == 0 {
+		return p.errorf(ErrEmptyEntity)
+	}
+
+	value = strings.Join(words, string(wordDelim))
+
+	if p.peek() == valueMark {
+		p.pos++
+
+		v, err := p.value()
+		if err != nil {
+			return err
+		}
+
+		value = v
+	}
+
+	p.pos++ // Skip entityEnd.
+
+	if p.peek() != typeStart {
+		return p.unexpected()
+	}
+	p.pos++
+
+	typ, err := p.literal()
+	if err != nil {
+		return err
+	}
+
+	if typ == "" {
+		return p.errorf(ErrEmptyType)
+	}
+
+	if p.peek() != typeEnd {
+		return p.unexpected()
+	}
+	p.pos++
+
+	seg.Entities[slu.EntityIndex{StartIndex: start, EndIndex: p.word}] = slu.Entity{
+		Type:        typ,
+		Value:       value,
+		StartIndex:  start,
+		EndIndex:    p.word,
+		IsFinalised: true,
+	}
+
+	return nil
+}
+
+// literal reads a sequence of non-special, non-space characters, resolving escapes.
+func (p *parser) literal() (string, error) {
+	b := strings.Builder{}
+
+	for !p.eof() {
+		r := p.peek()
+		if unicode.IsSpace(r) || (isSpecial(r) && r != escapeMark) {
+			break
+		}
+
+		r, err := p.next()
+		if err != nil {
+			return "", err
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String(), nil
+}
+
+// value reads an entity value up to the closing bracket, preserving whitespace and resolving escapes.
+func (p *parser) value() (string, error) {
+	b := strings.Builder{}
+
+	for p.peek() != entityEnd {
+		if p.eof() {
+			return "", p.errorf(ErrUnexpectedEOF)
+		}
+
+		r, err := p.next()
+		if err != nil {
+			return "", err
+		}
+
+		b.WriteRune(r)
+	}
+
+	return b.String(), nil
+}
+
+// next returns the next character, resolving escapes.
+func (p *parser) next() (rune, error) {
+	r := p.src[p.pos]
+	p.pos++
+
+	if r != escapeMark {
+		return r, nil
+	}
+
+	if p.eof() {
+		return 0, p.errorf(ErrUnexpectedEOF)
+	}
+
+	r = p.src[p.pos]
+	p.pos++
+
+	return r, nil
+}
+
+// delimiter makes sure that a token is followed by whitespace or the end of input.
+func (p *parser) delimiter() error {
+	if p.eof() || unicode.IsSpace(p.peek()) {
+		return nil
+	}
+
+	return p.unexpected()
+}
+
+func (p *parser) skipSpace() {
+	for !p.eof() && unicode.IsSpace(p.peek()) {
+		p.pos++
+	}
+}
+
+func (p *parser) peek() rune {
+	if p.eof() {
+		return 0
+	}
+
+	return p.src[p.pos]
+}
+
+func (p *parser) eof() bool {
+	return p.pos >= len(p.src)
+}
+
+func (p *parser) unexpected() error {
+	if p.eof() {
+		return p.errorf(ErrUnexpectedEOF)
+	}
+
+	return fmt.Errorf("%w %q at position %d", ErrUnexpectedChar, p.peek(), p.pos)
+}
+
+func (p *parser) errorf(err error) error {
+	return fmt.Errorf("%w at position %d", err, p.pos)
+}
@@ -0,0 +1,182 @@
+package sal
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/speechly/slu-client/pkg/speechly/slu"
+)
+
+// segment returns a finalised segment with intent and words, indexed starting from first.
+func segment(id int32, intent string, first int32, words ...string) slu.Segment {
+	s := slu.NewSegment(id)
+	s.IsFinalised = true
+	s.Intent = slu.Intent{Value: intent, IsFinalised: true}
+
+	for i, w := range words {
+		idx := first + int32(i)
+		s.Transcripts[idx] = slu.Transcript{Word: w, Index: idx, IsFinalised: true}
+	}
+
+	return s
+}
+
+// withEntity adds a finalised entity spanning words [start, end) to s.
+func withEntity(s slu.Segment, typ, value string, start, end int32) slu.Segment {
+	s.Entities[slu.EntityIndex{StartIndex: start, EndIndex: end}] = slu.Entity{
+		Type:        typ,
+		Value:       value,
+		StartIndex:  start,
+		EndIndex:    end,
+		IsFinalised: true,
+	}
+
+	return s
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []slu.Segment
+	}{
+		{
+			name: "intent only words",
+			in:   "*greet hello there",
+			want: []slu.Segment{segment(0, "greet", 0, "hello", "there")},
+		},
+		{
+			name: "entity",
+			in:   "*turn_off turn off the [kitchen](room) lights",
+			want: []slu.Segment{
+				withEntity(segment(0, "turn_off", 0, "turn", "off", "the", "kitchen", "lights"), "room", "kitchen", 3, 4),
+			},
+		},
+		{
+			name: "multi-word entity with value",
+			in:   "*book fly to [new york|NYC](city)",
+			want: []slu.Segment{
+				withEntity(segment(0, "book", 0, "fly", "to", "new", "york"), "city", "NYC", 2, 4),
+			},
+		},
+		{
+			name: "adjacent entities",
+			in:   "*set [red](colour) [lamp](device)",
+			want: []slu.Segment{
+				withEntity(withEntity(segment(0, "set", 0, "red", "lamp"), "colour", "red", 0, 1), "device", "lamp", 1, 2),
+			},
+		},
+		{
+			name: "multiple segments continue word indices",
+			in:   "*turn_off turn off the [kitchen](room) lights *turn_on turn on the [radio](device)",
+			want: []slu.Segment{
+				withEntity(segment(0, "turn_off", 0, "turn", "off", "the", "kitchen", "lights"), "room", "kitchen", 3, 4),
+				withEntity(segment(1, "turn_on", 5, "turn", "on", "the", "radio"), "device", "radio", 8, 9),
+			},
+		},
+		{
+			name: "escaped brackets",
+			in:   `*say print \[x\] and \(y\)`,
+			want: []slu.Segment{segment(0, "say", 0, "print", "[x]", "and", "(y)")},
+		},
+		{
+			name: "escaped special characters in entity",
+			in:   `*say [a\|b](pipe\*type)`,
+			want: []slu.Segment{withEntity(segment(0, "say", 0, "a|b"), "pipe*type", "a|b", 0, 1)},
+		},
+		{
+			name: "escaped whitespace",
+			in:   `*say hello\ world \\`,
+			want: []slu.Segment{segment(0, "say", 0, "hello world", `\`)},
+		},
+		{
+			name: "value preserves whitespace and escapes",
+			in:   `*say [x|foo \] bar](t)`,
+			want: []slu.Segment{withEntity(segment(0, "say", 0, "x"), "t", "foo ] bar", 0, 1)},
+		},
+		{
+			name: "empty value",
+			in:   "*say [x|](t)",
+			want: []slu.Segment{withEntity(segment(0, "say", 0, "x"), "t", "", 0, 1)},
+		},
+		{
+			name: "extra whitespace",
+			in:   " \t*greet   hello\n[ big  world ](place)  ",
+			want: []slu.Segment{withEntity(segment(0, "greet", 0, "hello", "big", "world"), "place", "big world", 1, 3)},
+		},
+		{
+			name: "unicode",
+			in:   "*tilaa tilaa [kahvia](juoma) ☕ *注文 [コーヒー](飲み物)",
+			want: []slu.Segment{
+				withEntity(segment(0, "tilaa", 0, "tilaa", "kahvia", "☕"), "juoma", "kahvia", 1, 2),
+				withEntity(segment(1, "注文", 3, "コーヒー"), "飲み物", "コーヒー", 3, 4),
+			},
+		},
+		{
+			name: "unicode whitespace",
+			in:   "*greet hello　world again",
+			want: []slu.Segment{segment(0, "greet", 0, "hello", "world", "again")},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if err != nil {
+				t.Fatalf("Parse(%q) error = %v", tt.in, err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want error
+	}{
+		{name: "empty", in: "", want: ErrEmptyUtterance},
+		{name: "whitespace", in: " \t\n", want: ErrEmptyUtterance},
+		{name: "missing intent", in: "turn off", want: ErrMissingIntent},
+		{name: "empty intent", in: "* turn off", want: ErrEmptyIntent},
+		{name: "segment without words", in: "*turn_off", want: ErrEmptySegment},
+		{name: "first of multiple segments without words", in: "*turn_off *turn_on lights", want: ErrEmptySegment},
+		{name: "entity without words", in: "*a [](t)", want: ErrEmptyEntity},
+		{name: "entity with only value", in: "*a [|v](t)", want: ErrEmptyEntity},
+		{name: "empty entity type", in: "*a [x]()", want: ErrEmptyType},
+		{name: "nested entities", in: "*a [b [c](t)](u)", want: ErrUnexpectedChar},
+		{name: "unclosed entity", in: "*a [x", want: ErrUnexpectedEOF},
+		{name: "unclosed entity value", in: "*a [x|v", want: ErrUnexpectedEOF},
+		{name: "missing entity type", in: "*a [x]", want: ErrUnexpectedEOF},
+		{name: "unclosed entity type", in: "*a [x](t", want: ErrUnexpectedEOF},
+		{name: "space before entity type", in: "*a [x] (t)", want: ErrUnexpectedChar},
+		{name: "unopened entity", in: "*a x](t)", want: ErrUnexpectedChar},
+		{name: "stray closing parenthesis", in: "*a x)", want: ErrUnexpectedChar},
+		{name: "stray value mark", in: "*a x | y", want: ErrUnexpectedChar},
+		{name: "word glued to entity", in: "*a [x](t)y", want: ErrUnexpectedChar},
+		{name: "intent glued to entity", in: "*a[x](t)", want: ErrUnexpectedChar},
+		{name: "trailing escape", in: `*a x\`, want: ErrUnexpectedEOF},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.in)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Parse(%q) error = %v, want %v", tt.in, err, tt.want)
+			}
+
+			if got != nil {
+				t.Errorf("Parse(%q) = %+v, want nil", tt.in, got)
+			}
+		})
+	}
+}
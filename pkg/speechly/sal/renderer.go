@@ -0,0 +1,147 @@
+package sal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/speechly/slu-client/pkg/speechly/slu"
+)
+
+// Render renders segments into an annotated utterance.
+// Segments are rendered in the order they are provided, words and entities are ordered by their indices.
+// Entity values are only rendered explicitly when they differ from the words that the entity spans,
+// so that Parse(Render(s)) results in the same segments as s.
+func Render(segs []slu.Segment) (string, error) {
+	b := strings.Builder{}
+
+	for i, s := range segs {
+		if i > 0 {
+			b.WriteRune(wordDelim)
+		}
+
+		if err := renderSegment(&b, s); err != nil {
+			return "", fmt.Errorf("segment %d: %w", s.ID, err)
+		}
+	}
+
+	return b.String(), nil
+}
+
+func renderSegment(b *strings.Builder, s slu.Segment) error {
+	if s.Intent.Value == "" {
+		return ErrEmptyIntent
+	}
+
+	if len(s.Transcripts) == 0 {
+		return ErrEmptySegment
+	}
+
+	var (
+		words    = sortedTranscripts(s.Transcripts)
+		entities = slu.SortedEntityIndexList(s.Entities)
+	)
+
+	b.WriteRune(intentMark)
+	writeEscaped(b, s.Intent.Value, true)
+
+	for i := 0; i < len(words); {
+		if words[i].Word == "" {
+			return ErrEmptyWord
+		}
+
+		b.WriteRune(wordDelim)
+
+		if len(entities) == 0 || entities[0].StartIndex != words[i].Index {
+			writeEscaped(b, words[i].Word, true)
+			i++
+
+			continue
+		}
+
+		n, err := renderEntity(b, s.Entities[entities[0]], words[i:])
+		if err != nil {
+			return err
+		}
+
+		entities = entities[1:]
+		i += n
+	}
+
+	if len(entities) > 0 {
+		return ErrInvalidEntity
+	}
+
+	return nil
+}
+
+// renderEntity renders entity e which starts at the first of provided words and returns the number of words it spans.
+func renderEntity(b *strings.Builder, e slu.Entity, words []slu.Transcript) (int, error) {
+	if e.Type == "" {
+		return 0, ErrEmptyType
+	}
+
+	n := int(e.EndIndex - e.StartIndex)
+	if n < 1 || n > len(words) {
+		return 0, ErrInvalidEntity
+	}
+
+	spanned := make([]string, 0, n)
+	for i, w := range words[:n] {
+		if w.Index != e.StartIndex+int32(i) {
+			return 0, ErrInvalidEntity
+		}
+
+		if w.Word == "" {
+			return 0, ErrEmptyWord
+		}
+
+		spanned = append(spanned, w.Word)
+	}
+
+	b.WriteRune(entityStart)
+
+	for i, w := range spanned {
+		if i > 0 {
+			b.WriteRune(wordDelim)
+		}
+
+		writeEscaped(b, w, true)
+	}
+
+	if e.Value != strings.Join(spanned, string(wordDelim)) {
+		b.WriteRune(valueMark)
+		writeEscaped(b, e.Value, false)
+	}
+
+	b.WriteRune(entityEnd)
+	b.WriteRune(typeStart)
+	writeEscaped(b, e.Type, true)
+	b.WriteRune(typeEnd)
+
+	return n, nil
+}
+
+func writeEscaped(b *strings.Builder, s string, escapeSpace bool) {
+	for _, r := range s {
+		if isSpecial(r) || (escapeSpace && unicode.IsSpace(r)) {
+			b.WriteRune(escapeMark)
+		}
+
+		b.WriteRune(r)
+	}
+}
+
+func sortedTranscripts(t slu.Transcripts) []slu.Transcript {
+	r := make([]slu.Transcript, 0, len(t))
+	for _, v := range t {
+		r = append(r, v)
+	}
+
+	sort.Slice(r, func(i, j int) bool {
+		return r[i].Index < r[j].Index
+	})
+
+	return r
+}
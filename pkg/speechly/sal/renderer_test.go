@@ -0,0 +1,167 @@
+package sal
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/speechly/slu-client/pkg/speechly/slu"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []slu.Segment
+		want string
+	}{
+		{
+			name: "words only",
+			in:   []slu.Segment{segment(0, "greet", 0, "hello", "there")},
+			want: "*greet hello there",
+		},
+		{
+			name: "multiple segments",
+			in: []slu.Segment{
+				withEntity(segment(0, "turn_off", 0, "turn", "off", "the", "kitchen", "lights"), "room", "kitchen", 3, 4),
+				withEntity(segment(1, "turn_on", 5, "turn", "on", "the", "radio"), "device", "radio", 8, 9),
+			},
+			want: "*turn_off turn off the [kitchen](room) lights *turn_on turn on the [radio](device)",
+		},
+		{
+			name: "entity value equal to words is implicit",
+			in:   []slu.Segment{withEntity(segment(0, "book", 0, "fly", "to", "new", "york"), "city", "new york", 2, 4)},
+			want: "*book fly to [new york](city)",
+		},
+		{
+			name: "entity value different from words is explicit",
+			in:   []slu.Segment{withEntity(segment(0, "book", 0, "fly", "to", "new", "york"), "city", "NYC", 2, 4)},
+			want: "*book fly to [new york|NYC](city)",
+		},
+		{
+			name: "adjacent entities",
+			in: []slu.Segment{
+				withEntity(withEntity(segment(0, "set", 0, "red", "lamp"), "colour", "red", 0, 1), "device", "lamp", 1, 2),
+			},
+			want: "*set [red](colour) [lamp](device)",
+		},
+		{
+			name: "special characters are escaped",
+			in:   []slu.Segment{withEntity(segment(0, "say*it", 0, "[x]", "(y)", `a\b`, "c|d"), "t)", "a b]", 1, 2)},
+			want: `*say\*it \[x\] [\(y\)|a b\]](t\)) a\\b c\|d`,
+		},
+		{
+			name: "whitespace in words is escaped",
+			in:   []slu.Segment{segment(0, "say", 0, "hello world")},
+			want: `*say hello\ world`,
+		},
+		{
+			name: "unicode",
+			in:   []slu.Segment{withEntity(segment(0, "注文", 0, "コーヒー", "☕"), "飲み物", "コーヒー", 0, 1)},
+			want: "*注文 [コーヒー](飲み物) ☕",
+		},
+		{
+			name: "words are ordered by index",
+			in:   []slu.Segment{segment(0, "count", 7, "one", "two", "three", "four", "five", "six", "seven")},
+			want: "*count one two three four five six seven",
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.in)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	// A segment with word indices 0 and 2, so that an entity of indices [0, 2) spans a missing word.
+	gap := segment(0, "a", 0, "x")
+	gap.Transcripts[2] = slu.Transcript{Word: "z", Index: 2, IsFinalised: true}
+
+	tests := []struct {
+		name string
+		in   slu.Segment
+		want error
+	}{
+		{name: "empty intent", in: segment(0, "", 0, "hello"), want: ErrEmptyIntent},
+		{name: "no words", in: segment(0, "greet", 0), want: ErrEmptySegment},
+		{name: "empty word", in: segment(0, "greet", 0, "hello", ""), want: ErrEmptyWord},
+		{name: "empty word in entity", in: withEntity(segment(0, "a", 0, "x", ""), "t", "x", 0, 2), want: ErrEmptyWord},
+		{name: "empty entity type", in: withEntity(segment(0, "a", 0, "x"), "", "x", 0, 1), want: ErrEmptyType},
+		{name: "empty entity", in: withEntity(segment(0, "a", 0, "x", "y"), "t", "", 0, 0), want: ErrInvalidEntity},
+		{name: "entity past last word", in: withEntity(segment(0, "a", 0, "x", "y"), "t", "y", 1, 3), want: ErrInvalidEntity},
+		{name: "entity outside words", in: withEntity(segment(0, "a", 0, "x"), "t", "z", 5, 6), want: ErrInvalidEntity},
+		{
+			name: "overlapping entities",
+			in:   withEntity(withEntity(segment(0, "a", 0, "x", "y", "z"), "t", "x y", 0, 2), "u", "y z", 1, 3),
+			want: ErrInvalidEntity,
+		},
+		{name: "entity over gap in words", in: withEntity(gap, "t", "x z", 0, 2), want: ErrInvalidEntity},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render([]slu.Segment{tt.in})
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Render() error = %v, want %v", err, tt.want)
+			}
+
+			if got != "" {
+				t.Errorf("Render() = %q, want empty string", got)
+			}
+		})
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	tests := []string{
+		"*greet hello there",
+		"*turn_off turn off the [kitchen](room) lights *turn_on turn on the [radio](device)",
+		"*book fly to [new york|NYC](city) *book and back to [helsinki|HEL](city)",
+		"*set [red](colour) [lamp](device)",
+		`*say \[x\] \(y\) a\\b c\|d \*e`,
+		`*say hello\ world [a\ b|c  d](t)`,
+		`*say [x|](t) [y|foo \] bar](u)`,
+		"*tilaa tilaa [kahvia](juoma) ☕ *注文 [コーヒー](飲み物)",
+	}
+
+	for _, in := range tests {
+		in := in
+
+		t.Run(in, func(t *testing.T) {
+			segs, err := Parse(in)
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+
+			out, err := Render(segs)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+
+			if out != in {
+				t.Errorf("Render(Parse(%q)) = %q", in, out)
+			}
+
+			again, err := Parse(out)
+			if err != nil {
+				t.Fatalf("Parse(Render()) error = %v", err)
+			}
+
+			if !reflect.DeepEqual(again, segs) {
+				t.Errorf("Parse(Render()) = %+v, want %+v", again, segs)
+			}
+		})
+	}
+}
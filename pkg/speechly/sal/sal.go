@@ -0,0 +1,50 @@
+// Package sal implements parsing and rendering of utterances written in Speechly Annotation Language (SAL).
+//
+// An annotated utterance consists of one or more segments.
+// Each segment starts with an intent, marked with '*', followed by the words of the segment.
+// Entities are marked as '[words](type)' and can optionally override the entity value as '[words|value](type)'.
+// For example:
+//
+//	*turn_off turn off the [kitchen](room) lights *turn_on turn on the [radio](device)
+//
+// Special characters ('*', '[', ']', '(', ')', '|', '\') and whitespace can be escaped with a backslash.
+//
+// Word indices are assigned sequentially across the whole utterance, same as the SLU API does within an audio context.
+// Word timings are not part of the annotation, so they are always zero in parsed transcripts.
+package sal
+
+import "errors"
+
+// SAL parsing and rendering errors.
+var (
+	ErrEmptyUtterance = errors.New("empty utterance")
+	ErrMissingIntent  = errors.New("segment must start with an intent")
+	ErrEmptyIntent    = errors.New("empty intent")
+	ErrEmptySegment   = errors.New("segment has no words")
+	ErrEmptyWord      = errors.New("empty word")
+	ErrEmptyEntity    = errors.New("entity has no words")
+	ErrEmptyType      = errors.New("empty entity type")
+	ErrUnexpectedChar = errors.New("unexpected character")
+	ErrUnexpectedEOF  = errors.New("unexpected end of utterance")
+	ErrInvalidEntity  = errors.New("entity does not match segment words")
+)
+
+const (
+	intentMark  = '*'
+	entityStart = '['
+	entityEnd   = ']'
+	typeStart   = '('
+	typeEnd     = ')'
+	valueMark   = '|'
+	escapeMark  = '\\'
+	wordDelim   = ' '
+)
+
+func isSpecial(r rune) bool {
+	switch r {
+	case intentMark, entityStart, entityEnd, typeStart, typeEnd, valueMark, escapeMark:
+		return true
+	default:
+		return false
+	}
+}
@@ -3,13 +3,14 @@ package slu
 import (
 	"context"
 	"net/url"
+	"time"
 
 	sluv1 "github.com/speechly/api/go/speechly/slu/v1"
 	"golang.org/x/text/language"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
 
-	pgrpc "github.com/speechly/slu-client/internal/grpc"
+	pgrpc "github.com/speechly/slu-client/pkg/grpc"
 	"github.com/speechly/slu-client/pkg/logger"
 	"github.com/speechly/slu-client/pkg/speechly"
 )
@@ -28,30 +29,58 @@ type Client struct {
 	*pgrpc.Client
 	token speechly.AccessToken
 	log   logger.Logger
+	instr Instrumentation
 }
 
 // NewClient returns a new Client that will access provided URL with provided access token.
-func NewClient(u url.URL, t speechly.AccessToken, log logger.Logger) (*Client, error) {
-	c, err := pgrpc.NewClient("speechly.slu.v1", u)
+func NewClient(u url.URL, t speechly.AccessToken, log logger.Logger, opts ...pgrpc.Option) (*Client, error) {
+	c, err := pgrpc.NewClient("speechly.slu.v1", u, log, opts...)
 	if err != nil {
 		return nil, err
 	}
 
-	return &Client{c, t, log}, nil
+	return &Client{c, t, log, nopInstrumentation{}}, nil
+}
+
+// SetInstrumentation sets the Instrumentation that will receive events from all streams started by the client.
+// Passing nil disables instrumentation.
+func (c *Client) SetInstrumentation(i Instrumentation) {
+	if i == nil {
+		i = nopInstrumentation{}
+	}
+
+	c.instr = i
 }
 
 // StreamingRecognise starts a new SLU recognition stream with specified Config.
 func (c *Client) StreamingRecognise(ctx context.Context, fmt Config) (RecogniseStream, error) {
-	conn, err := c.Conn()
+	s, err := c.streamingRecognise(ctx, fmt)
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (c *Client) streamingRecognise(ctx context.Context, fmt Config) (*stream, error) {
+	conn, err := c.ConnContext(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	ctx = metadata.AppendToOutgoingContext(ctx, "Authorization", "Bearer "+c.token.String())
+	ctx, cancel := context.WithCancel(metadata.AppendToOutgoingContext(ctx, "Authorization", "Bearer "+c.token.String()))
 	str, err := sluv1.NewSLUClient(conn).Stream(ctx, grpc.WaitForReady(true))
 	if err != nil {
+		cancel()
+		c.instr.Error(time.Now(), err)
+		return nil, err
+	}
+
+	s, err := newStream(str, cancel, fmt, c.log, c.instr)
+	if err != nil {
+		cancel()
 		return nil, err
 	}
 
-	return newStream(str, fmt, c.log)
+	return s, nil
 }
@@ -8,6 +8,10 @@ import (
 
 var emptyID = uuid.UUID{}
 
+// ErrNoSegments is returned when an audio context is finalised without any segments,
+// e.g. because there was no speech in its audio.
+var ErrNoSegments = errors.New("finalised context has no segments")
+
 // AudioContext represents a single SLU audio context, which can have multiple segments.
 // See Speechly documentation for more information about audio contexts.
 type AudioContext struct {
@@ -99,7 +103,7 @@ func (c *AudioContext) Finalise() error {
 	}
 
 	if len(c.Segments) == 0 {
-		return errors.New("finalised context has no segments")
+		return ErrNoSegments
 	}
 
 	c.IsFinalised = true
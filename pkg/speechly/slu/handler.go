@@ -5,6 +5,8 @@ import (
 	"context"
 	"errors"
 	"io"
+	"sync"
+	"time"
 
 	sluv1 "github.com/speechly/api/go/speechly/slu/v1"
 	"golang.org/x/sync/errgroup"
@@ -12,6 +14,13 @@ import (
 	"github.com/speechly/slu-client/pkg/logger"
 )
 
+// Fields used for tagging log lines.
+const (
+	logFieldStreamID  = "stream_id"
+	logFieldContextID = "context_id"
+	logFieldSegmentID = "segment_id"
+)
+
 var (
 	startReq = sluv1.SLURequest{
 		StreamingRequest: &sluv1.SLURequest_Event{Event: &sluv1.SLUEvent{Event: sluv1.SLUEvent_START}},
@@ -102,37 +111,72 @@ type AudioContextHandler interface {
 	Read() (AudioContext, error)
 
 	// Close closes the handler by signalling it to send the StopContext event and exit the loop.
+	// If the context is not finished by API by then, its stream is aborted and cannot be used for new audio contexts.
+	// It returns the error that has happened while handling the context, if any,
+	// e.g. IdleTimeoutError, DeadlineError or AudioLimitError.
 	Close() error
 }
 
 type ctxHandler struct {
 	str      sluv1.SLU_StreamClient
+	abort    context.CancelFunc
 	src      AudioSource
 	res      chan AudioContext
 	log      logger.Logger
+	logLock  sync.Mutex
+	instr    Instrumentation
+	ctxInstr ContextInstrumentation
+	lim      contextLimits
+	maxBytes int64
+	limited  bool
+	parent   context.Context
 	ctx      context.Context
 	cancel   context.CancelFunc
+	sent     chan struct{}
 	done     chan struct{}
-	doneFunc func()
+	doneFunc func(error)
 	runErr   error
 }
 
+// received is a response received from API, or the error of receiving it.
+type received struct {
+	res *sluv1.SLUResponse
+	err error
+}
+
 func newCtxHandler(
-	ctx context.Context, str sluv1.SLU_StreamClient, src AudioSource, chanSize int, log logger.Logger, done func(),
+	ctx context.Context, s *stream, src AudioSource, chanSize int, lim contextLimits, done func(error),
 ) (*ctxHandler, error) {
-	if err := str.Send(&startReq); err != nil {
+	if err := s.stream.Send(&startReq); err != nil {
+		s.instr.Error(time.Now(), err)
 		return nil, err
 	}
 
-	ctx, cancel := context.WithCancel(ctx)
+	var (
+		hctx   context.Context
+		cancel context.CancelFunc
+	)
+
+	if lim.deadline.IsZero() {
+		hctx, cancel = context.WithCancel(ctx)
+	} else {
+		hctx, cancel = context.WithDeadline(ctx, lim.deadline)
+	}
 
 	r := &ctxHandler{
-		str:      str,
+		str:      s.stream,
+		abort:    s.abort,
 		src:      src,
 		res:      make(chan AudioContext, chanSize),
-		log:      log,
-		ctx:      ctx,
+		log:      s.log,
+		instr:    s.instr,
+		ctxInstr: s.instr.ContextStarted(time.Now()),
+		lim:      lim,
+		maxBytes: lim.audioBytes(s.cfg),
+		parent:   ctx,
+		ctx:      hctx,
 		cancel:   cancel,
+		sent:     make(chan struct{}),
 		done:     make(chan struct{}),
 		doneFunc: done,
 	}
@@ -151,37 +195,110 @@ func (r *ctxHandler) Read() (AudioContext, error) {
 	return c, nil
 }
 
+// logger returns the logger of the handler, which is tagged with context ID as soon as API reports it.
+func (r *ctxHandler) logger() logger.Logger {
+	r.logLock.Lock()
+	defer r.logLock.Unlock()
+
+	return r.log
+}
+
+func (r *ctxHandler) setContextID(id string) {
+	r.logLock.Lock()
+	defer r.logLock.Unlock()
+
+	r.log = logger.WithFields(r.log, logger.Fields{logFieldContextID: id})
+}
+
 func (r *ctxHandler) Close() error {
 	r.cancel()
 	<-r.done
 	return r.runErr
 }
 
+// receive receives responses from API into ch, until the context is finished, receiving fails or ctx is done.
+// Receiving runs separately from handling responses, so that handling can time out while API does not respond.
+// It closes finished after receiving the last response of the context, or failing, and exited once it returns.
+func (r *ctxHandler) receive(ctx context.Context, ch chan<- received, finished, exited chan<- struct{}) {
+	defer close(exited)
+
+	for {
+		res, err := r.str.Recv()
+
+		last := err != nil || res.GetFinished() != nil
+		if last {
+			close(finished)
+		}
+
+		select {
+		case ch <- received{res, err}:
+		case <-ctx.Done():
+			return
+		}
+
+		if last {
+			return
+		}
+	}
+}
+
+// hasMoreAudio returns true if src has more audio, reading from it until it writes some or reports the end of audio.
+// The audio read is discarded.
+func hasMoreAudio(src AudioSource) bool {
+	for {
+		n, err := src.WriteTo(io.Discard)
+		if n > 0 {
+			return true
+		}
+
+		if err != nil {
+			return err != io.EOF
+		}
+	}
+}
+
+// deadlineExceeded returns true if the deadline set with WithDeadline has been exceeded,
+// as opposed to the parent context being done.
+func (r *ctxHandler) deadlineExceeded() bool {
+	return errors.Is(r.ctx.Err(), context.DeadlineExceeded) && r.parent.Err() == nil
+}
+
 // nolint: funlen, gocognit, gocyclo // It's a long function, but most of it is just a switch case.
 func (r *ctxHandler) run() {
+	var err error
+
 	defer func() {
 		r.cancel() // Make sure we cancel context to avoid leaking it, if Close() is never called.
 		close(r.done)
-		go r.doneFunc()
+		go r.doneFunc(err)
 	}()
 
-	g, ctx := errgroup.WithContext(r.ctx)
+	var (
+		g, ctx       = errgroup.WithContext(r.ctx)
+		recvFinished = make(chan struct{})
+		recvExited   = make(chan struct{})
+	)
 
 	g.Go(func() error {
 		defer func() {
 			if err := r.str.Send(&stopReq); err != nil {
-				r.log.Warn("failed to send stop request to API", err)
+				r.logger().Warn("failed to send stop request to API", err)
+			} else {
+				r.ctxInstr.ContextStopped(time.Now())
 			}
 
+			close(r.sent)
+
 			if err := r.src.Close(); err != nil {
-				r.log.Warn("failed to close audio source", err)
+				r.logger().Warn("failed to close audio source", err)
 			}
 		}()
 
 		var (
-			buf = bytes.Buffer{}
-			req = sluv1.SLURequest_Audio{}
-			msg = sluv1.SLURequest{StreamingRequest: &req}
+			buf  = bytes.Buffer{}
+			req  = sluv1.SLURequest_Audio{}
+			msg  = sluv1.SLURequest{StreamingRequest: &req}
+			sent int64
 		)
 
 		for done := false; !done; {
@@ -199,12 +316,33 @@ func (r *ctxHandler) run() {
 				}
 
 				req.Audio = buf.Bytes()
+
+				limit := r.maxBytes >= 0 && sent+int64(len(req.Audio)) >= r.maxBytes
+				if limit {
+					req.Audio = req.Audio[:r.maxBytes-sent]
+					r.limited = len(req.Audio) < buf.Len()
+				}
+
 				if err := r.str.Send(&msg); err != nil {
 					return err
 				}
+
+				sent += int64(len(req.Audio))
+				r.ctxInstr.AudioSent(time.Now(), len(req.Audio))
+
+				// Reaching the limit exactly only cuts audio off if the source has more of it.
+				if limit && !done && !r.limited {
+					r.limited = hasMoreAudio(r.src)
+				}
+
+				done = done || limit
 			}
 		}
 
+		if r.limited {
+			r.logger().Debug("audio context reached maximum audio duration", r.lim.maxAudio)
+		}
+
 		return nil
 	})
 
@@ -216,117 +354,201 @@ func (r *ctxHandler) run() {
 			t  = Transcript{}
 			e  = Entity{}
 			i  = Intent{}
+
+			hasTentative = false
+			recv         = make(chan received)
+			sent         = r.sent
+			idle         = time.NewTimer(0)
 		)
 
+		// The idle timer is only started once all audio has been sent.
+		if !idle.Stop() {
+			<-idle.C
+		}
+
+		defer idle.Stop()
+
+		go r.receive(ctx, recv, recvFinished, recvExited)
+
 		for done := false; !done; {
+			var rcv received
+
 			select {
 			case <-ctx.Done():
+				// Neither sending nor receiving can be interrupted otherwise, if API is not responding.
+				if r.deadlineExceeded() {
+					r.abort()
+				}
+
 				return ctx.Err()
-			default:
-				res, err := r.str.Recv()
-				if err == io.EOF {
-					return errors.New("unexpected io.EOF from API")
+			case <-sent:
+				sent = nil
+
+				if r.lim.idleTimeout > 0 {
+					idle.Reset(r.lim.idleTimeout)
 				}
 
-				if err != nil {
-					return err
+				continue
+			case <-idle.C:
+				r.abort()
+				return &IdleTimeoutError{Idle: r.lim.idleTimeout}
+			case rcv = <-recv:
+			}
+
+			if sent == nil && r.lim.idleTimeout > 0 {
+				if !idle.Stop() {
+					<-idle.C
 				}
 
-				r.log.Debug("received response from API", res)
+				idle.Reset(r.lim.idleTimeout)
+			}
 
-				var (
-					id  = res.GetAudioContext()
-					sid = res.GetSegmentId()
-				)
+			res, err := rcv.res, rcv.err
+			if err == io.EOF {
+				return errors.New("unexpected io.EOF from API")
+			}
+
+			if err != nil {
+				return err
+			}
+
+			var (
+				id  = res.GetAudioContext()
+				sid = res.GetSegmentId()
+			)
+
+			logger.WithFields(r.logger(), logger.Fields{logFieldSegmentID: sid}).Debug("received response from API", res)
+
+			if err := cn.CheckID(id); err != nil {
+				return err
+			}
 
-				if err := cn.CheckID(id); err != nil {
+			switch v := res.GetStreamingResponse().(type) {
+			case *sluv1.SLUResponse_Transcript:
+				if err := t.Parse(v.Transcript, false); err != nil {
 					return err
 				}
 
-				switch v := res.GetStreamingResponse().(type) {
-				case *sluv1.SLUResponse_Transcript:
-					if err := t.Parse(v.Transcript, false); err != nil {
-						return err
-					}
+				if err := cn.AddTranscript(sid, t); err != nil {
+					return err
+				}
+			case *sluv1.SLUResponse_Entity:
+				if err := e.Parse(v.Entity, false); err != nil {
+					return err
+				}
 
-					if err := cn.AddTranscript(sid, t); err != nil {
-						return err
-					}
-				case *sluv1.SLUResponse_Entity:
-					if err := e.Parse(v.Entity, false); err != nil {
-						return err
-					}
+				if err := cn.AddEntity(sid, e); err != nil {
+					return err
+				}
+			case *sluv1.SLUResponse_Intent:
+				if err := i.Parse(v.Intent, false); err != nil {
+					return err
+				}
 
-					if err := cn.AddEntity(sid, e); err != nil {
-						return err
-					}
-				case *sluv1.SLUResponse_Intent:
-					if err := i.Parse(v.Intent, false); err != nil {
-						return err
-					}
+				if err := cn.SetIntent(sid, i); err != nil {
+					return err
+				}
 
-					if err := cn.SetIntent(sid, i); err != nil {
-						return err
-					}
+				r.ctxInstr.IntentFinalised(time.Now(), sid)
 
-				case *sluv1.SLUResponse_TentativeTranscript:
-					for _, v := range v.TentativeTranscript.GetTentativeWords() {
-						if err := t.Parse(v, true); err != nil {
-							return err
-						}
+			case *sluv1.SLUResponse_TentativeTranscript:
+				if w := v.TentativeTranscript.GetTentativeWords(); !hasTentative && len(w) > 0 {
+					r.ctxInstr.FirstTentativeWord(time.Now())
+					hasTentative = true
+				}
 
-						if err := cn.AddTranscript(sid, t); err != nil {
-							return err
-						}
-					}
-				case *sluv1.SLUResponse_TentativeEntities:
-					for _, v := range v.TentativeEntities.GetTentativeEntities() {
-						if err := e.Parse(v, true); err != nil {
-							return err
-						}
-
-						if err := cn.AddEntity(sid, e); err != nil {
-							return err
-						}
-					}
-				case *sluv1.SLUResponse_TentativeIntent:
-					if err := i.Parse(v.TentativeIntent, true); err != nil {
+				for _, v := range v.TentativeTranscript.GetTentativeWords() {
+					if err := t.Parse(v, true); err != nil {
 						return err
 					}
 
-					if err := cn.SetIntent(sid, i); err != nil {
-						return err
-					}
-				case *sluv1.SLUResponse_SegmentEnd:
-					if err := cn.FinaliseSegment(sid); err != nil {
+					if err := cn.AddTranscript(sid, t); err != nil {
 						return err
 					}
-				case *sluv1.SLUResponse_Started:
-					if err := cn.SetID(res.GetAudioContext()); err != nil {
+				}
+			case *sluv1.SLUResponse_TentativeEntities:
+				for _, v := range v.TentativeEntities.GetTentativeEntities() {
+					if err := e.Parse(v, true); err != nil {
 						return err
 					}
-				case *sluv1.SLUResponse_Finished:
-					if err := cn.Finalise(); err != nil {
+
+					if err := cn.AddEntity(sid, e); err != nil {
 						return err
 					}
+				}
+			case *sluv1.SLUResponse_TentativeIntent:
+				if err := i.Parse(v.TentativeIntent, true); err != nil {
+					return err
+				}
 
-					done = true
-				default:
-					return errors.New("unknown response type")
+				if err := cn.SetIntent(sid, i); err != nil {
+					return err
+				}
+			case *sluv1.SLUResponse_SegmentEnd:
+				if err := cn.FinaliseSegment(sid); err != nil {
+					return err
 				}
 
-				select {
-				case r.res <- cn:
-				case <-ctx.Done():
-					return ctx.Err()
+				r.ctxInstr.SegmentFinalised(time.Now(), sid)
+			case *sluv1.SLUResponse_Started:
+				if err := cn.SetID(res.GetAudioContext()); err != nil {
+					return err
+				}
+
+				r.setContextID(cn.ID.String())
+			case *sluv1.SLUResponse_Finished:
+				if err := cn.Finalise(); err != nil {
+					return err
 				}
+
+				r.ctxInstr.ContextFinished(time.Now())
+				done = true
+			default:
+				return errors.New("unknown response type")
+			}
+
+			select {
+			case r.res <- cn:
+			case <-ctx.Done():
+				return ctx.Err()
 			}
 		}
 
 		return nil
 	})
 
-	if err := g.Wait(); err != nil {
+	err = g.Wait()
+
+	// Only one goroutine can receive from the stream at a time, so receiving must exit before the stream is released.
+	// If the context has failed before API finished it, receiving may be blocked in Recv, which cannot be
+	// interrupted otherwise, so the stream is aborted. Its state is unknown at that point anyway.
+	if err != nil {
+		select {
+		case <-recvFinished:
+		default:
+			r.abort()
+		}
+	}
+
+	<-recvExited
+
+	if err != nil && r.deadlineExceeded() {
+		err = &DeadlineError{Deadline: r.lim.deadline}
+	}
+
+	if err != nil {
 		r.runErr = err
+		r.logger().Debug("audio context failed", err)
+
+		if !errors.Is(err, context.Canceled) {
+			r.instr.Error(time.Now(), err)
+		}
+
+		return
+	}
+
+	// Stopping at the limit is not a failure of the stream, so it is only returned from Close.
+	if r.limited {
+		r.runErr = &AudioLimitError{Limit: r.lim.maxAudio}
 	}
 }
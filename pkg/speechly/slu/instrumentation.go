@@ -0,0 +1,64 @@
+package slu
+
+import (
+	"time"
+)
+
+// Instrumentation receives timestamped events from SLU recognition streams.
+// Events from multiple streams can be reported at the same time, so implementations must be safe for concurrent use.
+type Instrumentation interface {
+	// StreamOpened is called after a recognition stream has been opened and configured.
+	StreamOpened(at time.Time)
+
+	// ContextStarted is called after a new audio context has been started by sending the START event to API.
+	// Returned ContextInstrumentation will receive the events of that audio context.
+	ContextStarted(at time.Time) ContextInstrumentation
+
+	// Error is called when a stream or an audio context fails.
+	Error(at time.Time, err error)
+}
+
+// ContextInstrumentation receives timestamped events from a single audio context.
+// Events about sent audio are reported from the goroutine sending audio, and events about received results
+// from the goroutine receiving them, so implementations must be safe for concurrent calls.
+type ContextInstrumentation interface {
+	// AudioSent is called after n bytes of audio have been sent to API.
+	AudioSent(at time.Time, n int)
+
+	// ContextStopped is called after the STOP event of the context has been sent to API.
+	ContextStopped(at time.Time)
+
+	// FirstTentativeWord is called when the first tentative word of the context is received from API.
+	FirstTentativeWord(at time.Time)
+
+	// IntentFinalised is called when the final intent of a segment of the context is received from API.
+	IntentFinalised(at time.Time, segmentID int32)
+
+	// SegmentFinalised is called when a segment of the context has been finalised by API.
+	SegmentFinalised(at time.Time, segmentID int32)
+
+	// ContextFinished is called when the context has been finished by API.
+	ContextFinished(at time.Time)
+}
+
+type nopInstrumentation struct{}
+
+func (nopInstrumentation) StreamOpened(time.Time) {}
+
+func (n nopInstrumentation) ContextStarted(time.Time) ContextInstrumentation {
+	return n
+}
+
+func (nopInstrumentation) Error(time.Time, error) {}
+
+func (nopInstrumentation) AudioSent(time.Time, int) {}
+
+func (nopInstrumentation) ContextStopped(time.Time) {}
+
+func (nopInstrumentation) FirstTentativeWord(time.Time) {}
+
+func (nopInstrumentation) IntentFinalised(time.Time, int32) {}
+
+func (nopInstrumentation) SegmentFinalised(time.Time, int32) {}
+
+func (nopInstrumentation) ContextFinished(time.Time) {}
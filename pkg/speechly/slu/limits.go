@@ -0,0 +1,106 @@
+package slu
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ContextOption is an option of an audio context, see RecogniseStream.NewAudioContext.
+type ContextOption func(*contextLimits)
+
+// WithMaxAudioDuration limits the duration of audio sent in the context to d.
+// Once the limit is reached, audio is stopped as if the source was exhausted,
+// and the results of audio sent so far are still read, after which AudioContextHandler.Close returns AudioLimitError.
+func WithMaxAudioDuration(d time.Duration) ContextOption {
+	return func(l *contextLimits) {
+		l.maxAudio = d
+	}
+}
+
+// WithIdleTimeout fails the context with IdleTimeoutError if API sends no responses for d.
+// Since API does not respond while there is no speech, the timeout only applies once all audio has been sent.
+// The stream of the context is aborted on timeout, so it cannot be used for new audio contexts.
+func WithIdleTimeout(d time.Duration) ContextOption {
+	return func(l *contextLimits) {
+		l.idleTimeout = d
+	}
+}
+
+// WithDeadline fails the context with DeadlineError if it is not finished by t.
+// The stream of the context is aborted on deadline, so it cannot be used for new audio contexts.
+func WithDeadline(t time.Time) ContextOption {
+	return func(l *contextLimits) {
+		l.deadline = t
+	}
+}
+
+// AudioLimitError is returned when the audio of a context was stopped,
+// because it reached the limit set with WithMaxAudioDuration.
+type AudioLimitError struct {
+	Limit time.Duration
+}
+
+func (e *AudioLimitError) Error() string {
+	return fmt.Sprintf("audio context reached maximum audio duration of %s", e.Limit)
+}
+
+// IdleTimeoutError is returned when API sends no responses within the timeout set with WithIdleTimeout.
+type IdleTimeoutError struct {
+	Idle time.Duration
+}
+
+func (e *IdleTimeoutError) Error() string {
+	return fmt.Sprintf("no response from API in %s", e.Idle)
+}
+
+// Timeout returns true, because the error is a timeout.
+func (e *IdleTimeoutError) Timeout() bool {
+	return true
+}
+
+// DeadlineError is returned when a context is not finished by the deadline set with WithDeadline.
+type DeadlineError struct {
+	Deadline time.Time
+}
+
+func (e *DeadlineError) Error() string {
+	return fmt.Sprintf("audio context not finished by deadline %s", e.Deadline.Format(time.RFC3339))
+}
+
+// Timeout returns true, because the error is a timeout.
+func (e *DeadlineError) Timeout() bool {
+	return true
+}
+
+// Unwrap returns context.DeadlineExceeded.
+func (e *DeadlineError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+type contextLimits struct {
+	maxAudio    time.Duration
+	idleTimeout time.Duration
+	deadline    time.Time
+}
+
+func newContextLimits(opts []ContextOption) contextLimits {
+	var l contextLimits
+	for _, o := range opts {
+		o(&l)
+	}
+
+	return l
+}
+
+// audioBytes returns the number of bytes of audio of a stream with Config f that can be sent,
+// or a negative number for no limit.
+func (l contextLimits) audioBytes(f Config) int64 {
+	if l.maxAudio <= 0 {
+		return -1
+	}
+
+	frames := int64(l.maxAudio) * int64(f.SampleRateHertz) / int64(time.Second)
+
+	return frames * int64(f.NumChannels) * linear16SampleSize
+}
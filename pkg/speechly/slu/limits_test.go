@@ -0,0 +1,190 @@
+package slu
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// newTestStream starts a fakeSLU server and returns it and a stream opened on it.
+func newTestStream(t *testing.T) (*fakeSLU, RecogniseStream) {
+	t.Helper()
+
+	api, c := newTestClient(t)
+
+	s, err := c.StreamingRecognise(context.Background(), testConfig)
+	if err != nil {
+		t.Fatalf("StreamingRecognise() error = %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = s.Close()
+	})
+
+	return api, s
+}
+
+func TestContextMaxAudioDuration(t *testing.T) {
+	// 100ms of audio takes 3200 bytes.
+	tests := []struct {
+		name    string
+		chunks  []int
+		want    int
+		limited bool
+	}{
+		{name: "below limit", chunks: []int{1000, 1000}, want: 2000},
+		{name: "cut", chunks: []int{2000, 2000}, want: 3200, limited: true},
+		{name: "cut in first chunk", chunks: []int{5000}, want: 3200, limited: true},
+		{name: "exact limit at end of audio", chunks: []int{1600, 1600}, want: 3200},
+		{name: "exact limit with more audio", chunks: []int{1600, 1600, 10}, want: 3200, limited: true},
+		{name: "exact limit with more audio after empty chunks", chunks: []int{3200, 0, 0, 10}, want: 3200, limited: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			api, s := newTestStream(t)
+
+			src := &testSource{}
+			for _, n := range tt.chunks {
+				src.chunks = append(src.chunks, make([]byte, n))
+			}
+
+			h, err := s.NewAudioContext(context.Background(), src, 1, WithMaxAudioDuration(100*time.Millisecond))
+			if err != nil {
+				t.Fatalf("NewAudioContext() error = %v", err)
+			}
+
+			// Results of the audio sent are still read when the limit is reached.
+			got, err := readAll(t, h)
+			if !got.IsFinalised {
+				t.Errorf("last context state = %+v, want finalised context", got)
+			}
+
+			var limErr *AudioLimitError
+
+			switch {
+			case !tt.limited && err != nil:
+				t.Errorf("Close() error = %v, want nil", err)
+			case tt.limited && !errors.As(err, &limErr):
+				t.Errorf("Close() error = %v, want AudioLimitError", err)
+			case tt.limited && limErr.Limit != 100*time.Millisecond:
+				t.Errorf("AudioLimitError.Limit = %s, want 100ms", limErr.Limit)
+			}
+
+			if _, _, audio := api.stats(); len(audio) != 1 || audio[0] != tt.want {
+				t.Errorf("API received audio %v, want [%d]", audio, tt.want)
+			}
+
+			if !src.closed {
+				t.Error("audio source was not closed")
+			}
+		})
+	}
+}
+
+func TestContextIdleTimeout(t *testing.T) {
+	api, s := newTestStream(t)
+	api.stallContexts()
+
+	start := time.Now()
+
+	h, err := s.NewAudioContext(context.Background(), &testSource{chunks: [][]byte{make([]byte, 10)}}, 1,
+		WithIdleTimeout(50*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewAudioContext() error = %v", err)
+	}
+
+	_, err = readAll(t, h)
+
+	var idleErr *IdleTimeoutError
+	if !errors.As(err, &idleErr) || idleErr.Idle != 50*time.Millisecond {
+		t.Fatalf("Close() error = %v, want IdleTimeoutError of 50ms", err)
+	}
+
+	if d := time.Since(start); d < 50*time.Millisecond {
+		t.Errorf("context timed out after %s, want at least 50ms", d)
+	}
+
+	// The stream is aborted on timeout, since API may still respond to the context.
+	waitFor(t, "stream to be aborted", func() bool {
+		_, open, _ := api.stats()
+		return open == 0
+	})
+
+	if _, err := s.NewAudioContext(context.Background(), &testSource{}, 1); err == nil {
+		t.Error("NewAudioContext() on aborted stream succeeded")
+	}
+}
+
+func TestContextIdleTimeoutWaitsForAudio(t *testing.T) {
+	_, s := newTestStream(t)
+
+	// API does not respond while audio is being sent, so the timeout does not apply until all of it is sent.
+	src := &testSource{chunks: [][]byte{make([]byte, 10)}, wait: make(chan struct{})}
+
+	h, err := s.NewAudioContext(context.Background(), src, 1, WithIdleTimeout(20*time.Millisecond))
+	if err != nil {
+		t.Fatalf("NewAudioContext() error = %v", err)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	close(src.wait)
+
+	if _, err := readAll(t, h); err != nil {
+		t.Errorf("Close() error = %v, want nil", err)
+	}
+}
+
+func TestContextDeadline(t *testing.T) {
+	api, s := newTestStream(t)
+	api.stallContexts()
+
+	deadline := time.Now().Add(50 * time.Millisecond)
+
+	h, err := s.NewAudioContext(context.Background(), &testSource{chunks: [][]byte{make([]byte, 10)}}, 1,
+		WithDeadline(deadline))
+	if err != nil {
+		t.Fatalf("NewAudioContext() error = %v", err)
+	}
+
+	_, err = readAll(t, h)
+
+	var dlErr *DeadlineError
+	if !errors.As(err, &dlErr) || !dlErr.Deadline.Equal(deadline) {
+		t.Fatalf("Close() error = %v, want DeadlineError at %s", err, deadline)
+	}
+
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Close() error = %v, want it to wrap %v", err, context.DeadlineExceeded)
+	}
+
+	waitFor(t, "stream to be aborted", func() bool {
+		_, open, _ := api.stats()
+		return open == 0
+	})
+}
+
+func TestContextCancelledBeforeDeadline(t *testing.T) {
+	api, s := newTestStream(t)
+	api.stallContexts()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	// The deadline of the parent context is not the deadline of the audio context.
+	h, err := s.NewAudioContext(ctx, &testSource{chunks: [][]byte{make([]byte, 10)}}, 1,
+		WithDeadline(time.Now().Add(time.Hour)))
+	if err != nil {
+		t.Fatalf("NewAudioContext() error = %v", err)
+	}
+
+	_, err = readAll(t, h)
+
+	var dlErr *DeadlineError
+	if errors.As(err, &dlErr) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Close() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
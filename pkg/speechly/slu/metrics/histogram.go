@@ -0,0 +1,93 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// DefaultBuckets are the default upper bounds of histogram buckets, in seconds.
+var DefaultBuckets = []float64{0.05, 0.1, 0.25, 0.5, 0.75, 1, 1.5, 2, 3, 5, 10}
+
+// Histogram is a cumulative histogram of observed values, modelled after Prometheus histograms.
+// It is not safe for concurrent use.
+type Histogram struct {
+	bounds []float64
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+// NewHistogram returns a new Histogram with specified upper bounds of buckets.
+// An implicit +Inf bucket is always added.
+func NewHistogram(bounds []float64) *Histogram {
+	b := make([]float64, len(bounds))
+	copy(b, bounds)
+	sort.Float64s(b)
+
+	return &Histogram{
+		bounds: b,
+		counts: make([]uint64, len(b)),
+	}
+}
+
+// Observe adds a single observation to the histogram.
+func (h *Histogram) Observe(v float64) {
+	for i, b := range h.bounds {
+		if v <= b {
+			h.counts[i]++
+		}
+	}
+
+	h.sum += v
+	h.count++
+}
+
+// Count returns the total number of observations.
+func (h *Histogram) Count() uint64 {
+	return h.count
+}
+
+// Sum returns the sum of all observations.
+func (h *Histogram) Sum() float64 {
+	return h.sum
+}
+
+// WritePrometheus writes the histogram to w in Prometheus text exposition format.
+func (h *Histogram) WritePrometheus(w io.Writer, name, help string) error {
+	if err := writeHeader(w, name, help, "histogram"); err != nil {
+		return err
+	}
+
+	for i, b := range h.bounds {
+		if _, err := fmt.Fprintf(w, "%s_bucket{le=\"%s\"} %d\n", name, formatFloat(b), h.counts[i]); err != nil {
+			return err
+		}
+	}
+
+	_, err := fmt.Fprintf(
+		w, "%s_bucket{le=\"+Inf\"} %d\n%s_sum %s\n%s_count %d\n",
+		name, h.count, name, formatFloat(h.sum), name, h.count,
+	)
+
+	return err
+}
+
+func writeCounter(w io.Writer, name, help string, v uint64) error {
+	if err := writeHeader(w, name, help, "counter"); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(w, "%s %d\n", name, v)
+	return err
+}
+
+func writeHeader(w io.Writer, name, help, typ string) error {
+	_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+	return err
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestHistogram(t *testing.T) {
+	h := NewHistogram([]float64{1, 0.5, 2})
+
+	for _, v := range []float64{0.25, 0.5, 1.5, 3} {
+		h.Observe(v)
+	}
+
+	if got := h.Count(); got != 4 {
+		t.Errorf("Count() = %d, want 4", got)
+	}
+
+	if got := h.Sum(); got != 5.25 {
+		t.Errorf("Sum() = %v, want 5.25", got)
+	}
+
+	var buf bytes.Buffer
+	if err := h.WritePrometheus(&buf, "test_seconds", "Test histogram."); err != nil {
+		t.Fatalf("WritePrometheus() error = %v", err)
+	}
+
+	// Buckets are sorted and cumulative, and a value equal to a bound belongs to its bucket.
+	want := `# HELP test_seconds Test histogram.
+# TYPE test_seconds histogram
+test_seconds_bucket{le="0.5"} 2
+test_seconds_bucket{le="1"} 2
+test_seconds_bucket{le="2"} 3
+test_seconds_bucket{le="+Inf"} 4
+test_seconds_sum 5.25
+test_seconds_count 4
+`
+
+	if got := buf.String(); got != want {
+		t.Errorf("WritePrometheus() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestHistogramEmpty(t *testing.T) {
+	var buf bytes.Buffer
+	if err := NewHistogram(nil).WritePrometheus(&buf, "empty", "Empty histogram."); err != nil {
+		t.Fatalf("WritePrometheus() error = %v", err)
+	}
+
+	want := `# HELP empty Empty histogram.
+# TYPE empty histogram
+empty_bucket{le="+Inf"} 0
+empty_sum 0
+empty_count 0
+`
+
+	if got := buf.String(); got != want {
+		t.Errorf("WritePrometheus() =\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,195 @@
+// Package metrics implements SLU instrumentation that collects latency histograms
+// and exposes them in Prometheus text exposition format.
+package metrics
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/speechly/slu-client/pkg/speechly/slu"
+)
+
+// Names of exported metrics.
+const (
+	MetricTimeToFirstWord   = "speechly_slu_time_to_first_word_seconds"
+	MetricTimeToFinalIntent = "speechly_slu_time_to_final_intent_seconds"
+	MetricStreamsOpened     = "speechly_slu_streams_opened_total"
+	MetricContextsStarted   = "speechly_slu_contexts_started_total"
+	MetricContextsFinished  = "speechly_slu_contexts_finished_total"
+	MetricAudioSentBytes    = "speechly_slu_audio_sent_bytes_total"
+	MetricErrors            = "speechly_slu_errors_total"
+)
+
+const contentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// Latency is an slu.Instrumentation that computes time-to-first-word and time-to-final-intent histograms.
+// Time-to-first-word is measured from the moment the first audio of a context was sent.
+// Time-to-final-intent is observed once for every final intent of the context,
+// and it is measured from the moment the last audio or the STOP event of the context was sent before the intent.
+//
+// Latency implements http.Handler, which serves collected metrics in Prometheus text exposition format.
+// It is safe for concurrent use.
+type Latency struct {
+	lock              sync.Mutex
+	timeToFirstWord   *Histogram
+	timeToFinalIntent *Histogram
+	streamsOpened     uint64
+	contextsStarted   uint64
+	contextsFinished  uint64
+	audioSentBytes    uint64
+	errors            uint64
+}
+
+// NewLatency returns a new Latency that uses specified bucket bounds (in seconds) for its histograms.
+// If no bounds are provided, DefaultBuckets are used.
+func NewLatency(bounds ...float64) *Latency {
+	if len(bounds) == 0 {
+		bounds = DefaultBuckets
+	}
+
+	return &Latency{
+		timeToFirstWord:   NewHistogram(bounds),
+		timeToFinalIntent: NewHistogram(bounds),
+	}
+}
+
+// StreamOpened implements slu.Instrumentation.
+func (l *Latency) StreamOpened(time.Time) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.streamsOpened++
+}
+
+// ContextStarted implements slu.Instrumentation.
+func (l *Latency) ContextStarted(time.Time) slu.ContextInstrumentation {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.contextsStarted++
+
+	return &contextLatency{
+		parent: l,
+	}
+}
+
+// Error implements slu.Instrumentation.
+func (l *Latency) Error(time.Time, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	l.errors++
+}
+
+// WritePrometheus writes collected metrics in Prometheus text exposition format to w.
+func (l *Latency) WritePrometheus(w io.Writer) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if err := l.timeToFirstWord.WritePrometheus(
+		w, MetricTimeToFirstWord, "Time from the first audio sent in an audio context to the first tentative word.",
+	); err != nil {
+		return err
+	}
+
+	if err := l.timeToFinalIntent.WritePrometheus(
+		w, MetricTimeToFinalIntent, "Time from the last audio or stop sent in an audio context to a final intent.",
+	); err != nil {
+		return err
+	}
+
+	counters := []struct {
+		name, help string
+		val        uint64
+	}{
+		{MetricStreamsOpened, "Number of opened recognition streams.", l.streamsOpened},
+		{MetricContextsStarted, "Number of started audio contexts.", l.contextsStarted},
+		{MetricContextsFinished, "Number of audio contexts finished by API.", l.contextsFinished},
+		{MetricAudioSentBytes, "Number of audio bytes sent to API.", l.audioSentBytes},
+		{MetricErrors, "Number of failed streams and audio contexts.", l.errors},
+	}
+
+	for _, c := range counters {
+		if err := writeCounter(w, c.name, c.help, c.val); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ServeHTTP implements http.Handler.
+func (l *Latency) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	buf := bytes.Buffer{}
+	if err := l.WritePrometheus(&buf); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	_, _ = buf.WriteTo(w)
+}
+
+// contextLatency is guarded by the lock of its parent,
+// since audio events and result events of a context are reported from different goroutines.
+type contextLatency struct {
+	parent    *Latency
+	firstSent time.Time
+	lastSent  time.Time
+	firstWord bool
+}
+
+func (c *contextLatency) AudioSent(at time.Time, n int) {
+	c.parent.lock.Lock()
+	defer c.parent.lock.Unlock()
+
+	if c.firstSent.IsZero() {
+		c.firstSent = at
+	}
+
+	c.lastSent = at
+	c.parent.audioSentBytes += uint64(n)
+}
+
+func (c *contextLatency) ContextStopped(at time.Time) {
+	c.parent.lock.Lock()
+	defer c.parent.lock.Unlock()
+
+	c.lastSent = at
+}
+
+func (c *contextLatency) FirstTentativeWord(at time.Time) {
+	c.parent.lock.Lock()
+	defer c.parent.lock.Unlock()
+
+	// Words cannot be recognised before any audio has been sent, so such an event cannot be measured.
+	if c.firstWord || c.firstSent.IsZero() {
+		return
+	}
+
+	c.firstWord = true
+	c.parent.timeToFirstWord.Observe(at.Sub(c.firstSent).Seconds())
+}
+
+func (c *contextLatency) IntentFinalised(at time.Time, _ int32) {
+	c.parent.lock.Lock()
+	defer c.parent.lock.Unlock()
+
+	if c.lastSent.IsZero() {
+		return
+	}
+
+	c.parent.timeToFinalIntent.Observe(at.Sub(c.lastSent).Seconds())
+}
+
+func (c *contextLatency) SegmentFinalised(time.Time, int32) {}
+
+func (c *contextLatency) ContextFinished(time.Time) {
+	c.parent.lock.Lock()
+	defer c.parent.lock.Unlock()
+
+	c.parent.contextsFinished++
+}
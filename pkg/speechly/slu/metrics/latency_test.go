@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLatency(t *testing.T) {
+	var (
+		l     = NewLatency(0.5, 1, 2)
+		start = time.Unix(1000, 0)
+		at    = func(ms int) time.Time { return start.Add(time.Duration(ms) * time.Millisecond) }
+	)
+
+	l.StreamOpened(at(0))
+
+	c := l.ContextStarted(at(0))
+	c.AudioSent(at(500), 100)
+	c.FirstTentativeWord(at(900)) // 0.4s after the first audio.
+	c.AudioSent(at(1000), 100)
+	c.FirstTentativeWord(at(1100)) // Only the first word is observed.
+	c.IntentFinalised(at(2500), 0) // 1.5s after the last audio.
+	c.AudioSent(at(3000), 50)
+	c.ContextStopped(at(3200))
+	c.IntentFinalised(at(3400), 1) // 0.2s after the stop.
+	c.SegmentFinalised(at(3400), 1)
+	c.ContextFinished(at(3500))
+
+	// A context without audio cannot be measured.
+	c = l.ContextStarted(at(4000))
+	c.FirstTentativeWord(at(4100))
+	c.IntentFinalised(at(4200), 0)
+
+	l.Error(at(5000), errors.New("failed"))
+
+	var buf strings.Builder
+	if err := l.WritePrometheus(&buf); err != nil {
+		t.Fatalf("WritePrometheus() error = %v", err)
+	}
+
+	got := buf.String()
+
+	for _, want := range []string{
+		MetricTimeToFirstWord + `_bucket{le="0.5"} 1`,
+		MetricTimeToFirstWord + "_count 1",
+		MetricTimeToFinalIntent + `_bucket{le="0.5"} 1`,
+		MetricTimeToFinalIntent + `_bucket{le="1"} 1`,
+		MetricTimeToFinalIntent + `_bucket{le="2"} 2`,
+		MetricTimeToFinalIntent + "_count 2",
+		"# TYPE " + MetricStreamsOpened + " counter\n" + MetricStreamsOpened + " 1\n",
+		MetricContextsStarted + " 2\n",
+		MetricContextsFinished + " 1\n",
+		MetricAudioSentBytes + " 250\n",
+		MetricErrors + " 1\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("WritePrometheus() output does not contain %q:\n%s", want, got)
+		}
+	}
+}
+
+func TestLatencyConcurrentEvents(t *testing.T) {
+	var (
+		l  = NewLatency()
+		c  = l.ContextStarted(time.Now())
+		wg sync.WaitGroup
+	)
+
+	// Audio is reported by the sender and results by the receiver of the stream.
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 100; i++ {
+			c.AudioSent(time.Now(), 1)
+		}
+
+		c.ContextStopped(time.Now())
+	}()
+
+	go func() {
+		defer wg.Done()
+
+		for i := 0; i < 100; i++ {
+			c.FirstTentativeWord(time.Now())
+			c.IntentFinalised(time.Now(), int32(i))
+		}
+	}()
+
+	wg.Wait()
+
+	var buf strings.Builder
+	if err := l.WritePrometheus(&buf); err != nil {
+		t.Fatalf("WritePrometheus() error = %v", err)
+	}
+
+	if want := MetricAudioSentBytes + " 100\n"; !strings.Contains(buf.String(), want) {
+		t.Errorf("WritePrometheus() output does not contain %q:\n%s", want, buf.String())
+	}
+}
+
+func TestLatencyServeHTTP(t *testing.T) {
+	l := NewLatency()
+	l.StreamOpened(time.Now())
+
+	rec := httptest.NewRecorder()
+	l.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("ServeHTTP() status = %d, want %d", rec.Code, http.StatusOK)
+	}
+
+	if got := rec.Header().Get("Content-Type"); got != contentType {
+		t.Errorf("ServeHTTP() Content-Type = %q, want %q", got, contentType)
+	}
+
+	if want := MetricStreamsOpened + " 1\n"; !strings.Contains(rec.Body.String(), want) {
+		t.Errorf("ServeHTTP() body does not contain %q:\n%s", want, rec.Body.String())
+	}
+}
@@ -0,0 +1,373 @@
+package slu
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrPoolClosed is returned when starting a new audio context from a closed StreamPool.
+var ErrPoolClosed = errors.New("stream pool is closed")
+
+// PoolConfig is the configuration of a StreamPool.
+type PoolConfig struct {
+	// Size is the number of warm streams that the pool keeps open, ready to be used for new audio contexts.
+	// Streams opened on top of Size to serve concurrent contexts are closed once their contexts are finished.
+	Size int
+
+	// MaxStreams is the maximum number of streams that the pool can have open at a time,
+	// which is the maximum number of concurrent audio contexts.
+	// Once the limit is reached, new audio contexts wait until one of running contexts is finished.
+	// If it is less than Size, Size is used instead.
+	MaxStreams int
+}
+
+// PoolStats are the usage statistics of a StreamPool.
+type PoolStats struct {
+	// Open is the number of currently open streams, Idle and InUse are the numbers of idle and busy ones.
+	Open, Idle, InUse int
+
+	// Waiting is the number of audio contexts currently waiting for a stream to become available.
+	Waiting int
+
+	// Replaced is the total number of streams that were closed and replaced due to errors.
+	Replaced uint64
+
+	// WaitCount is the total number of audio contexts that had to wait for a stream,
+	// and WaitDuration and MaxWaitDuration are the total and the longest time they waited.
+	WaitCount       uint64
+	WaitDuration    time.Duration
+	MaxWaitDuration time.Duration
+}
+
+// StreamPool is a pool of SLU recognition streams with the same Config, which allows running concurrent audio contexts.
+// Since a single RecogniseStream can only run one audio context at a time, the pool hands out a separate stream
+// for every audio context, keeping a number of warm streams open to avoid the latency of opening new ones.
+// Streams of audio contexts that fail are closed and replaced with new ones.
+// If an audio context cannot be started on a warm stream, e.g. because API has closed it while it was idle,
+// it is retried once on a new stream.
+// StreamPool is safe for concurrent use.
+type StreamPool struct {
+	cli   *Client
+	cfg   Config
+	size  int
+	ctx   context.Context
+	slots chan struct{}
+	lock  sync.Mutex
+	idle  []pooledStream
+	stats PoolStats
+	inUse int
+	open  int
+
+	closed bool
+}
+
+type pooledStream struct {
+	*stream
+	cancel context.CancelFunc
+}
+
+// NewStreamPool returns a new StreamPool of streams with Config cfg and opens pc.Size warm streams.
+// All streams of the pool are closed when ctx is done.
+func (c *Client) NewStreamPool(ctx context.Context, cfg Config, pc PoolConfig) (*StreamPool, error) {
+	max := pc.MaxStreams
+	if max < pc.Size {
+		max = pc.Size
+	}
+
+	if max < 1 {
+		return nil, errors.New("stream pool must allow at least one stream")
+	}
+
+	p := &StreamPool{
+		cli:   c,
+		cfg:   cfg,
+		size:  pc.Size,
+		ctx:   ctx,
+		slots: make(chan struct{}, max),
+		idle:  make([]pooledStream, 0, pc.Size),
+	}
+
+	for i := 0; i < pc.Size; i++ {
+		s, err := p.openStream()
+		if err != nil {
+			if err := p.Close(); err != nil {
+				c.log.Warn("Error closing stream pool", err)
+			}
+
+			return nil, err
+		}
+
+		p.idle = append(p.idle, s)
+	}
+
+	return p, nil
+}
+
+// NewAudioContext starts a new audio context on a stream from the pool.
+// If all streams are busy and the pool has reached its maximum number of streams,
+// this blocks until a stream becomes available or ctx is done.
+// The stream is returned to the pool once the context is finished.
+func (p *StreamPool) NewAudioContext(
+	ctx context.Context, src AudioSource, chanSize int, opts ...ContextOption,
+) (AudioContextHandler, error) {
+	if err := p.acquire(ctx); err != nil {
+		return nil, err
+	}
+
+	s, idle, err := p.get()
+	if err != nil {
+		p.release()
+		return nil, err
+	}
+
+	lim := newContextLimits(opts)
+
+	h, err := p.newAudioContext(ctx, s, src, chanSize, lim)
+	if err != nil && idle {
+		p.cli.log.Debug("Error starting audio context on idle stream, retrying with a new stream", err)
+
+		if s, err = p.reopen(s); err != nil {
+			p.release()
+			return nil, err
+		}
+
+		h, err = p.newAudioContext(ctx, s, src, chanSize, lim)
+	}
+
+	if err != nil {
+		p.put(s, err)
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// newAudioContext starts a new audio context on s, which is returned to the pool once the context is finished.
+func (p *StreamPool) newAudioContext(
+	ctx context.Context, s pooledStream, src AudioSource, chanSize int, lim contextLimits,
+) (AudioContextHandler, error) {
+	h, err := s.newAudioContext(ctx, src, chanSize, lim, func(err error) {
+		p.put(s, err)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// Stats returns current usage statistics of the pool.
+func (p *StreamPool) Stats() PoolStats {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	s := p.stats
+	s.Open = p.open
+	s.Idle = len(p.idle)
+	s.InUse = p.inUse
+
+	return s
+}
+
+// Close closes the pool by closing all idle streams.
+// Streams that are in use are closed once their audio contexts are finished.
+func (p *StreamPool) Close() error {
+	p.lock.Lock()
+	idle := p.idle
+	p.idle = nil
+	p.closed = true
+	p.lock.Unlock()
+
+	var err error
+	for _, s := range idle {
+		if e := p.closeStream(s); e != nil {
+			err = e
+		}
+	}
+
+	return err
+}
+
+// acquire waits for one of the pool slots, which limit the number of concurrent streams, to become available.
+func (p *StreamPool) acquire(ctx context.Context) error {
+	select {
+	case p.slots <- struct{}{}:
+		return nil
+	default:
+	}
+
+	p.lock.Lock()
+	p.stats.Waiting++
+	p.lock.Unlock()
+
+	start := time.Now()
+
+	var err error
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	wait := time.Since(start)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.stats.Waiting--
+	p.stats.WaitCount++
+	p.stats.WaitDuration += wait
+
+	if wait > p.stats.MaxWaitDuration {
+		p.stats.MaxWaitDuration = wait
+	}
+
+	return err
+}
+
+func (p *StreamPool) release() {
+	<-p.slots
+}
+
+// get returns an idle stream or opens a new one, if there are no idle streams.
+// The returned bool is true if the stream was idle.
+func (p *StreamPool) get() (pooledStream, bool, error) {
+	p.lock.Lock()
+
+	if p.closed {
+		p.lock.Unlock()
+		return pooledStream{}, false, ErrPoolClosed
+	}
+
+	if n := len(p.idle); n > 0 {
+		s := p.idle[n-1]
+		p.idle = p.idle[:n-1]
+		p.inUse++
+		p.lock.Unlock()
+
+		return s, true, nil
+	}
+
+	p.lock.Unlock()
+
+	s, err := p.openStream()
+	if err != nil {
+		return s, false, err
+	}
+
+	p.lock.Lock()
+	p.inUse++
+	p.lock.Unlock()
+
+	return s, false, nil
+}
+
+// reopen closes s, which is in use but broken, and opens a new stream in its place.
+func (p *StreamPool) reopen(s pooledStream) (pooledStream, error) {
+	p.lock.Lock()
+	p.stats.Replaced++
+	p.lock.Unlock()
+
+	if err := p.closeStream(s); err != nil {
+		p.cli.log.Warn("Error closing pooled stream", err)
+	}
+
+	s, err := p.openStream()
+	if err != nil {
+		p.lock.Lock()
+		p.inUse--
+		p.lock.Unlock()
+
+		return s, err
+	}
+
+	return s, nil
+}
+
+// put returns s to the pool after its audio context has exited with err.
+// If the context has failed, the state of the stream is unknown, so it is closed and replaced instead.
+func (p *StreamPool) put(s pooledStream, err error) {
+	defer p.release()
+
+	p.lock.Lock()
+
+	p.inUse--
+
+	if err == nil && !p.closed && len(p.idle) < p.size {
+		p.idle = append(p.idle, s)
+		p.lock.Unlock()
+
+		return
+	}
+
+	replace := err != nil && !p.closed
+	if replace {
+		p.stats.Replaced++
+	}
+
+	replenish := replace && len(p.idle) < p.size
+
+	p.lock.Unlock()
+
+	// Closing waits for the stream, so it is done without holding the lock.
+	if e := p.closeStream(s); e != nil {
+		p.cli.log.Warn("Error closing pooled stream", e)
+	}
+
+	if replenish {
+		go p.replenish()
+	}
+}
+
+// replenish opens a new stream to replace a broken one, to keep the pool warm.
+func (p *StreamPool) replenish() {
+	s, err := p.openStream()
+	if err != nil {
+		p.cli.log.Warn("Error opening replacement stream", err)
+		return
+	}
+
+	p.lock.Lock()
+
+	if !p.closed && len(p.idle) < p.size {
+		p.idle = append(p.idle, s)
+		p.lock.Unlock()
+
+		return
+	}
+
+	p.lock.Unlock()
+
+	if err := p.closeStream(s); err != nil {
+		p.cli.log.Warn("Error closing pooled stream", err)
+	}
+}
+
+func (p *StreamPool) openStream() (pooledStream, error) {
+	ctx, cancel := context.WithCancel(p.ctx)
+
+	s, err := p.cli.streamingRecognise(ctx, p.cfg)
+	if err != nil {
+		cancel()
+		return pooledStream{}, err
+	}
+
+	p.lock.Lock()
+	p.open++
+	p.lock.Unlock()
+
+	return pooledStream{s, cancel}, nil
+}
+
+// closeStream closes s, it must be called without the lock held.
+func (p *StreamPool) closeStream(s pooledStream) error {
+	defer s.cancel()
+
+	p.lock.Lock()
+	p.open--
+	p.lock.Unlock()
+
+	return s.Close()
+}
@@ -0,0 +1,282 @@
+package slu
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// waitFor waits until cond returns true, failing the test if it does not in a few seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func newTestPool(t *testing.T, pc PoolConfig) (*fakeSLU, *StreamPool) {
+	t.Helper()
+
+	api, c := newTestClient(t)
+
+	p, err := c.NewStreamPool(context.Background(), testConfig, pc)
+	if err != nil {
+		t.Fatalf("NewStreamPool() error = %v", err)
+	}
+
+	t.Cleanup(func() {
+		_ = p.Close()
+	})
+
+	return api, p
+}
+
+// runContext runs an audio context with src on p until it is finished.
+func runContext(t *testing.T, p *StreamPool, src *testSource) error {
+	t.Helper()
+
+	h, err := p.NewAudioContext(context.Background(), src, 1)
+	if err != nil {
+		t.Fatalf("NewAudioContext() error = %v", err)
+	}
+
+	_, err = readAll(t, h)
+
+	return err
+}
+
+func TestNewStreamPool(t *testing.T) {
+	api, p := newTestPool(t, PoolConfig{Size: 2})
+
+	if got := p.Stats(); got.Open != 2 || got.Idle != 2 || got.InUse != 0 {
+		t.Errorf("Stats() = %+v, want 2 open idle streams", got)
+	}
+
+	waitFor(t, "API to receive streams", func() bool {
+		streams, open, _ := api.stats()
+		return streams == 2 && open == 2
+	})
+
+	_, c := newTestClient(t)
+
+	if _, err := c.NewStreamPool(context.Background(), testConfig, PoolConfig{}); err == nil {
+		t.Error("NewStreamPool() without streams succeeded")
+	}
+}
+
+func TestStreamPoolReusesStreams(t *testing.T) {
+	api, p := newTestPool(t, PoolConfig{Size: 1})
+
+	for i := 0; i < 3; i++ {
+		if err := runContext(t, p, &testSource{chunks: [][]byte{make([]byte, 10)}}); err != nil {
+			t.Fatalf("audio context %d error = %v", i, err)
+		}
+
+		waitFor(t, "stream to be returned", func() bool {
+			return p.Stats().Idle == 1
+		})
+	}
+
+	if streams, _, audio := api.stats(); streams != 1 || len(audio) != 3 {
+		t.Errorf("API received %d contexts on %d streams, want 3 contexts on 1 stream", len(audio), streams)
+	}
+
+	if got := p.Stats(); got.Open != 1 || got.InUse != 0 || got.Replaced != 0 || got.WaitCount != 0 {
+		t.Errorf("Stats() = %+v, want 1 open stream without replacements or waiting", got)
+	}
+}
+
+func TestStreamPoolMaxStreams(t *testing.T) {
+	api, p := newTestPool(t, PoolConfig{Size: 1, MaxStreams: 2})
+
+	// Two running contexts use the warm stream and a new one, which is the limit.
+	var (
+		srcs = []*testSource{{wait: make(chan struct{})}, {wait: make(chan struct{})}}
+		hs   []AudioContextHandler
+	)
+
+	for _, src := range srcs {
+		h, err := p.NewAudioContext(context.Background(), src, 1)
+		if err != nil {
+			t.Fatalf("NewAudioContext() error = %v", err)
+		}
+
+		hs = append(hs, h)
+	}
+
+	if got := p.Stats(); got.Open != 2 || got.InUse != 2 || got.Idle != 0 {
+		t.Errorf("Stats() = %+v, want 2 open streams in use", got)
+	}
+
+	// The third context waits for one of the running ones to finish.
+	started := make(chan error, 1)
+
+	go func() {
+		started <- runContext(t, p, &testSource{})
+	}()
+
+	waitFor(t, "audio context to wait", func() bool {
+		return p.Stats().Waiting == 1
+	})
+
+	waitFor(t, "API to receive streams", func() bool {
+		_, open, _ := api.stats()
+		return open == 2
+	})
+
+	close(srcs[0].wait)
+
+	if _, err := readAll(t, hs[0]); err != nil {
+		t.Fatalf("audio context error = %v", err)
+	}
+
+	if err := <-started; err != nil {
+		t.Fatalf("waiting audio context error = %v", err)
+	}
+
+	close(srcs[1].wait)
+
+	if _, err := readAll(t, hs[1]); err != nil {
+		t.Fatalf("audio context error = %v", err)
+	}
+
+	// Streams on top of Size are closed once they are no longer used.
+	waitFor(t, "streams to be returned", func() bool {
+		s := p.Stats()
+		return s.InUse == 0 && s.Open == 1
+	})
+
+	got := p.Stats()
+	if got.Idle != 1 || got.Waiting != 0 || got.WaitCount != 1 || got.WaitDuration <= 0 ||
+		got.MaxWaitDuration != got.WaitDuration {
+		t.Errorf("Stats() = %+v, want 1 idle stream and 1 wait", got)
+	}
+
+	if streams, _, audio := api.stats(); streams != 2 || len(audio) != 3 {
+		t.Errorf("API received %d contexts on %d streams, want 3 contexts on 2 streams", len(audio), streams)
+	}
+}
+
+func TestStreamPoolWaitCancelled(t *testing.T) {
+	_, p := newTestPool(t, PoolConfig{Size: 1})
+
+	src := &testSource{wait: make(chan struct{})}
+
+	h, err := p.NewAudioContext(context.Background(), src, 1)
+	if err != nil {
+		t.Fatalf("NewAudioContext() error = %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	if _, err := p.NewAudioContext(ctx, &testSource{}, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("NewAudioContext() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	if got := p.Stats(); got.Waiting != 0 || got.WaitCount != 1 || got.MaxWaitDuration < 50*time.Millisecond {
+		t.Errorf("Stats() = %+v, want 1 wait of at least 50ms", got)
+	}
+
+	close(src.wait)
+
+	if _, err := readAll(t, h); err != nil {
+		t.Fatalf("audio context error = %v", err)
+	}
+}
+
+func TestStreamPoolReplacesFailedStreams(t *testing.T) {
+	api, p := newTestPool(t, PoolConfig{Size: 1})
+
+	if err := runContext(t, p, &testSource{chunks: [][]byte{failAudio}}); err == nil {
+		t.Fatal("failed audio context returned no error")
+	}
+
+	// The failed stream is closed and replaced with a new warm stream in the background.
+	waitFor(t, "stream to be replaced", func() bool {
+		return p.Stats().Idle == 1
+	})
+
+	if got := p.Stats(); got.Open != 1 || got.InUse != 0 || got.Replaced != 1 {
+		t.Errorf("Stats() = %+v, want 1 open stream and 1 replacement", got)
+	}
+
+	if err := runContext(t, p, &testSource{}); err != nil {
+		t.Fatalf("audio context error = %v", err)
+	}
+
+	if streams, _, _ := api.stats(); streams != 2 {
+		t.Errorf("API received %d streams, want 2", streams)
+	}
+}
+
+func TestStreamPoolRetriesBrokenIdleStream(t *testing.T) {
+	api, p := newTestPool(t, PoolConfig{Size: 1})
+
+	// Break the warm stream, as if the connection was lost while it was idle.
+	p.lock.Lock()
+	p.idle[0].cancel()
+	p.lock.Unlock()
+
+	waitFor(t, "stream to be broken", func() bool {
+		streams, open, _ := api.stats()
+		return streams == 1 && open == 0
+	})
+
+	if err := runContext(t, p, &testSource{chunks: [][]byte{make([]byte, 10)}}); err != nil {
+		t.Fatalf("audio context error = %v", err)
+	}
+
+	waitFor(t, "stream to be returned", func() bool {
+		return p.Stats().Idle == 1
+	})
+
+	if got := p.Stats(); got.Open != 1 || got.Replaced != 1 {
+		t.Errorf("Stats() = %+v, want 1 open stream and 1 replacement", got)
+	}
+
+	if streams, _, audio := api.stats(); streams != 2 || len(audio) != 1 || audio[0] != 10 {
+		t.Errorf("API received audio %v on %d streams, want [10] on 2 streams", audio, streams)
+	}
+}
+
+func TestStreamPoolClose(t *testing.T) {
+	api, p := newTestPool(t, PoolConfig{Size: 2})
+
+	src := &testSource{wait: make(chan struct{})}
+
+	h, err := p.NewAudioContext(context.Background(), src, 1)
+	if err != nil {
+		t.Fatalf("NewAudioContext() error = %v", err)
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if _, err := p.NewAudioContext(context.Background(), &testSource{}, 1); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("NewAudioContext() error = %v, want %v", err, ErrPoolClosed)
+	}
+
+	// The stream in use is closed once its context is finished.
+	if got := p.Stats(); got.Open != 1 || got.Idle != 0 || got.InUse != 1 {
+		t.Errorf("Stats() = %+v, want 1 open stream in use", got)
+	}
+
+	close(src.wait)
+
+	if _, err := readAll(t, h); err != nil {
+		t.Fatalf("audio context error = %v", err)
+	}
+
+	waitFor(t, "streams to be closed", func() bool {
+		_, open, _ := api.stats()
+		return open == 0 && p.Stats().Open == 0
+	})
+}
@@ -4,14 +4,22 @@ import (
 	"context"
 	"io"
 	"sync"
+	"time"
 
+	"github.com/google/uuid"
 	sluv1 "github.com/speechly/api/go/speechly/slu/v1"
 
 	"github.com/speechly/slu-client/pkg/logger"
 )
 
+// linear16SampleSize is the size of LINEAR16 samples in bytes.
+const linear16SampleSize = 2
+
 // AudioSource is the interface that represents the audio data source
 // that is sent to SLU API within a particular audio context.
+// Audio must be encoded as 16-bit signed little-endian samples (LINEAR16), which is the encoding declared to API,
+// with the number of channels and the sample rate of the stream's Config.
+// Audio in other formats has to be converted first, e.g. with audio.ConvertedStream.
 type AudioSource interface {
 	io.WriterTo
 	io.Closer
@@ -20,11 +28,13 @@ type AudioSource interface {
 // RecogniseStream is a single SLU recognition stream, which maps to a gRPC stream.
 // Since one stream can have multiple contexts RecogniseStream provides an API for launching these.
 // However, only a single audio context can be active at a time, which is controlled and guaranteed by the stream.
+// Use StreamPool for running multiple audio contexts concurrently.
 type RecogniseStream interface {
 	// NewAudioContext starts a new audio context by sending a START even to SLU API.
 	// If there is already an audio context running,
 	// this will block until the running context is stopped, or the stream closed.
-	NewAudioContext(context.Context, AudioSource, int) (AudioContextHandler, error)
+	// The duration of the context can be limited with ContextOptions.
+	NewAudioContext(context.Context, AudioSource, int, ...ContextOption) (AudioContextHandler, error)
 
 	// Close closes the stream by closing the sending part of gRPC stream.
 	// It will wait for current audio context (if any) to be stopped, before closing the stream.
@@ -33,11 +43,19 @@ type RecogniseStream interface {
 
 type stream struct {
 	stream sluv1.SLU_StreamClient
+	cfg    Config
+	abort  context.CancelFunc
 	log    logger.Logger
+	instr  Instrumentation
 	lock   sync.Mutex
 }
 
-func newStream(str sluv1.SLU_StreamClient, f Config, log logger.Logger) (*stream, error) {
+// newStream configures str with f and returns it as a stream. abort must cancel str.
+func newStream(
+	str sluv1.SLU_StreamClient, abort context.CancelFunc, f Config, log logger.Logger, instr Instrumentation,
+) (*stream, error) {
+	log = logger.WithFields(log, logger.Fields{logFieldStreamID: uuid.New().String()})
+
 	if err := str.Send(&sluv1.SLURequest{
 		StreamingRequest: &sluv1.SLURequest_Config{
 			Config: &sluv1.SLUConfig{
@@ -48,6 +66,8 @@ func newStream(str sluv1.SLU_StreamClient, f Config, log logger.Logger) (*stream
 			},
 		},
 	}); err != nil {
+		instr.Error(time.Now(), err)
+
 		if err := str.CloseSend(); err != nil {
 			log.Warn("error closing recognition stream", err)
 		}
@@ -55,22 +75,50 @@ func newStream(str sluv1.SLU_StreamClient, f Config, log logger.Logger) (*stream
 		return nil, err
 	}
 
+	log.Debug("recognition stream opened")
+	instr.StreamOpened(time.Now())
+
 	return &stream{
 		stream: str,
+		cfg:    f,
+		abort:  abort,
 		log:    log,
+		instr:  instr,
 	}, nil
 }
 
-func (s *stream) NewAudioContext(ctx context.Context, src AudioSource, chanSize int) (AudioContextHandler, error) {
+func (s *stream) NewAudioContext(
+	ctx context.Context, src AudioSource, chanSize int, opts ...ContextOption,
+) (AudioContextHandler, error) {
+	h, err := s.newAudioContext(ctx, src, chanSize, newContextLimits(opts), func(error) {})
+	if err != nil {
+		return nil, err
+	}
+
+	return h, nil
+}
+
+// newAudioContext starts a new audio context and calls done with the error of the context (if any) once it exits.
+func (s *stream) newAudioContext(
+	ctx context.Context, src AudioSource, chanSize int, lim contextLimits, done func(error),
+) (*ctxHandler, error) {
 	s.lock.Lock() // Wait for previous context to exit.
 
-	return newCtxHandler(ctx, s.stream, src, chanSize, s.log, func() {
+	h, err := newCtxHandler(ctx, s, src, chanSize, lim, func(err error) {
 		s.lock.Unlock() // Notify that context is done.
+		done(err)
 	})
+	if err != nil {
+		s.lock.Unlock()
+		return nil, err
+	}
+
+	return h, nil
 }
 
 func (s *stream) Close() error {
 	s.lock.Lock() // Wait for current context to exit (if any).
 	defer s.lock.Unlock()
+	defer s.abort()
 	return s.stream.CloseSend()
 }
@@ -0,0 +1,308 @@
+package slu
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+	sluv1 "github.com/speechly/api/go/speechly/slu/v1"
+	"golang.org/x/text/language"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	pgrpc "github.com/speechly/slu-client/pkg/grpc"
+	"github.com/speechly/slu-client/pkg/logger"
+	"github.com/speechly/slu-client/pkg/speechly"
+)
+
+// failAudio is the audio that makes fakeSLU fail the stream it is sent on.
+var failAudio = []byte("fail")
+
+// testConfig is the Config of test streams, with 1 second of audio taking 32000 bytes.
+var testConfig = Config{NumChannels: 1, SampleRateHertz: 16000, LanguageCode: language.English}
+
+// fakeSLU is an in-process SLU API, which starts and finishes audio contexts on START and STOP events,
+// and records the amount of audio received in every context.
+type fakeSLU struct {
+	sluv1.UnimplementedSLUServer
+
+	lock    sync.Mutex
+	streams int
+	open    int
+	audio   []int
+	stall   bool
+}
+
+func (f *fakeSLU) Stream(s sluv1.SLU_StreamServer) error {
+	f.lock.Lock()
+	f.streams++
+	f.open++
+	f.lock.Unlock()
+
+	defer func() {
+		f.lock.Lock()
+		f.open--
+		f.lock.Unlock()
+	}()
+
+	if _, err := s.Recv(); err != nil {
+		return err
+	}
+
+	var (
+		id    string
+		audio int
+	)
+
+	for {
+		req, err := s.Recv()
+		if err == io.EOF {
+			return nil
+		}
+
+		if err != nil {
+			return err
+		}
+
+		res := &sluv1.SLUResponse{AudioContext: id}
+
+		switch r := req.GetStreamingRequest().(type) {
+		case *sluv1.SLURequest_Audio:
+			if bytes.Equal(r.Audio, failAudio) {
+				return status.Error(codes.Internal, "failed")
+			}
+
+			audio += len(r.Audio)
+
+			continue
+		case *sluv1.SLURequest_Event:
+			if r.Event.GetEvent() == sluv1.SLUEvent_START {
+				id, audio = uuid.New().String(), 0
+				res = &sluv1.SLUResponse{AudioContext: id, StreamingResponse: &sluv1.SLUResponse_Started{
+					Started: &sluv1.SLUStarted{},
+				}}
+
+				break
+			}
+
+			f.lock.Lock()
+			f.audio = append(f.audio, audio)
+			stall := f.stall
+			f.lock.Unlock()
+
+			if stall {
+				continue
+			}
+
+			if err := f.sendSegment(s, id); err != nil {
+				return err
+			}
+
+			res.StreamingResponse = &sluv1.SLUResponse_Finished{Finished: &sluv1.SLUFinished{}}
+		}
+
+		if err := s.Send(res); err != nil {
+			return err
+		}
+	}
+}
+
+// sendSegment sends a finalised segment of the audio context id.
+func (f *fakeSLU) sendSegment(s sluv1.SLU_StreamServer, id string) error {
+	for _, r := range []*sluv1.SLUResponse{
+		{StreamingResponse: &sluv1.SLUResponse_Transcript{Transcript: &sluv1.SLUTranscript{Word: "HELLO", EndTime: 500}}},
+		{StreamingResponse: &sluv1.SLUResponse_Intent{Intent: &sluv1.SLUIntent{Intent: "greet"}}},
+		{StreamingResponse: &sluv1.SLUResponse_SegmentEnd{SegmentEnd: &sluv1.SLUSegmentEnd{}}},
+	} {
+		r.AudioContext = id
+
+		if err := s.Send(r); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// stats returns the total number of streams, the number of currently open ones
+// and the amount of audio received in every finished context.
+func (f *fakeSLU) stats() (streams, open int, audio []int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	return f.streams, f.open, append([]int(nil), f.audio...)
+}
+
+// stallContexts makes the server stop responding to STOP events, so that audio contexts are never finished.
+func (f *fakeSLU) stallContexts() {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+
+	f.stall = true
+}
+
+// newTestClient starts a fakeSLU server and returns it and a Client connected to it.
+func newTestClient(t *testing.T) (*fakeSLU, *Client) {
+	t.Helper()
+
+	var (
+		lis = bufconn.Listen(1 << 20)
+		srv = grpc.NewServer()
+		api = &fakeSLU{}
+	)
+
+	sluv1.RegisterSLUServer(srv, api)
+
+	go func() {
+		_ = srv.Serve(lis)
+	}()
+
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial(
+		"passthrough:///bufnet",
+		grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+
+	c, err := NewClient(url.URL{Scheme: "grpc", Host: "bufnet"}, speechly.AccessToken{}, logger.NewStdLogger(io.Discard),
+		pgrpc.WithConn(conn))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Dial(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	return api, c
+}
+
+// testSource is an AudioSource that writes its chunks one at a time.
+// If wait is not nil, the source blocks until wait is closed before reporting the end of audio.
+type testSource struct {
+	chunks [][]byte
+	wait   chan struct{}
+	closed bool
+}
+
+func (s *testSource) WriteTo(w io.Writer) (int64, error) {
+	if len(s.chunks) == 0 {
+		if s.wait != nil {
+			<-s.wait
+		}
+
+		return 0, io.EOF
+	}
+
+	n, err := w.Write(s.chunks[0])
+	s.chunks = s.chunks[1:]
+
+	return int64(n), err
+}
+
+func (s *testSource) Close() error {
+	s.closed = true
+	return nil
+}
+
+// readAll reads all states of h, closes it and returns the last state and the error of closing.
+func readAll(t *testing.T, h AudioContextHandler) (AudioContext, error) {
+	t.Helper()
+
+	var last AudioContext
+
+	for {
+		c, err := h.Read()
+		if err == io.EOF {
+			break
+		}
+
+		if err != nil {
+			t.Fatalf("Read() error = %v", err)
+		}
+
+		last = c
+	}
+
+	return last, h.Close()
+}
+
+func TestStreamAudioContexts(t *testing.T) {
+	api, c := newTestClient(t)
+
+	s, err := c.StreamingRecognise(context.Background(), testConfig)
+	if err != nil {
+		t.Fatalf("StreamingRecognise() error = %v", err)
+	}
+
+	// A stream runs multiple audio contexts, one after another.
+	for i := 0; i < 2; i++ {
+		src := &testSource{chunks: [][]byte{make([]byte, 100), make([]byte, 60)}}
+
+		h, err := s.NewAudioContext(context.Background(), src, 1)
+		if err != nil {
+			t.Fatalf("NewAudioContext() error = %v", err)
+		}
+
+		got, err := readAll(t, h)
+		if err != nil {
+			t.Fatalf("Close() error = %v", err)
+		}
+
+		if !got.IsFinalised || got.ID == uuid.Nil || got.Segments[0].Intent.Value != "greet" {
+			t.Errorf("last context state = %+v, want finalised context with ID and a greet segment", got)
+		}
+
+		if !src.closed {
+			t.Error("audio source was not closed")
+		}
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	if streams, _, audio := api.stats(); streams != 1 || len(audio) != 2 || audio[0] != 160 || audio[1] != 160 {
+		t.Errorf("API received %d streams with audio %v, want 1 stream with audio [160 160]", streams, audio)
+	}
+}
+
+func TestStreamAudioContextFails(t *testing.T) {
+	_, c := newTestClient(t)
+
+	s, err := c.StreamingRecognise(context.Background(), testConfig)
+	if err != nil {
+		t.Fatalf("StreamingRecognise() error = %v", err)
+	}
+
+	h, err := s.NewAudioContext(context.Background(), &testSource{chunks: [][]byte{failAudio}}, 1)
+	if err != nil {
+		t.Fatalf("NewAudioContext() error = %v", err)
+	}
+
+	var readErr error
+	for readErr == nil {
+		_, readErr = h.Read()
+	}
+
+	if err := h.Close(); status.Code(err) != codes.Internal {
+		t.Errorf("Close() error = %v, want %s status", err, codes.Internal)
+	}
+}
@@ -0,0 +1,347 @@
+// Package tokencache implements a file-based cache of Speechly access tokens.
+//
+// The cache is safe for concurrent use by multiple processes: all modifications are done while holding
+// an exclusive lock on the cache directory and tokens are written atomically, so readers never see partial files.
+package tokencache
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/speechly/slu-client/pkg/speechly"
+)
+
+// Defaults for cache options.
+const (
+	DefaultMaxEntries   = 32
+	DefaultExpiryMargin = time.Minute
+)
+
+const (
+	dirPerms     = 0700
+	lockFileName = "cache.lock"
+	tokenFileExt = ".token"
+	tempPattern  = ".tmp-*"
+)
+
+// ErrNotFound is returned when there is no valid token cached with requested key.
+var ErrNotFound = errors.New("token not found in cache")
+
+// Option is an option of a Cache.
+type Option func(*Cache)
+
+// WithMaxEntries sets the maximum number of tokens kept in the cache.
+// When the limit is exceeded, least recently written tokens are evicted.
+func WithMaxEntries(n int) Option {
+	return func(c *Cache) {
+		c.maxEntries = n
+	}
+}
+
+// WithExpiryMargin sets the margin for token expiry.
+// Tokens that expire within the margin are treated as expired, so that they do not expire while being used.
+func WithExpiryMargin(d time.Duration) Option {
+	return func(c *Cache) {
+		c.margin = d
+	}
+}
+
+// WithVerifier makes the cache verify tokens with v, both when reading them from the cache and after fetching them.
+// Cached tokens that fail verification are treated as not found.
+func WithVerifier(v *speechly.Verifier) Option {
+	return func(c *Cache) {
+		c.verifier = v
+	}
+}
+
+// Cache is a cache of Speechly access tokens, stored as files in a directory.
+// Tokens are stored under arbitrary string keys, which are hashed for file names.
+type Cache struct {
+	dir        string
+	maxEntries int
+	margin     time.Duration
+	verifier   *speechly.Verifier
+}
+
+// DefaultDir returns the default cache directory of current user.
+func DefaultDir() (string, error) {
+	d, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(d, "speechly", "tokens"), nil
+}
+
+// New returns a new Cache that stores tokens in dir, which is created if it does not exist.
+// Since cached tokens are secrets, the directory and the files in it are only accessible by current user.
+func New(dir string, opts ...Option) (*Cache, error) {
+	if err := os.MkdirAll(dir, dirPerms); err != nil {
+		return nil, err
+	}
+
+	// MkdirAll does not change permissions of an existing directory.
+	if err := os.Chmod(dir, dirPerms); err != nil {
+		return nil, err
+	}
+
+	c := &Cache{
+		dir:        dir,
+		maxEntries: DefaultMaxEntries,
+		margin:     DefaultExpiryMargin,
+	}
+
+	for _, o := range opts {
+		o(c)
+	}
+
+	return c, nil
+}
+
+// Get returns a valid token cached with key.
+// If there is no token or it has expired, ErrNotFound is returned.
+func (c *Cache) Get(key string) (speechly.AccessToken, error) {
+	return c.read(c.path(key))
+}
+
+// Put stores token t in the cache with key, replacing any previous token with the same key.
+func (c *Cache) Put(key string, t speechly.AccessToken) error {
+	return c.withLock(func() error {
+		if err := c.write(c.path(key), t); err != nil {
+			return err
+		}
+
+		return c.evict()
+	})
+}
+
+// Fetch returns a valid token cached with key or fetches a new one by calling fetch and caches it.
+// Concurrent calls, including those in other processes, are serialised, so that only one of them calls fetch.
+func (c *Cache) Fetch(
+	ctx context.Context, key string, fetch func(context.Context) (speechly.AccessToken, error),
+) (speechly.AccessToken, error) {
+	if t, err := c.Get(key); err == nil {
+		return t, nil
+	}
+
+	var t speechly.AccessToken
+
+	err := c.withLock(func() error {
+		var err error
+
+		// Token may have been fetched by someone else, while we were waiting for the lock.
+		if t, err = c.Get(key); err == nil {
+			return nil
+		}
+
+		if t, err = fetch(ctx); err != nil {
+			return err
+		}
+
+		if c.verifier != nil {
+			if err := c.verifier.Verify(t); err != nil {
+				return err
+			}
+		}
+
+		if err := c.write(c.path(key), t); err != nil {
+			return err
+		}
+
+		return c.evict()
+	})
+
+	return t, err
+}
+
+// Delete removes the token cached with key, if there is one.
+func (c *Cache) Delete(key string) error {
+	return c.withLock(func() error {
+		return removeFile(c.path(key))
+	})
+}
+
+// Evict removes expired tokens and, if there are more tokens than the maximum number of entries,
+// the least recently written ones.
+func (c *Cache) Evict() error {
+	return c.withLock(c.evict)
+}
+
+// Clear removes all tokens from the cache.
+func (c *Cache) Clear() error {
+	return c.withLock(func() error {
+		ps, err := c.list()
+		if err != nil {
+			return err
+		}
+
+		for _, p := range ps {
+			if err := removeFile(p); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (c *Cache) evict() error {
+	ps, err := c.list()
+	if err != nil {
+		return err
+	}
+
+	type entry struct {
+		path    string
+		modTime time.Time
+	}
+
+	es := make([]entry, 0, len(ps))
+
+	for _, p := range ps {
+		// Tokens are not verified, since they may belong to someone using a different verifier.
+		if _, err := c.load(p); err != nil {
+			if err := removeFile(p); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		s, err := os.Stat(p)
+		if err != nil {
+			return err
+		}
+
+		es = append(es, entry{p, s.ModTime()})
+	}
+
+	if c.maxEntries <= 0 || len(es) <= c.maxEntries {
+		return nil
+	}
+
+	sort.Slice(es, func(i, j int) bool {
+		return es[i].modTime.After(es[j].modTime)
+	})
+
+	for _, e := range es[c.maxEntries:] {
+		if err := removeFile(e.path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Cache) read(path string) (speechly.AccessToken, error) {
+	t, err := c.load(path)
+	if err != nil {
+		return t, err
+	}
+
+	if c.verifier != nil {
+		if err := c.verifier.Verify(t); err != nil {
+			return t, fmt.Errorf("%w: %s", ErrNotFound, err)
+		}
+	}
+
+	return t, nil
+}
+
+// load reads an unexpired token from path, without verifying it.
+func (c *Cache) load(path string) (speechly.AccessToken, error) {
+	var t speechly.AccessToken
+
+	data, err := os.ReadFile(path) // nolint: gosec
+	if os.IsNotExist(err) {
+		return t, ErrNotFound
+	}
+
+	if err != nil {
+		return t, err
+	}
+
+	if err := t.Parse(strings.TrimSpace(string(data))); err != nil {
+		return t, fmt.Errorf("%w: %s", ErrNotFound, err)
+	}
+
+	if !t.VerifyExpiresAt(time.Now().Add(c.margin).Unix(), true) {
+		return t, fmt.Errorf("%w: %s", ErrNotFound, speechly.ErrTokenExpired)
+	}
+
+	return t, nil
+}
+
+// write writes t to path atomically, by writing it to a temporary file first and then renaming it.
+func (c *Cache) write(path string, t speechly.AccessToken) error {
+	f, err := os.CreateTemp(c.dir, tempPattern) // Created with 0600 permissions.
+	if err != nil {
+		return err
+	}
+
+	if err := writeAndClose(f, t.String()+"\n"); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		_ = os.Remove(f.Name())
+		return err
+	}
+
+	return nil
+}
+
+func (c *Cache) list() ([]string, error) {
+	return filepath.Glob(filepath.Join(c.dir, "*"+tokenFileExt))
+}
+
+func (c *Cache) path(key string) string {
+	h := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(h[:])+tokenFileExt)
+}
+
+func (c *Cache) withLock(fn func() error) (err error) {
+	l, err := lockFile(filepath.Join(c.dir, lockFileName))
+	if err != nil {
+		return err
+	}
+
+	defer func() {
+		if e := l.unlock(); err == nil {
+			err = e
+		}
+	}()
+
+	return fn()
+}
+
+func writeAndClose(f *os.File, s string) error {
+	if _, err := f.WriteString(s); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		_ = f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func removeFile(path string) error {
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,413 @@
+package tokencache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/speechly/slu-client/pkg/speechly"
+)
+
+// newToken returns a new token with subject sub, which expires in exp.
+func newToken(t *testing.T, sub string, exp time.Duration) speechly.AccessToken {
+	t.Helper()
+
+	claims := speechly.AccessToken{
+		StandardClaims: jwt.StandardClaims{
+			IssuedAt:  time.Now().Unix(),
+			ExpiresAt: time.Now().Add(exp).Unix(),
+			Subject:   sub,
+		},
+	}
+
+	s, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tok speechly.AccessToken
+	if err := tok.Parse(s); err != nil {
+		t.Fatal(err)
+	}
+
+	return tok
+}
+
+// files returns the names of all files in dir, except the lock file.
+func files(t *testing.T, dir string) []string {
+	t.Helper()
+
+	es, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+
+	for _, e := range es {
+		if e.Name() != lockFileName {
+			names = append(names, e.Name())
+		}
+	}
+
+	return names
+}
+
+func TestCacheGetPut(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "tokens")
+
+	c, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Get("app"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get() of missing token error = %v, want %v", err, ErrNotFound)
+	}
+
+	var (
+		first  = newToken(t, "first", time.Hour)
+		second = newToken(t, "second", time.Hour)
+	)
+
+	for _, tok := range []speechly.AccessToken{first, second} {
+		if err := c.Put("app", tok); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+
+		got, err := c.Get("app")
+		if err != nil {
+			t.Fatalf("Get() error = %v", err)
+		}
+
+		if got.String() != tok.String() || got.Subject != tok.Subject {
+			t.Errorf("Get() = %q, want %q", got.Subject, tok.Subject)
+		}
+	}
+
+	if _, err := c.Get("other"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of other key error = %v, want %v", err, ErrNotFound)
+	}
+
+	if err := c.Delete("app"); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+
+	if _, err := c.Get("app"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of deleted token error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestCachePermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permissions are not supported on Windows")
+	}
+
+	dir := filepath.Join(t.TempDir(), "tokens")
+
+	// Permissions of an existing directory must be fixed as well.
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Put("app", newToken(t, "app", time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if s, err := os.Stat(dir); err != nil {
+		t.Fatal(err)
+	} else if p := s.Mode().Perm(); p != dirPerms {
+		t.Errorf("directory permissions = %o, want %o", p, dirPerms)
+	}
+
+	if s, err := os.Stat(c.path("app")); err != nil {
+		t.Fatal(err)
+	} else if p := s.Mode().Perm(); p != 0o600 {
+		t.Errorf("token file permissions = %o, want %o", p, 0o600)
+	}
+}
+
+func TestCacheExpiry(t *testing.T) {
+	c, err := New(t.TempDir(), WithExpiryMargin(time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Put evicts expired tokens, so the token has to be written directly.
+	if err := c.write(c.path("app"), newToken(t, "app", 30*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Get("app"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of token expiring within margin error = %v, want %v", err, ErrNotFound)
+	}
+
+	if err := os.WriteFile(filepath.Join(c.dir, "invalid"+tokenFileExt), []byte("invalid"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := c.Put("valid", newToken(t, "valid", time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Expired and invalid tokens are evicted by Put.
+	if got := files(t, c.dir); len(got) != 1 || got[0] != filepath.Base(c.path("valid")) {
+		t.Errorf("files after Put() = %v, want only the valid token", got)
+	}
+
+	// A token that is valid without the margin is returned by a cache with a smaller margin.
+	if err := c.write(c.path("app"), newToken(t, "app", 30*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	short, err := New(c.dir, WithExpiryMargin(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := short.Get("app"); err != nil {
+		t.Errorf("Get() with smaller margin error = %v", err)
+	}
+
+	if err := c.Evict(); err != nil {
+		t.Fatalf("Evict() error = %v", err)
+	}
+
+	if _, err := short.Get("app"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of evicted token error = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestCacheMaxEntries(t *testing.T) {
+	c, err := New(t.TempDir(), WithMaxEntries(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Modification times are set explicitly, since their resolution may be too coarse for consecutive writes.
+	for i, key := range []string{"oldest", "older"} {
+		if err := c.Put(key, newToken(t, key, time.Hour)); err != nil {
+			t.Fatal(err)
+		}
+
+		mt := time.Now().Add(time.Duration(i-2) * time.Hour)
+		if err := os.Chtimes(c.path(key), mt, mt); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := c.Put("newest", newToken(t, "newest", time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Get("oldest"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() of least recently written token error = %v, want %v", err, ErrNotFound)
+	}
+
+	for _, key := range []string{"older", "newest"} {
+		if _, err := c.Get(key); err != nil {
+			t.Errorf("Get(%q) error = %v", key, err)
+		}
+	}
+
+	if err := c.Clear(); err != nil {
+		t.Fatalf("Clear() error = %v", err)
+	}
+
+	if got := files(t, c.dir); len(got) != 0 {
+		t.Errorf("files after Clear() = %v, want none", got)
+	}
+}
+
+func TestCacheAtomicWrite(t *testing.T) {
+	c, err := New(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tokens of different lengths make a partially written file fail to parse.
+	tokens := []speechly.AccessToken{
+		newToken(t, "short", time.Hour),
+		newToken(t, strings.Repeat("long", 256), time.Hour),
+	}
+
+	if err := c.Put("app", tokens[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		done = make(chan struct{})
+	)
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+
+			if _, err := c.Get("app"); err != nil {
+				t.Errorf("Get() while writing error = %v", err)
+				return
+			}
+		}
+	}()
+
+	for i := 0; i < 200; i++ {
+		if err := c.Put("app", tokens[i%2]); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+
+	close(done)
+	wg.Wait()
+
+	// No temporary files are left behind.
+	if got := files(t, c.dir); len(got) != 1 || got[0] != filepath.Base(c.path("app")) {
+		t.Errorf("files after writing = %v, want only the token", got)
+	}
+}
+
+func TestCacheFetchConcurrent(t *testing.T) {
+	var (
+		dir     = t.TempDir()
+		fetched int32
+		wg      sync.WaitGroup
+		tok     = newToken(t, "app", time.Hour)
+	)
+
+	fetch := func(context.Context) (speechly.AccessToken, error) {
+		atomic.AddInt32(&fetched, 1)
+		time.Sleep(50 * time.Millisecond) // Give other writers a chance to race.
+
+		return tok, nil
+	}
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		// Each writer has its own Cache, and so its own lock file handle, like separate processes do.
+		go func() {
+			defer wg.Done()
+
+			c, err := New(dir)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+
+			got, err := c.Fetch(context.Background(), "app", fetch)
+			if err != nil {
+				t.Errorf("Fetch() error = %v", err)
+			} else if got.String() != tok.String() {
+				t.Errorf("Fetch() = %q, want %q", got, tok)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if fetched != 1 {
+		t.Errorf("fetch was called %d times, want 1", fetched)
+	}
+}
+
+const (
+	helperDirEnv = "TOKENCACHE_TEST_DIR"
+	helperLogEnv = "TOKENCACHE_TEST_LOG"
+)
+
+// TestHelperProcess is not a real test, it's run by TestCacheFetchProcesses as a separate writer process.
+func TestHelperProcess(t *testing.T) {
+	dir := os.Getenv(helperDirEnv)
+	if dir == "" {
+		t.Skip("only run as a helper process")
+	}
+
+	c, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = c.Fetch(context.Background(), "app", func(context.Context) (speechly.AccessToken, error) {
+		log, err := os.OpenFile(os.Getenv(helperLogEnv), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+		if err != nil {
+			return speechly.AccessToken{}, err
+		}
+
+		_, _ = fmt.Fprintln(log, os.Getpid())
+		_ = log.Close()
+
+		time.Sleep(100 * time.Millisecond)
+
+		return newToken(t, "app", time.Hour), nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCacheFetchProcesses(t *testing.T) {
+	var (
+		dir  = t.TempDir()
+		log  = filepath.Join(t.TempDir(), "fetches.log")
+		cmds []*exec.Cmd
+	)
+
+	for i := 0; i < 4; i++ {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestHelperProcess$") // nolint: gosec
+		cmd.Env = append(os.Environ(), helperDirEnv+"="+dir, helperLogEnv+"="+log)
+
+		if err := cmd.Start(); err != nil {
+			t.Fatal(err)
+		}
+
+		cmds = append(cmds, cmd)
+	}
+
+	for _, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Errorf("helper process error = %v", err)
+		}
+	}
+
+	data, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n := len(strings.Fields(string(data))); n != 1 {
+		t.Errorf("token was fetched by %d processes, want 1", n)
+	}
+
+	c, err := New(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := c.Get("app"); err != nil {
+		t.Errorf("Get() of fetched token error = %v", err)
+	}
+}
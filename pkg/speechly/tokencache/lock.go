@@ -0,0 +1,36 @@
+package tokencache
+
+import (
+	"os"
+)
+
+const lockFilePerms = 0600
+
+// fileLock is an exclusive advisory lock on a file, which is shared across processes.
+type fileLock struct {
+	f *os.File
+}
+
+// lockFile opens or creates the file at path and waits until an exclusive lock on it is acquired.
+func lockFile(path string) (*fileLock, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, lockFilePerms) // nolint: gosec
+	if err != nil {
+		return nil, err
+	}
+
+	if err := lock(f); err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+
+	return &fileLock{f}, nil
+}
+
+func (l *fileLock) unlock() error {
+	if err := unlock(l.f); err != nil {
+		_ = l.f.Close()
+		return err
+	}
+
+	return l.f.Close()
+}
@@ -0,0 +1,303 @@
+package speechly
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+// Token verification errors.
+var (
+	ErrInvalidSignature    = errors.New("JWT token has invalid signature")
+	ErrTokenNotYetValid    = errors.New("JWT token is not valid yet")
+	ErrInvalidAudience     = errors.New("JWT token has invalid audience")
+	ErrInvalidIssuer       = errors.New("JWT token has invalid issuer")
+	ErrAlgorithmNotAllowed = errors.New("JWT token signing algorithm is not allowed")
+	ErrUnknownKey          = errors.New("JWT token is signed with an unknown key")
+	ErrInvalidTokenKey     = errors.New("invalid token verification key")
+)
+
+// DefaultAlgorithms are the signing algorithms that are allowed by default.
+// Symmetric algorithms are not allowed, since verification keys are public.
+var DefaultAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}
+
+// VerifierOption is an option of a Verifier.
+type VerifierOption func(*Verifier)
+
+// WithAlgorithms sets the signing algorithms that are allowed, instead of DefaultAlgorithms.
+func WithAlgorithms(algs ...string) VerifierOption {
+	return func(v *Verifier) {
+		v.algs = algs
+	}
+}
+
+// WithIssuer makes the Verifier require tokens to be issued by iss.
+func WithIssuer(iss string) VerifierOption {
+	return func(v *Verifier) {
+		v.issuer = iss
+	}
+}
+
+// WithAudience makes the Verifier require tokens to be intended for aud.
+func WithAudience(aud string) VerifierOption {
+	return func(v *Verifier) {
+		v.audience = aud
+	}
+}
+
+// Verifier verifies signatures and claims of access tokens, using a set of public keys.
+type Verifier struct {
+	keys     map[string]interface{} // Public keys by key ID, a single key without ID uses an empty ID.
+	algs     []string
+	issuer   string
+	audience string
+}
+
+// NewPEMVerifier returns a new Verifier that uses a single RSA or ECDSA public key.
+// The key must be PEM-encoded, either as a PKIX public key, a PKCS #1 RSA public key or a certificate.
+func NewPEMVerifier(data []byte, opts ...VerifierOption) (*Verifier, error) {
+	b, _ := pem.Decode(data)
+	if b == nil {
+		return nil, fmt.Errorf("%w: no PEM data found", ErrInvalidTokenKey)
+	}
+
+	var (
+		key interface{}
+		err error
+	)
+
+	switch b.Type {
+	case "CERTIFICATE":
+		var c *x509.Certificate
+		if c, err = x509.ParseCertificate(b.Bytes); err == nil {
+			key = c.PublicKey
+		}
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(b.Bytes)
+	default:
+		key, err = x509.ParsePKIXPublicKey(b.Bytes)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTokenKey, err)
+	}
+
+	switch key.(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+	default:
+		return nil, fmt.Errorf("%w: unsupported key type %T", ErrInvalidTokenKey, key)
+	}
+
+	return newVerifier(map[string]interface{}{"": key}, opts), nil
+}
+
+// NewJWKSVerifier returns a new Verifier that uses the RSA and EC keys of a JSON Web Key Set.
+// Tokens must specify the ID of the key they were signed with, unless the set only contains a single key.
+func NewJWKSVerifier(data []byte, opts ...VerifierOption) (*Verifier, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidTokenKey, err)
+	}
+
+	keys := make(map[string]interface{}, len(set.Keys))
+
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("%w: key %q: %s", ErrInvalidTokenKey, k.KeyID, err)
+		}
+
+		keys[k.KeyID] = key
+	}
+
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("%w: no signing keys in key set", ErrInvalidTokenKey)
+	}
+
+	return newVerifier(keys, opts), nil
+}
+
+// NewVerifierFromFile returns a new Verifier that uses the keys from a PEM or a JWKS file.
+func NewVerifierFromFile(path string, opts ...VerifierOption) (*Verifier, error) {
+	data, err := os.ReadFile(path) // nolint: gosec
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("{")) {
+		return NewJWKSVerifier(data, opts...)
+	}
+
+	return NewPEMVerifier(data, opts...)
+}
+
+func newVerifier(keys map[string]interface{}, opts []VerifierOption) *Verifier {
+	v := &Verifier{
+		keys: keys,
+		algs: DefaultAlgorithms,
+	}
+
+	for _, o := range opts {
+		o(v)
+	}
+
+	return v
+}
+
+// Verify verifies the signature and the claims of access token t.
+func (v *Verifier) Verify(t AccessToken) error {
+	var c AccessToken
+	return c.ParseVerified(t.String(), v)
+}
+
+func (v *Verifier) key(t *jwt.Token) (interface{}, error) {
+	alg := t.Method.Alg()
+	if !v.allowed(alg) {
+		return nil, fmt.Errorf("%w: %s", ErrAlgorithmNotAllowed, alg)
+	}
+
+	kid, _ := t.Header["kid"].(string)
+
+	key, ok := v.keys[kid]
+	if !ok && kid == "" && len(v.keys) == 1 {
+		for _, k := range v.keys {
+			key, ok = k, true
+		}
+	}
+
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrUnknownKey, kid)
+	}
+
+	return key, nil
+}
+
+func (v *Verifier) allowed(alg string) bool {
+	for _, a := range v.algs {
+		if a == alg {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (v *Verifier) verifyClaims(t *AccessToken) error {
+	if v.issuer != "" && !t.VerifyIssuer(v.issuer, true) {
+		return fmt.Errorf("%w: %q", ErrInvalidIssuer, t.Issuer)
+	}
+
+	if v.audience != "" && !t.VerifyAudience(v.audience, true) {
+		return fmt.Errorf("%w: %q", ErrInvalidAudience, t.Audience)
+	}
+
+	return nil
+}
+
+// verificationError converts a validation error of jwt-go into one of token verification errors.
+func verificationError(err error) error {
+	var ve *jwt.ValidationError
+	if !errors.As(err, &ve) {
+		return err
+	}
+
+	switch {
+	case ve.Errors&jwt.ValidationErrorUnverifiable != 0 && ve.Inner != nil:
+		return ve.Inner // Returned by Verifier.key.
+	case ve.Errors&jwt.ValidationErrorSignatureInvalid != 0:
+		return fmt.Errorf("%w: %s", ErrInvalidSignature, ve)
+	case ve.Errors&jwt.ValidationErrorExpired != 0:
+		return ErrTokenExpired
+	case ve.Errors&(jwt.ValidationErrorNotValidYet|jwt.ValidationErrorIssuedAt) != 0:
+		return ErrTokenNotYetValid
+	default:
+		return err
+	}
+}
+
+// jwk is a JSON Web Key, as defined by RFC 7517.
+type jwk struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	Curve   string `json:"crv"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+func (k jwk) publicKey() (interface{}, error) {
+	switch k.KeyType {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var c elliptic.Curve
+
+		switch k.Curve {
+		case "P-256":
+			c = elliptic.P256()
+		case "P-384":
+			c = elliptic.P384()
+		case "P-521":
+			c = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, err
+		}
+
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, err
+		}
+
+		if !c.IsOnCurve(x, y) {
+			return nil, errors.New("point is not on curve")
+		}
+
+		return &ecdsa.PublicKey{Curve: c, X: x, Y: y}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+	}
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,478 @@
+package speechly
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	testIssuer   = "https://api.speechly.com"
+	testAudience = "https://api.speechly.com/slu"
+)
+
+// Signing keys of test tokens.
+var (
+	testRSAKey, _ = rsa.GenerateKey(rand.Reader, 2048)
+	testECKey, _  = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+)
+
+// encodePEM returns the PEM encoding of a public key of type typ.
+func encodePEM(t *testing.T, typ string, key interface{}) []byte {
+	t.Helper()
+
+	var (
+		der []byte
+		err error
+	)
+
+	switch typ {
+	case "RSA PUBLIC KEY":
+		der = x509.MarshalPKCS1PublicKey(key.(*rsa.PublicKey))
+	case "CERTIFICATE":
+		tmpl := &x509.Certificate{
+			SerialNumber: big.NewInt(1),
+			NotBefore:    time.Now(),
+			NotAfter:     time.Now().Add(time.Hour),
+		}
+		der, err = x509.CreateCertificate(rand.Reader, tmpl, tmpl, key, testECKey)
+	default:
+		der, err = x509.MarshalPKIXPublicKey(key)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der})
+}
+
+// encodeJWKS returns a JSON Web Key Set containing keys.
+func encodeJWKS(t *testing.T, keys ...map[string]string) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]interface{}{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return data
+}
+
+func encodeBigInt(i *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(i.Bytes())
+}
+
+func rsaJWK(kid string) map[string]string {
+	return map[string]string{
+		"kty": "RSA",
+		"kid": kid,
+		"use": "sig",
+		"n":   encodeBigInt(testRSAKey.N),
+		"e":   encodeBigInt(big.NewInt(int64(testRSAKey.E))),
+	}
+}
+
+func ecJWK(kid string) map[string]string {
+	return map[string]string{
+		"kty": "EC",
+		"kid": kid,
+		"crv": "P-256",
+		"x":   encodeBigInt(testECKey.X),
+		"y":   encodeBigInt(testECKey.Y),
+	}
+}
+
+// signToken returns a token with valid claims, modified by mod, signed with key using m.
+func signToken(t *testing.T, m jwt.SigningMethod, kid string, key interface{}, mod func(*AccessToken)) string {
+	t.Helper()
+
+	now := time.Now()
+	c := AccessToken{
+		StandardClaims: jwt.StandardClaims{
+			Issuer:    testIssuer,
+			Audience:  testAudience,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(time.Hour).Unix(),
+		},
+		AppID: "app",
+	}
+
+	if mod != nil {
+		mod(&c)
+	}
+
+	token := jwt.NewWithClaims(m, c)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+// newTestVerifier returns a Verifier that uses the PEM or JWKS keys, requiring the test issuer and audience.
+func newTestVerifier(t *testing.T, keys []byte, opts ...VerifierOption) *Verifier {
+	t.Helper()
+
+	opts = append([]VerifierOption{WithIssuer(testIssuer), WithAudience(testAudience)}, opts...)
+
+	var (
+		v   *Verifier
+		err error
+	)
+
+	if strings.HasPrefix(string(keys), "{") {
+		v, err = NewJWKSVerifier(keys, opts...)
+	} else {
+		v, err = NewPEMVerifier(keys, opts...)
+	}
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return v
+}
+
+func TestNewVerifier(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		new  func([]byte, ...VerifierOption) (*Verifier, error)
+		data []byte
+		want error
+	}{
+		{name: "PKIX RSA key", new: NewPEMVerifier, data: encodePEM(t, "PUBLIC KEY", &testRSAKey.PublicKey)},
+		{name: "PKCS #1 RSA key", new: NewPEMVerifier, data: encodePEM(t, "RSA PUBLIC KEY", &testRSAKey.PublicKey)},
+		{name: "PKIX EC key", new: NewPEMVerifier, data: encodePEM(t, "PUBLIC KEY", &testECKey.PublicKey)},
+		{name: "EC certificate", new: NewPEMVerifier, data: encodePEM(t, "CERTIFICATE", &testECKey.PublicKey)},
+		{
+			name: "no PEM data",
+			new:  NewPEMVerifier,
+			data: []byte("not a key"),
+			want: ErrInvalidTokenKey,
+		},
+		{
+			name: "invalid PEM key",
+			new:  NewPEMVerifier,
+			data: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: []byte("not a key")}),
+			want: ErrInvalidTokenKey,
+		},
+		{
+			name: "unsupported PEM key type",
+			new:  NewPEMVerifier,
+			data: encodePEM(t, "PUBLIC KEY", edKey.Public()),
+			want: ErrInvalidTokenKey,
+		},
+		{name: "RSA and EC key set", new: NewJWKSVerifier, data: encodeJWKS(t, rsaJWK("rsa"), ecJWK("ec"))},
+		{
+			name: "invalid JSON",
+			new:  NewJWKSVerifier,
+			data: []byte(`{"keys":`),
+			want: ErrInvalidTokenKey,
+		},
+		{
+			name: "no signing keys",
+			new:  NewJWKSVerifier,
+			data: encodeJWKS(t, map[string]string{"kty": "RSA", "kid": "enc", "use": "enc"}),
+			want: ErrInvalidTokenKey,
+		},
+		{
+			name: "unsupported key type",
+			new:  NewJWKSVerifier,
+			data: encodeJWKS(t, map[string]string{"kty": "oct", "kid": "hmac", "k": "c2VjcmV0"}),
+			want: ErrInvalidTokenKey,
+		},
+		{
+			name: "unsupported curve",
+			new:  NewJWKSVerifier,
+			data: encodeJWKS(t, map[string]string{"kty": "EC", "kid": "ec", "crv": "P-224", "x": "AQ", "y": "AQ"}),
+			want: ErrInvalidTokenKey,
+		},
+		{
+			name: "point not on curve",
+			new:  NewJWKSVerifier,
+			data: encodeJWKS(t, map[string]string{"kty": "EC", "kid": "ec", "crv": "P-256", "x": "AQ", "y": "AQ"}),
+			want: ErrInvalidTokenKey,
+		},
+		{
+			name: "invalid encoding",
+			new:  NewJWKSVerifier,
+			data: encodeJWKS(t, map[string]string{"kty": "RSA", "kid": "rsa", "n": "!", "e": "AQAB"}),
+			want: ErrInvalidTokenKey,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			v, err := tt.new(tt.data)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("error = %v, want %v", err, tt.want)
+			}
+
+			if err == nil && len(v.keys) == 0 {
+				t.Error("verifier has no keys")
+			}
+		})
+	}
+}
+
+func TestNewVerifierFromFile(t *testing.T) {
+	dir := t.TempDir()
+
+	tests := []struct {
+		file  string
+		data  []byte
+		token string
+	}{
+		{
+			file:  "key.pem",
+			data:  encodePEM(t, "PUBLIC KEY", &testRSAKey.PublicKey),
+			token: signToken(t, jwt.SigningMethodRS256, "", testRSAKey, nil),
+		},
+		{
+			file:  "keys.json",
+			data:  append([]byte("\n  "), encodeJWKS(t, rsaJWK("rsa"), ecJWK("ec"))...),
+			token: signToken(t, jwt.SigningMethodES256, "ec", testECKey, nil),
+		},
+	}
+
+	for _, tt := range tests {
+		path := filepath.Join(dir, tt.file)
+		if err := os.WriteFile(path, tt.data, 0o600); err != nil {
+			t.Fatal(err)
+		}
+
+		v, err := NewVerifierFromFile(path)
+		if err != nil {
+			t.Fatalf("NewVerifierFromFile(%s) error = %v", tt.file, err)
+		}
+
+		var a AccessToken
+		if err := a.ParseVerified(tt.token, v); err != nil {
+			t.Errorf("%s: ParseVerified() error = %v", tt.file, err)
+		}
+	}
+
+	if _, err := NewVerifierFromFile(filepath.Join(dir, "missing.pem")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("NewVerifierFromFile() error = %v, want %v", err, os.ErrNotExist)
+	}
+}
+
+func TestAccessTokenParseVerified(t *testing.T) {
+	var (
+		rsaPEM = encodePEM(t, "PUBLIC KEY", &testRSAKey.PublicKey)
+		ecPEM  = encodePEM(t, "PUBLIC KEY", &testECKey.PublicKey)
+		jwks   = encodeJWKS(t, rsaJWK("rsa"), ecJWK("ec"))
+		now    = time.Now()
+	)
+
+	// A token with the header and the signature of a valid token, but with different claims.
+	tampered := strings.Split(signToken(t, jwt.SigningMethodRS256, "", testRSAKey, nil), ".")
+	tampered[1] = strings.Split(signToken(t, jwt.SigningMethodRS256, "", testRSAKey, func(c *AccessToken) {
+		c.AppID = "other"
+	}), ".")[1]
+
+	tests := []struct {
+		name  string
+		keys  []byte
+		opts  []VerifierOption
+		token string
+		want  error
+	}{
+		{
+			name:  "RSA PEM key",
+			keys:  rsaPEM,
+			token: signToken(t, jwt.SigningMethodRS256, "", testRSAKey, nil),
+		},
+		{
+			name:  "EC PEM key",
+			keys:  ecPEM,
+			token: signToken(t, jwt.SigningMethodES256, "", testECKey, nil),
+		},
+		{
+			name:  "RSA key in key set",
+			keys:  jwks,
+			token: signToken(t, jwt.SigningMethodPS256, "rsa", testRSAKey, nil),
+		},
+		{
+			name:  "EC key in key set",
+			keys:  jwks,
+			token: signToken(t, jwt.SigningMethodES256, "ec", testECKey, nil),
+		},
+		{
+			name:  "single key in key set without key ID",
+			keys:  encodeJWKS(t, ecJWK("ec")),
+			token: signToken(t, jwt.SigningMethodES256, "", testECKey, nil),
+		},
+		{
+			name:  "tampered signature",
+			keys:  rsaPEM,
+			token: strings.Join(tampered, "."),
+			want:  ErrInvalidSignature,
+		},
+		{
+			name:  "signed with another key type",
+			keys:  rsaPEM,
+			token: signToken(t, jwt.SigningMethodES256, "", testECKey, nil),
+			want:  ErrInvalidSignature,
+		},
+		{
+			name:  "alg none",
+			keys:  rsaPEM,
+			token: signToken(t, jwt.SigningMethodNone, "", jwt.UnsafeAllowNoneSignatureType, nil),
+			want:  ErrAlgorithmNotAllowed,
+		},
+		{
+			name:  "HS256 with public key as secret",
+			keys:  rsaPEM,
+			token: signToken(t, jwt.SigningMethodHS256, "", rsaPEM, nil),
+			want:  ErrAlgorithmNotAllowed,
+		},
+		{
+			name:  "algorithm not in allow-list",
+			keys:  rsaPEM,
+			opts:  []VerifierOption{WithAlgorithms("RS256")},
+			token: signToken(t, jwt.SigningMethodPS256, "", testRSAKey, nil),
+			want:  ErrAlgorithmNotAllowed,
+		},
+		{
+			name:  "unknown key ID",
+			keys:  jwks,
+			token: signToken(t, jwt.SigningMethodRS256, "other", testRSAKey, nil),
+			want:  ErrUnknownKey,
+		},
+		{
+			name:  "no key ID with multiple keys",
+			keys:  jwks,
+			token: signToken(t, jwt.SigningMethodRS256, "", testRSAKey, nil),
+			want:  ErrUnknownKey,
+		},
+		{
+			name: "wrong issuer",
+			keys: rsaPEM,
+			token: signToken(t, jwt.SigningMethodRS256, "", testRSAKey, func(c *AccessToken) {
+				c.Issuer = "https://example.com"
+			}),
+			want: ErrInvalidIssuer,
+		},
+		{
+			name: "wrong audience",
+			keys: rsaPEM,
+			token: signToken(t, jwt.SigningMethodRS256, "", testRSAKey, func(c *AccessToken) {
+				c.Audience = "https://example.com"
+			}),
+			want: ErrInvalidAudience,
+		},
+		{
+			name: "expired",
+			keys: rsaPEM,
+			token: signToken(t, jwt.SigningMethodRS256, "", testRSAKey, func(c *AccessToken) {
+				c.ExpiresAt = now.Add(-time.Minute).Unix()
+			}),
+			want: ErrTokenExpired,
+		},
+		{
+			name: "no expiration time",
+			keys: rsaPEM,
+			token: signToken(t, jwt.SigningMethodRS256, "", testRSAKey, func(c *AccessToken) {
+				c.ExpiresAt = 0
+			}),
+			want: ErrTokenExpired,
+		},
+		{
+			name: "not valid yet",
+			keys: rsaPEM,
+			token: signToken(t, jwt.SigningMethodRS256, "", testRSAKey, func(c *AccessToken) {
+				c.NotBefore = now.Add(time.Minute).Unix()
+			}),
+			want: ErrTokenNotYetValid,
+		},
+		{
+			name: "issued in the future",
+			keys: rsaPEM,
+			token: signToken(t, jwt.SigningMethodRS256, "", testRSAKey, func(c *AccessToken) {
+				c.IssuedAt = now.Add(time.Minute).Unix()
+			}),
+			want: ErrTokenNotYetValid,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			a := AccessToken{AppID: "previous"}
+
+			err := a.ParseVerified(tt.token, newTestVerifier(t, tt.keys, tt.opts...))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("ParseVerified() error = %v, want %v", err, tt.want)
+			}
+
+			if err != nil {
+				if a.AppID != "previous" || a.String() != "" {
+					t.Errorf("ParseVerified() modified the token to %+v on error", a)
+				}
+
+				return
+			}
+
+			if a.AppID != "app" || a.Issuer != testIssuer || a.String() != tt.token {
+				t.Errorf("ParseVerified() token = %+v, want the parsed claims", a)
+			}
+		})
+	}
+}
+
+func TestVerifierVerify(t *testing.T) {
+	v := newTestVerifier(t, encodeJWKS(t, rsaJWK("rsa"), ecJWK("ec")))
+
+	var valid, forged AccessToken
+	if err := valid.Parse(signToken(t, jwt.SigningMethodES256, "ec", testECKey, nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := v.Verify(valid); err != nil {
+		t.Errorf("Verify() error = %v", err)
+	}
+
+	// A token signed with another key parses, but does not verify.
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := forged.Parse(signToken(t, jwt.SigningMethodES256, "ec", otherKey, nil)); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := v.Verify(forged); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("Verify() error = %v, want %v", err, ErrInvalidSignature)
+	}
+}